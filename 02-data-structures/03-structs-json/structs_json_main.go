@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+
+	"go-systems-learning/pkg/redact"
 )
 
 // --- 1. Basic Struct ---
@@ -26,8 +28,10 @@ type DatabaseConfig struct {
 	// Embedding BaseConfig allows us to access its fields directly
 	BaseConfig
 
-	DBName     string
-	Connection string
+	DBName string
+	// A real connection string carries a password. redact.Secret prints,
+	// logs and marshals as [REDACTED]; Reveal() returns the value.
+	Connection redact.Secret
 }
 
 // --- 3. JSON Tags & Serialization ---
@@ -52,6 +56,7 @@ func main() {
 	}
 
 	fmt.Printf("Env: %s, DB: %s\n", db.Environment, db.DBName)
+	fmt.Printf("Full struct: %+v\n", db) // Connection prints as [REDACTED]
 
 	fmt.Println("\n--- 2. JSON Marshaling (Go -> JSON) ---")
 	response := APIResponse{
@@ -206,4 +206,8 @@ Directional channels ensure:
 - Producers cannot consume
 - Consumers cannot produce
 - System stays correct by construction
+
+Multi-stage versions of producer → consumer (Map, Filter, Batch,
+FanOut, Merge, Tee) with error and cancellation handling live in
+pkg/pipeline.
 */
@@ -201,4 +201,11 @@ KUBERNETES CONTEXT
 
 context.Context is NOT optional.
 It is the backbone of Cloud Native Go.
+
+A ctx stops at the process boundary. pkg/deadline carries its deadline
+over HTTP: the client Transport sends the time left in a header, and
+the server Middleware rebuilds it on r.Context():
+
+	client := &http.Client{Transport: &deadline.Transport{}}
+	handler := deadline.Middleware(deadline.ServerConfig{})(mux)
 */
@@ -230,4 +230,26 @@ Meaning:
 - Controller stays responsive
 
 This file models EXACTLY that design.
+
+A reusable, generic version lives in pkg/workerpool:
+
+	pool := workerpool.New(process, workerpool.Options{Workers: 3})
+	id, err := pool.Submit(ctx, job)
+	for res := range pool.Results() { ... }
+
+When each job is cheaper in bulk (one CSV file per 100 rows, bulk API
+writes), pkg/batcher flushes N items or every T, whichever comes first.
+
+To spread jobs across processes instead of goroutines, pkg/distpool
+replaces the jobs channel with a coordinator handing out leases over a
+socket.
+
+Threadiness caps the whole pool. pkg/bulkhead caps each dependency
+inside it, so one slow database cannot hold every worker:
+
+	reg := bulkhead.NewRegistry(bulkhead.Config{Capacity: 2}, nil)
+	err := reg.Get("db").Do(ctx, 1, query)
+
+Both numbers are guesses. pkg/conclimit learns the limit instead, from
+measured latency and timeouts, and rejects calls above it.
 */
@@ -3,6 +3,8 @@ package main
 import (
 	"errors"
 	"fmt"
+
+	"go-systems-learning/pkg/aggregate"
 )
 
 /*
@@ -150,6 +152,21 @@ Why?
 - Easy tracing
 */
 
+/*
+Fail fast is right when later steps depend on earlier ones.
+For validation and cleanup, run EVERY step and report EVERY failure.
+pkg/aggregate returns them as one error; errors.Is and errors.As
+still see each member.
+*/
+
+func processAllSteps() error {
+	var errs []error
+	for _, step := range []func() error{step1, step2, step3} {
+		errs = append(errs, step()) // nils are dropped
+	}
+	return aggregate.New(errs)
+}
+
 // ==========================================================
 // 7. NEVER IGNORE ERRORS
 // ==========================================================
@@ -235,6 +252,10 @@ func main() {
 		fmt.Println("Pipeline failed:", err)
 	}
 
+	if err := processAllSteps(); err != nil {
+		fmt.Println("Steps failed:", err)
+	}
+
 	// Sentinel error usage
 	if err := openFile("config.yaml"); err != nil {
 		fmt.Println("Open file error:", err)
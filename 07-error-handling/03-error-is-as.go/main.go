@@ -281,4 +281,11 @@ Kubernetes controllers rely on errors.Is / errors.As to:
 
 If you master THIS file,
 you can read Kubernetes error handling confidently.
+
+deepErrorChain tells you WHAT failed, never WHERE.
+pkg/errtrace records call frames, opt-in, without changing Is/As:
+
+	errtrace.SetEnabled(true) // or build with -tags errtrace_off to compile it out
+	err := errtrace.Errorf("startup failed: %w", loadApplication())
+	fmt.Printf("%+v\n", err) // message, then file:line per frame
 */
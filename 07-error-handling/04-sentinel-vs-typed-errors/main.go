@@ -290,4 +290,21 @@ Kubernetes uses:
 
 If you understand THIS file,
 you can design stable Go APIs.
+
+ValidationError names one flat field and stops at the first problem.
+pkg/field is the Kubernetes-style version: a path into the object, a
+typed reason and the bad value, collected for every problem at once:
+
+	var errs field.ErrorList
+	errs = append(errs, field.Required(field.NewPath("spec", "containers").Index(2).Child("image"), ""))
+	return errs.ToAggregate() // spec.containers[2].image: Required value
+
+Across an API boundary, pkg/status is the StatusError side: a registry
+maps sentinels and typed errors to HTTP codes and a JSON Status, and
+the client decodes it into an error that errors.Is still recognizes:
+
+	reg := status.NewRegistry()
+	reg.Register(ErrNotFound, status.ReasonNotFound, http.StatusNotFound)
+	reg.WriteError(w, err)          // server
+	err := reg.FromResponse(resp)   // client: errors.Is(err, ErrNotFound)
 */
@@ -7,6 +7,8 @@ import (
 	"math"
 	"math/rand"
 	"time"
+
+	"go-systems-learning/pkg/faultinject"
 )
 
 /*
@@ -43,17 +45,44 @@ Rule:
 - Retrying permanent errors makes systems worse
 */
 
-func flakyOperation() error {
-	n := rand.Intn(10)
-
-	switch {
-	case n < 5:
-		return ErrTemporaryFailure // retryable
-	case n < 7:
-		return ErrPermanentFailure // fatal
-	default:
-		return nil // success
+/*
+flakyOperation used to roll rand.Intn(10), so every run failed
+differently. The same 50/20/30 split now comes from a seeded fault
+injector: same seed, same failures, every run.
+
+Override it without recompiling, e.g.
+
+	FAULTINJECT='{"seed":7,"rules":{"flaky":{"faults":[{"calls":[1,2],"error":"temporary failure"}]}}}'
+*/
+
+var faults = newFaults()
+
+func newFaults() *faultinject.Injector {
+	cfg := faultinject.Config{
+		Seed: 1,
+		Rules: map[string]faultinject.Rule{
+			"flaky": {Faults: []faultinject.Fault{
+				{Rate: 0.5, Err: ErrTemporaryFailure}, // retryable
+				{Rate: 0.2, Err: ErrPermanentFailure}, // fatal
+				// the remaining 30% succeed
+			}},
+		},
+	}
+	if fromEnv, ok, err := faultinject.FromEnv(""); err != nil {
+		fmt.Println("ignoring fault config:", err)
+	} else if ok {
+		cfg = fromEnv
+	}
+
+	cfg.Errors = map[string]error{
+		ErrTemporaryFailure.Error(): ErrTemporaryFailure,
+		ErrPermanentFailure.Error(): ErrPermanentFailure,
 	}
+	return faultinject.New(cfg)
+}
+
+func flakyOperation() error {
+	return faults.Inject(context.Background(), "flaky")
 }
 
 // ==========================================================
@@ -290,4 +319,23 @@ KUBERNETES CONTEXT
 
 If you understand THIS file,
 you understand real-world Go error recovery.
+
+The three loops above are generalized in pkg/retry:
+
+	err := retry.Retry(ctx, op, retry.Policy{
+		MaxAttempts: 10,
+		Backoff:     retry.FullJitter(100*time.Millisecond, 10*time.Second),
+		Retryable:   func(err error) bool { return !errors.Is(err, ErrPermanentFailure) },
+	})
+
+When the dependency is down for good, pkg/breaker stops the retries
+from hammering it and fails fast with ErrCircuitOpen. Give it the same
+classification, or ErrPermanentFailure counts against the dependency:
+
+	cb := breaker.New(breaker.Config{
+		IsFailure: func(err error) bool { return !errors.Is(err, ErrPermanentFailure) },
+	})
+
+Non-idempotent operations become safe to retry behind an idempotency
+key: pkg/idempotency runs them once per key and replays the result.
 */
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
+
+	"go-systems-learning/pkg/redact"
 )
 
 /*
@@ -100,7 +103,8 @@ Rule:
 // ==========================================================
 
 func connectDB() error {
-	return errors.New("connection refused")
+	// The DSN carries a password; main's log output redacts it.
+	return fmt.Errorf("dial postgres://app:hunter2@db:5432/users: %w", errors.New("connection refused"))
 }
 
 func initStorage() error {
@@ -238,6 +242,9 @@ func controllerLoop() {
 
 func main() {
 	log.SetFlags(log.Ltime | log.Lshortfile)
+	// Errors carry whatever their context held, secrets included.
+	// Scrub them on the way out instead of trusting every call site.
+	log.SetOutput(redact.NewWriter(os.Stderr, redact.Default()))
 
 	fmt.Println("=== Logging vs Returning Errors ===")
 
@@ -247,6 +254,9 @@ func main() {
 	fmt.Println("\n-- Correct logging --")
 	startAppGood()
 
+	fmt.Println("\n-- Wrapped context, redacted --")
+	mainInit()
+
 	fmt.Println("\n-- Context logging --")
 	ctx := context.WithValue(context.Background(), requestIDKey, "req-123")
 	handleRequest(ctx)
@@ -0,0 +1,204 @@
+// Package aggregate combines several errors into one.
+//
+// processPipeline in 07-error-handling/01-errors-basics fails fast,
+// which is right for a pipeline whose steps depend on each other. But
+// validation and cleanup should run every step and report every
+// failure. An Aggregate is an error holding a list of errors, modeled
+// on k8s.io/apimachinery/pkg/util/errors. It implements
+// Unwrap() []error, so errors.Is and errors.As look through every
+// member.
+//
+// Aggregates can be flattened, de-duplicated and filtered, and built
+// from the errors of concurrent workers with Collect and Go.
+package aggregate
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ==========================================================
+// 1. AGGREGATE
+// ==========================================================
+
+// Aggregate is an error made of other errors.
+type Aggregate interface {
+	error
+	Errors() []error
+}
+
+// aggregate is the Aggregate implementation. It always holds at least
+// one error and no nils.
+type aggregate []error
+
+// New returns an Aggregate of the non-nil errors in errs, or nil if
+// there are none.
+func New(errs []error) Aggregate {
+	var list []error
+	for _, err := range errs {
+		if err != nil {
+			list = append(list, err)
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return aggregate(list)
+}
+
+// Error shows a single error as itself and several as a list.
+func (agg aggregate) Error() string {
+	if len(agg) == 1 {
+		return agg[0].Error()
+	}
+	msgs := make([]string, len(agg))
+	for i, err := range agg {
+		msgs[i] = err.Error()
+	}
+	return "[" + strings.Join(msgs, ", ") + "]"
+}
+
+func (agg aggregate) Errors() []error {
+	return []error(agg)
+}
+
+// Unwrap lets errors.Is and errors.As search every member.
+func (agg aggregate) Unwrap() []error {
+	return []error(agg)
+}
+
+// ==========================================================
+// 2. TRANSFORMATIONS
+// ==========================================================
+
+// Matcher reports whether an error is of interest.
+type Matcher func(err error) bool
+
+// MatchIs returns a Matcher for errors.Is(err, target).
+func MatchIs(target error) Matcher {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+// Flatten replaces every nested Aggregate in agg with its members,
+// recursively. It returns nil if nothing is left.
+func Flatten(agg Aggregate) Aggregate {
+	if agg == nil {
+		return nil
+	}
+	var out []error
+	for _, err := range agg.Errors() {
+		if nested, ok := err.(Aggregate); ok {
+			if flat := Flatten(nested); flat != nil {
+				out = append(out, flat.Errors()...)
+			}
+			continue
+		}
+		out = append(out, err)
+	}
+	return New(out)
+}
+
+// Dedupe drops members whose message repeats an earlier member's. The
+// first of each is kept, so errors.As still finds the original value.
+func Dedupe(agg Aggregate) Aggregate {
+	if agg == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var out []error
+	for _, err := range agg.Errors() {
+		msg := err.Error()
+		if seen[msg] {
+			continue
+		}
+		seen[msg] = true
+		out = append(out, err)
+	}
+	return New(out)
+}
+
+// FilterOut removes from err every error matching any of fns, looking
+// inside nested Aggregates. It returns nil if nothing is left. An err
+// that is not an Aggregate is returned unless it matches.
+func FilterOut(err error, fns ...Matcher) error {
+	if err == nil {
+		return nil
+	}
+	agg, ok := err.(Aggregate)
+	if !ok {
+		if matchesAny(err, fns) {
+			return nil
+		}
+		return err
+	}
+
+	var out []error
+	for _, e := range agg.Errors() {
+		if kept := FilterOut(e, fns...); kept != nil {
+			out = append(out, kept)
+		}
+	}
+	return reduce(New(out))
+}
+
+func matchesAny(err error, fns []Matcher) bool {
+	for _, fn := range fns {
+		if fn(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reduce unwraps an Aggregate of one error to that error, and an empty
+// one to nil. Other errors are returned unchanged.
+func Reduce(err error) error {
+	if agg, ok := err.(Aggregate); ok {
+		return reduce(agg)
+	}
+	return err
+}
+
+func reduce(agg Aggregate) error {
+	if agg == nil {
+		return nil
+	}
+	if errs := agg.Errors(); len(errs) == 1 {
+		return errs[0]
+	}
+	return agg
+}
+
+// ==========================================================
+// 3. CONCURRENT WORKERS
+// ==========================================================
+
+// Collect reads errors from ch until it is closed and aggregates the
+// non-nil ones, in the order received.
+func Collect(ch <-chan error) Aggregate {
+	var errs []error
+	for err := range ch {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return New(errs)
+}
+
+// Go runs every fn in its own goroutine, waits for all of them, and
+// aggregates their errors in the order of fns, so the result does not
+// depend on which finished first.
+func Go(fns ...func() error) Aggregate {
+	errs := make([]error, len(fns))
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn()
+		}()
+	}
+	wg.Wait()
+	return New(errs)
+}
@@ -0,0 +1,118 @@
+package aggregate
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	if agg := New(nil); agg != nil {
+		t.Fatalf("New(nil) = %v; want nil", agg)
+	}
+	if agg := New([]error{nil, nil}); agg != nil {
+		t.Fatalf("New of nils = %v; want nil", agg)
+	}
+
+	errA := errors.New("a")
+	if agg := New([]error{nil, errA}); agg.Error() != "a" || len(agg.Errors()) != 1 {
+		t.Fatalf("New of one error = %q with %d members; want a alone", agg, len(agg.Errors()))
+	}
+
+	pathErr := &fs.PathError{Op: "open", Path: "/x", Err: fs.ErrNotExist}
+	agg := New([]error{errA, pathErr})
+	if got, want := agg.Error(), "[a, open /x: file does not exist]"; got != want {
+		t.Fatalf("Error() = %q; want %q", got, want)
+	}
+	if !errors.Is(agg, errA) || !errors.Is(agg, fs.ErrNotExist) {
+		t.Fatal("errors.Is does not see every member")
+	}
+	var pe *fs.PathError
+	if !errors.As(agg, &pe) || pe.Path != "/x" {
+		t.Fatal("errors.As does not find a member")
+	}
+}
+
+func TestFlattenAndDedupe(t *testing.T) {
+	errA, errB, errC := errors.New("a"), errors.New("b"), errors.New("c")
+	nested := New([]error{errA, New([]error{errB, New([]error{errC, errors.New("a")})})})
+
+	flat := Flatten(nested)
+	if got, want := flat.Error(), "[a, b, c, a]"; got != want {
+		t.Fatalf("Flatten = %q; want %q", got, want)
+	}
+	deduped := Dedupe(flat)
+	if got, want := deduped.Error(), "[a, b, c]"; got != want {
+		t.Fatalf("Dedupe = %q; want %q", got, want)
+	}
+	if deduped.Errors()[0] != errA {
+		t.Fatal("Dedupe did not keep the first of the duplicates")
+	}
+	if Flatten(nil) != nil || Dedupe(nil) != nil {
+		t.Fatal("Flatten(nil) or Dedupe(nil) is not nil")
+	}
+}
+
+func TestFilterOutAndReduce(t *testing.T) {
+	errKeep := errors.New("disk full")
+	agg := New([]error{fs.ErrNotExist, New([]error{fs.ErrNotExist, errKeep})})
+
+	tests := []struct {
+		name string
+		err  error
+		fns  []Matcher
+		want error
+	}{
+		{"nil", nil, []Matcher{MatchIs(fs.ErrNotExist)}, nil},
+		{"single match", fs.ErrNotExist, []Matcher{MatchIs(fs.ErrNotExist)}, nil},
+		{"single kept", errKeep, []Matcher{MatchIs(fs.ErrNotExist)}, errKeep},
+		{"nested, one left, reduced", agg, []Matcher{MatchIs(fs.ErrNotExist)}, errKeep},
+		{"everything filtered", agg, []Matcher{MatchIs(fs.ErrNotExist), MatchIs(errKeep)}, nil},
+	}
+	for _, tt := range tests {
+		if got := FilterOut(tt.err, tt.fns...); got != tt.want {
+			t.Fatalf("%s: FilterOut = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := Reduce(New([]error{errKeep})); got != errKeep {
+		t.Fatalf("Reduce of one = %v; want %v", got, errKeep)
+	}
+	if got := Reduce(errKeep); got != errKeep {
+		t.Fatalf("Reduce of a plain error = %v; want it unchanged", got)
+	}
+}
+
+func TestCollectAndGo(t *testing.T) {
+	ch := make(chan error)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 5; i++ {
+			if i%2 == 0 {
+				ch <- &fs.PathError{Op: "remove", Path: string(rune('a' + i)), Err: fs.ErrPermission}
+			} else {
+				ch <- nil
+			}
+		}
+	}()
+	agg := Collect(ch)
+	if n := len(agg.Errors()); n != 3 {
+		t.Fatalf("Collect kept %d errors; want 3", n)
+	}
+	if !errors.Is(agg, fs.ErrPermission) {
+		t.Fatal("errors.Is does not see collected errors")
+	}
+
+	errSlow := errors.New("slow")
+	agg = Go(
+		func() error { return nil },
+		func() error { return errSlow },
+		func() error { return fs.ErrClosed },
+	)
+	if got, want := agg.Error(), "[slow, file already closed]"; got != want {
+		t.Fatalf("Go = %q; want %q", got, want)
+	}
+	if Go(func() error { return nil }) != nil {
+		t.Fatal("Go with no failures is not nil")
+	}
+}
@@ -0,0 +1,81 @@
+// Package backoff holds the delay maths from
+// 07-error-handling/05-retry-backoff-patterns so that queues and retry
+// helpers compute backoff the same way the lesson does.
+//
+//	retryWithBackoff: delay = 2^(attempt-1) * baseDelay
+//	retryWithJitter:  delay = 2^(attempt-1) * jitter * baseDelay
+//	                  jitter in [0.5, 1.5)
+//
+// It also has the jitter variants from the AWS "Exponential Backoff And
+// Jitter" article, which spread retries further apart than the lesson's
+// ±50%: FullJitter, EqualJitter and Decorrelated.
+package backoff
+
+import (
+	"math"
+	"time"
+)
+
+// maxDuration is the largest representable time.Duration.
+const maxDuration = time.Duration(math.MaxInt64)
+
+// Exponential returns 2^(attempt-1) * base, the retryWithBackoff delay.
+//
+// attempt is 1-based. The result never exceeds max (when max > 0) and
+// never overflows, no matter how large attempt grows.
+func Exponential(base time.Duration, attempt int, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	exp := math.Pow(2, float64(attempt-1))
+	return clamp(exp*float64(base), max)
+}
+
+// Jitter scales d by a factor in [0.5, 1.5), the retryWithJitter
+// spread. r must be in [0, 1), typically rand.Float64().
+func Jitter(d time.Duration, r float64, max time.Duration) time.Duration {
+	jitter := r + 0.5 // 0.5x – 1.5x
+	return clamp(jitter*float64(d), max)
+}
+
+// ExponentialJitter combines Exponential and Jitter exactly like
+// retryWithJitter does.
+func ExponentialJitter(base time.Duration, attempt int, r float64, max time.Duration) time.Duration {
+	return Jitter(Exponential(base, attempt, 0), r, max)
+}
+
+func clamp(d float64, max time.Duration) time.Duration {
+	out := maxDuration
+	if d < float64(maxDuration) {
+		out = time.Duration(d)
+	}
+	if max > 0 && out > max {
+		return max
+	}
+	return out
+}
+
+// FullJitter returns a delay uniformly spread over [0, Exponential).
+// r must be in [0, 1).
+func FullJitter(base time.Duration, attempt int, r float64, max time.Duration) time.Duration {
+	return clamp(r*float64(Exponential(base, attempt, max)), max)
+}
+
+// EqualJitter keeps half of the exponential delay and spreads the other
+// half, so a retry never comes sooner than Exponential/2.
+func EqualJitter(base time.Duration, attempt int, r float64, max time.Duration) time.Duration {
+	half := float64(Exponential(base, attempt, max)) / 2
+	return clamp(half+r*half, max)
+}
+
+// Decorrelated returns a delay in [base, 3*prev), growing from the
+// previous delay rather than from the attempt number. prev <= 0 means
+// this is the first retry.
+func Decorrelated(base, prev time.Duration, r float64, max time.Duration) time.Duration {
+	if prev < base {
+		prev = base
+	}
+	upper := 3 * float64(prev)
+	return clamp(float64(base)+r*(upper-float64(base)), max)
+}
@@ -0,0 +1,90 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponential(t *testing.T) {
+	base := 100 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		attempt int
+		max     time.Duration
+		want    time.Duration
+	}{
+		{"first attempt", 1, 0, 100 * time.Millisecond},
+		{"second attempt", 2, 0, 200 * time.Millisecond},
+		{"fourth attempt", 4, 0, 800 * time.Millisecond},
+		{"zero attempt treated as first", 0, 0, 100 * time.Millisecond},
+		{"capped", 10, time.Second, time.Second},
+		{"huge attempt does not overflow", 10000, 0, maxDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Exponential(base, tt.attempt, tt.max)
+			if got != tt.want {
+				t.Fatalf("Exponential(%v, %d, %v) = %v; want %v",
+					base, tt.attempt, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	d := time.Second
+
+	tests := []struct {
+		name string
+		r    float64
+		want time.Duration
+	}{
+		{"lowest", 0, 500 * time.Millisecond},
+		{"middle", 0.5, time.Second},
+		{"near highest", 0.75, 1250 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Jitter(d, tt.r, 0); got != tt.want {
+				t.Fatalf("Jitter(%v, %v) = %v; want %v", d, tt.r, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExponentialJitter_Capped(t *testing.T) {
+	got := ExponentialJitter(100*time.Millisecond, 20, 0.99, time.Minute)
+	if got != time.Minute {
+		t.Fatalf("ExponentialJitter = %v; want %v", got, time.Minute)
+	}
+}
+
+func TestJitterVariants(t *testing.T) {
+	base := 100 * time.Millisecond
+
+	tests := []struct {
+		name string
+		got  time.Duration
+		want time.Duration
+	}{
+		{"full jitter lowest", FullJitter(base, 3, 0, 0), 0},
+		{"full jitter middle", FullJitter(base, 3, 0.5, 0), 200 * time.Millisecond},
+		{"equal jitter lowest", EqualJitter(base, 3, 0, 0), 200 * time.Millisecond},
+		{"equal jitter middle", EqualJitter(base, 3, 0.5, 0), 300 * time.Millisecond},
+		{"equal jitter stays under max", EqualJitter(base, 30, 0.99, time.Second), 995 * time.Millisecond},
+		{"decorrelated first retry", Decorrelated(base, 0, 0.5, 0), 200 * time.Millisecond},
+		{"decorrelated grows from prev", Decorrelated(base, time.Second, 0.5, 0), 1550 * time.Millisecond},
+		{"decorrelated capped", Decorrelated(base, time.Hour, 0.99, time.Minute), time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Fatalf("got %v; want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
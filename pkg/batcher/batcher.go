@@ -0,0 +1,376 @@
+// Package batcher groups items and hands them to a flush function in
+// bulk.
+//
+// The worker in 05-concurrency/06-patterns processes exactly one Job at
+// a time, but writing a CSV file or calling a bulk API is far cheaper
+// for a hundred rows than for one. A Batcher flushes when EITHER:
+//
+//   - MaxSize items are waiting (size trigger), or
+//   - MaxWait has passed since the oldest waiting item (time trigger)
+//
+// whichever comes first. The time trigger bounds latency when traffic
+// is low; the size trigger bounds memory and request size when it is
+// high. Shutdown and Close flush whatever is left.
+//
+// A flush may fail for only some items. Returning a *PartialError puts
+// just those items back for the next flush; everything else is done.
+// After a failed flush the Batcher waits out a retry.Backoff before
+// flushing again, so a struggling backend is not hammered.
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/retry"
+)
+
+// ==========================================================
+// 1. ERRORS
+// ==========================================================
+
+// ErrClosed is returned by Add once Close has been called.
+var ErrClosed = errors.New("batcher: batcher is closed")
+
+// PartialError reports which items of a batch failed. Failed maps an
+// index into the flushed batch to that item's error; items not listed
+// succeeded.
+type PartialError struct {
+	Failed map[int]error
+}
+
+func (e *PartialError) Error() string {
+	idx := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+
+	parts := make([]string, len(idx))
+	for n, i := range idx {
+		parts[n] = fmt.Sprintf("item %d: %v", i, e.Failed[i])
+	}
+	return fmt.Sprintf("batcher: %d item(s) failed: %s", len(idx), strings.Join(parts, "; "))
+}
+
+// ==========================================================
+// 2. CONFIGURATION
+// ==========================================================
+
+// FlushFunc writes one batch. A nil error means every item succeeded,
+// a *PartialError means only the listed items failed, and any other
+// error fails the whole batch. ctx is cancelled when a Shutdown's
+// context ends before the Batcher has drained.
+type FlushFunc[T any] func(ctx context.Context, batch []T) error
+
+// Config configures a Batcher.
+type Config[T any] struct {
+	// MaxSize flushes as soon as this many items are waiting.
+	// Defaults to 100.
+	MaxSize int
+
+	// MaxWait flushes once the oldest waiting item is this old.
+	// Defaults to 1s.
+	MaxWait time.Duration
+
+	// MaxAttempts is how many flushes an item gets before it is given
+	// up on and passed to OnDrop. Defaults to 3.
+	MaxAttempts int
+
+	// Backoff is the wait after a failed flush before the next one,
+	// counted in consecutive failed flushes. Items keep arriving
+	// meanwhile, up to MaxSize, after which Add blocks. Defaults to
+	// retry.Exponential(100ms, 10s).
+	Backoff retry.Backoff
+
+	// OnDrop, if set, receives every item that used up MaxAttempts, or
+	// was still waiting when a Shutdown's context ended, along with its
+	// last error.
+	OnDrop func(item T, err error)
+
+	// QueueSize is the capacity of the channel between Add and the
+	// batching goroutine. Defaults to MaxSize.
+	QueueSize int
+
+	// Clock drives the time trigger. Defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c Config[T]) withDefaults() Config[T] {
+	if c.MaxSize <= 0 {
+		c.MaxSize = 100
+	}
+	if c.MaxWait <= 0 {
+		c.MaxWait = time.Second
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.Backoff == nil {
+		c.Backoff = retry.Exponential(100*time.Millisecond, 10*time.Second)
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = c.MaxSize
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+// ==========================================================
+// 3. BATCHER
+// ==========================================================
+
+// entry is one waiting item and how many flushes it has been through.
+type entry[T any] struct {
+	item     T
+	attempts int
+}
+
+// Batcher collects items from Add and flushes them from one goroutine,
+// so FlushFunc never runs concurrently with itself.
+type Batcher[T any] struct {
+	flush FlushFunc[T]
+	cfg   Config[T]
+
+	in   chan T
+	done chan struct{}
+
+	// ctx is passed to every flush. Shutdown cancels it, with its own
+	// context's error as the cause, if draining takes too long.
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	// mu guards closed and the close of in, like the pool's submitMu:
+	// Add must never send on a closed channel.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New starts a Batcher that hands batches to flush.
+func New[T any](flush FlushFunc[T], cfg Config[T]) *Batcher[T] {
+	cfg = cfg.withDefaults()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	b := &Batcher[T]{
+		flush:  flush,
+		cfg:    cfg,
+		in:     make(chan T, cfg.QueueSize),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go b.loop()
+	return b
+}
+
+// Add queues item for the next batch. It blocks while the queue is
+// full, until ctx is done or a Shutdown gives up.
+func (b *Batcher[T]) Add(ctx context.Context, item T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+	select {
+	case b.in <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.ctx.Done():
+		return ErrClosed
+	}
+}
+
+// Shutdown stops accepting items and flushes everything still waiting,
+// retrying failed items up to MaxAttempts with the usual backoff. If
+// ctx ends first, the flush in progress has its context cancelled, the
+// items still waiting go to OnDrop, and Shutdown returns ctx.Err() once
+// the last flush has returned.
+func (b *Batcher[T]) Shutdown(ctx context.Context) error {
+	// Registered first: an Add blocked on a full queue holds mu, and
+	// only gives it up once b.ctx is cancelled.
+	stop := context.AfterFunc(ctx, func() { b.cancel(ctx.Err()) })
+
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.in)
+	}
+	b.mu.Unlock()
+
+	<-b.done
+	if !stop() {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Close is Shutdown without a deadline: it returns once every item has
+// been flushed or dropped.
+func (b *Batcher[T]) Close() {
+	b.Shutdown(context.Background())
+}
+
+// loop owns the pending buffer and its one timer, which is either the
+// MaxWait trigger or, after a failed flush, the backoff.
+func (b *Batcher[T]) loop() {
+	defer close(b.done)
+
+	var (
+		pending    []entry[T]
+		timer      clock.Timer
+		expired    <-chan time.Time // nil while no timer runs
+		backingOff bool             // timer is the backoff, not MaxWait
+		failures   int              // consecutive failed flushes
+		delay      time.Duration    // the last backoff
+	)
+
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, expired = nil, nil
+		}
+	}
+	startTimer := func() {
+		if timer == nil && len(pending) > 0 {
+			timer = b.cfg.Clock.NewTimer(b.cfg.MaxWait)
+			expired = timer.C()
+		}
+	}
+	// flush flushes once and, if that failed and items are left, starts
+	// the backoff. No flush happens until it expires.
+	flush := func() {
+		stopTimer()
+		var failed bool
+		pending, failed = b.flushOnce(pending)
+		backingOff = false
+		if !failed {
+			failures, delay = 0, 0
+			return
+		}
+		failures++
+		delay = b.cfg.Backoff.Delay(failures, delay, rand.Float64())
+		if len(pending) > 0 {
+			timer = b.cfg.Clock.NewTimer(delay)
+			expired, backingOff = timer.C(), true
+		}
+	}
+
+	for {
+		// While backing off with a full batch waiting, stop taking
+		// items so Add applies backpressure.
+		in := b.in
+		if backingOff && len(pending) >= b.cfg.MaxSize {
+			in = nil
+		}
+
+		select {
+		case item, ok := <-in:
+			if !ok {
+				// Shutdown: keep flushing, backing off between
+				// failures, until every item has either succeeded or
+				// been dropped, or Shutdown gives up.
+				for len(pending) > 0 {
+					if backingOff {
+						select {
+						case <-expired:
+						case <-b.ctx.Done():
+						}
+					}
+					if b.ctx.Err() != nil {
+						stopTimer()
+						b.drop(pending)
+						return
+					}
+					flush()
+				}
+				stopTimer()
+				return
+			}
+			pending = append(pending, entry[T]{item: item})
+			if !backingOff && len(pending) >= b.cfg.MaxSize {
+				flush()
+			}
+			startTimer()
+
+		case <-expired:
+			timer, expired = nil, nil
+			flush()
+			startTimer()
+
+		case <-b.ctx.Done():
+			// Shutdown gave up while Add was blocked by the backoff.
+			stopTimer()
+			b.drop(pending)
+			return
+		}
+	}
+}
+
+// flushOnce flushes up to MaxSize of the oldest entries and returns
+// what is still waiting: the unflushed tail plus any retried failures.
+// Retried items keep their place at the front of the next batch.
+// failed reports whether the flush returned an error.
+func (b *Batcher[T]) flushOnce(pending []entry[T]) (left []entry[T], failed bool) {
+	n := min(len(pending), b.cfg.MaxSize)
+	batch, rest := pending[:n], pending[n:]
+
+	items := make([]T, n)
+	for i, e := range batch {
+		items[i] = e.item
+	}
+
+	err := b.flush(b.ctx, items)
+	if err == nil {
+		return rest, false
+	}
+
+	var pErr *PartialError
+	itemFailed := func(i int) error { return err }
+	if errors.As(err, &pErr) {
+		itemFailed = func(i int) error { return pErr.Failed[i] }
+	}
+
+	var again []entry[T]
+	for i, e := range batch {
+		itemErr := itemFailed(i)
+		if itemErr == nil {
+			continue
+		}
+		e.attempts++
+		if e.attempts >= b.cfg.MaxAttempts {
+			if b.cfg.OnDrop != nil {
+				b.cfg.OnDrop(e.item, itemErr)
+			}
+			continue
+		}
+		again = append(again, e)
+	}
+	return append(again, rest...), true
+}
+
+// drop hands every pending entry, and every item still queued after
+// Shutdown closed the queue, to OnDrop with the reason Shutdown gave
+// up.
+func (b *Batcher[T]) drop(pending []entry[T]) {
+	err := context.Cause(b.ctx)
+	for _, e := range pending {
+		if b.cfg.OnDrop != nil {
+			b.cfg.OnDrop(e.item, err)
+		}
+	}
+	for item := range b.in {
+		if b.cfg.OnDrop != nil {
+			b.cfg.OnDrop(item, err)
+		}
+	}
+}
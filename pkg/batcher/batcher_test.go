@@ -0,0 +1,276 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/retry"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// recorder is a FlushFunc that hands every batch to the test.
+type recorder struct {
+	batches chan []int
+	fail    func(batch []int) error
+}
+
+func newRecorder() *recorder {
+	return &recorder{batches: make(chan []int, 16)}
+}
+
+func (r *recorder) flush(_ context.Context, batch []int) error {
+	r.batches <- append([]int(nil), batch...)
+	if r.fail != nil {
+		return r.fail(batch)
+	}
+	return nil
+}
+
+func (r *recorder) next(t *testing.T) []int {
+	t.Helper()
+	select {
+	case b := <-r.batches:
+		return b
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a flush")
+		return nil
+	}
+}
+
+func (r *recorder) none(t *testing.T) {
+	t.Helper()
+	select {
+	case b := <-r.batches:
+		t.Fatalf("unexpected flush of %v", b)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func addAll(t *testing.T, b *Batcher[int], items ...int) {
+	t.Helper()
+	for _, item := range items {
+		if err := b.Add(context.Background(), item); err != nil {
+			t.Fatalf("Add(%d) returned %v", item, err)
+		}
+	}
+}
+
+func waitForWaiters(t *testing.T, c *clock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a clock waiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatcher_FlushesOnSize(t *testing.T) {
+	rec := newRecorder()
+	fake := clock.NewFakeClock(epoch)
+	b := New(rec.flush, Config[int]{MaxSize: 3, MaxWait: time.Minute, Clock: fake})
+	defer b.Close()
+
+	addAll(t, b, 1, 2, 3, 4)
+
+	if got, want := rec.next(t), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("batch = %v; want %v", got, want)
+	}
+	rec.none(t)
+}
+
+func TestBatcher_FlushesOnTime(t *testing.T) {
+	rec := newRecorder()
+	fake := clock.NewFakeClock(epoch)
+	b := New(rec.flush, Config[int]{MaxSize: 10, MaxWait: time.Second, Clock: fake})
+	defer b.Close()
+
+	addAll(t, b, 1, 2)
+	waitForWaiters(t, fake)
+	rec.none(t)
+
+	fake.Step(time.Second)
+	if got, want := rec.next(t), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("batch = %v; want %v", got, want)
+	}
+}
+
+func TestBatcher_CloseFlushesRemainder(t *testing.T) {
+	rec := newRecorder()
+	b := New(rec.flush, Config[int]{MaxSize: 10, MaxWait: time.Hour})
+
+	addAll(t, b, 1, 2, 3)
+	b.Close()
+
+	if got, want := rec.next(t), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("batch = %v; want %v", got, want)
+	}
+	if err := b.Add(context.Background(), 4); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Add after Close = %v; want %v", err, ErrClosed)
+	}
+}
+
+func TestBatcher_RetriesOnlyFailedItems(t *testing.T) {
+	errBad := errors.New("bad row")
+	rec := newRecorder()
+	var once sync.Once
+	rec.fail = func(batch []int) error {
+		var err error
+		once.Do(func() {
+			// Item at index 1 (the value 2) fails on the first flush only.
+			err = &PartialError{Failed: map[int]error{1: errBad}}
+		})
+		return err
+	}
+
+	fake := clock.NewFakeClock(epoch)
+	b := New(rec.flush, Config[int]{MaxSize: 3, MaxWait: time.Second, Backoff: retry.Constant(500 * time.Millisecond), Clock: fake})
+	defer b.Close()
+
+	addAll(t, b, 1, 2, 3)
+	if got, want := rec.next(t), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("first batch = %v; want %v", got, want)
+	}
+
+	// The failed item is retried once the backoff has passed.
+	waitForWaiters(t, fake)
+	fake.Step(500 * time.Millisecond)
+	if got, want := rec.next(t), []int{2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("retry batch = %v; want %v", got, want)
+	}
+}
+
+func TestBatcher_DropsAfterMaxAttempts(t *testing.T) {
+	errDown := errors.New("backend down")
+	rec := newRecorder()
+	rec.fail = func([]int) error { return errDown }
+
+	var mu sync.Mutex
+	dropped := map[int]error{}
+	b := New(rec.flush, Config[int]{
+		MaxSize:     2,
+		MaxAttempts: 2,
+		OnDrop: func(item int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dropped[item] = err
+		},
+	})
+
+	addAll(t, b, 1, 2)
+	b.Close()
+
+	// One size-triggered flush, one retry on Close, then both dropped.
+	rec.next(t)
+	rec.next(t)
+	rec.none(t)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) != 2 || !errors.Is(dropped[1], errDown) || !errors.Is(dropped[2], errDown) {
+		t.Fatalf("dropped = %v; want items 1 and 2 with %v", dropped, errDown)
+	}
+}
+
+func TestBatcher_BacksOffBetweenFailedFlushes(t *testing.T) {
+	errDown := errors.New("backend down")
+	rec := newRecorder()
+	rec.fail = func([]int) error { return errDown }
+
+	fake := clock.NewFakeClock(epoch)
+	b := New(rec.flush, Config[int]{
+		MaxSize:     2,
+		MaxWait:     time.Hour,
+		MaxAttempts: 10,
+		Backoff:     retry.Exponential(time.Second, time.Minute),
+		Clock:       fake,
+	})
+
+	addAll(t, b, 1, 2)
+	rec.next(t)
+
+	// While a full batch waits out the backoff, new items only fill
+	// the queue, and then Add blocks until Shutdown gives up.
+	addAll(t, b, 3, 4)
+	blocked := make(chan error, 1)
+	go func() { blocked <- b.Add(context.Background(), 5) }()
+	for _, d := range []time.Duration{time.Second, 2 * time.Second} {
+		waitForWaiters(t, fake)
+		fake.Step(d - time.Millisecond)
+		rec.none(t)
+		fake.Step(time.Millisecond)
+		if got, want := rec.next(t), []int{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("retry after %v = %v; want %v", d, got, want)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v; want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-blocked; !errors.Is(err, ErrClosed) {
+		t.Fatalf("blocked Add = %v; want %v", err, ErrClosed)
+	}
+}
+
+func TestBatcher_ShutdownGivesUpWhenCtxEnds(t *testing.T) {
+	flushed := make(chan struct{}, 1)
+	flush := func(ctx context.Context, _ []int) error {
+		flushed <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	var mu sync.Mutex
+	dropped := map[int]error{}
+	b := New(flush, Config[int]{
+		MaxSize: 10,
+		MaxWait: time.Hour,
+		OnDrop: func(item int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dropped[item] = err
+		},
+	})
+	addAll(t, b, 1, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- b.Shutdown(ctx) }()
+	<-flushed
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Shutdown = %v; want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not return after its ctx ended")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) != 2 || !errors.Is(dropped[1], context.DeadlineExceeded) {
+		t.Fatalf("dropped = %v; want items 1 and 2 with %v", dropped, context.DeadlineExceeded)
+	}
+}
+
+func TestPartialError_Message(t *testing.T) {
+	err := &PartialError{Failed: map[int]error{
+		4: errors.New("too long"),
+		0: errors.New("empty"),
+	}}
+	want := "batcher: 2 item(s) failed: item 0: empty; item 4: too long"
+	if got := err.Error(); got != want {
+		t.Fatalf("Error() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,360 @@
+// Package breaker stops calling a dependency that is clearly down.
+//
+// Retrying flakyOperation (07-error-handling/05-retry-backoff-patterns)
+// helps when failures are rare and short. When the dependency is down,
+// every caller retrying just adds load to something that cannot answer.
+// A circuit breaker notices and fails fast instead:
+//
+//	CLOSED ──(too many failures)──▶ OPEN ──(OpenTimeout)──▶ HALF-OPEN
+//	   ▲                                ▲                       │
+//	   └──────(probes succeed)──────────┼───────────────────────┤
+//	                                    └────(a probe fails)────┘
+//
+// In each state:
+//
+//   - Closed: calls go through; failures are counted.
+//   - Open: calls fail immediately with ErrCircuitOpen.
+//   - Half-open: a few probe calls test whether the dependency is back.
+//
+// Only errors that the retry classification treats as failures count.
+// A permanent error such as "not found" means the dependency answered,
+// so it counts as a success for the breaker.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/internal/window"
+	"go-systems-learning/pkg/retry"
+)
+
+// ==========================================================
+// 1. STATES & ERRORS
+// ==========================================================
+
+// ErrCircuitOpen is returned instead of calling the dependency while
+// the breaker is open, or half-open with every probe slot taken.
+var ErrCircuitOpen = errors.New("breaker: circuit open")
+
+// State is the breaker's position.
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "Closed"
+	case StateOpen:
+		return "Open"
+	case StateHalfOpen:
+		return "HalfOpen"
+	default:
+		return "Unknown"
+	}
+}
+
+// ==========================================================
+// 2. CONFIGURATION
+// ==========================================================
+
+// Config configures a Breaker. The breaker trips on whichever rule
+// fires first.
+type Config struct {
+	// Name identifies the breaker in callbacks and errors.
+	Name string
+
+	// ConsecutiveFailures trips the breaker after this many failures in
+	// a row. Defaults to 5; negative disables the rule.
+	ConsecutiveFailures int
+
+	// FailureRatio trips the breaker when failures/calls over Window
+	// reaches it, once at least MinCalls were made. Zero disables the
+	// rule.
+	FailureRatio float64
+	MinCalls     int           // defaults to 10
+	Window       time.Duration // defaults to 10s
+	Buckets      int           // window granularity, defaults to 10
+
+	// OpenTimeout is how long the breaker stays open before probing.
+	// Defaults to 30s.
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is how many calls may run while half-open. That
+	// many successes close the breaker; one failure reopens it.
+	// Defaults to 1.
+	HalfOpenProbes int
+
+	// ProbeTimeout is how long a probe may run before it counts as a
+	// failure and reopens the breaker, so a hung probe or a done that
+	// is never called cannot hold a probe slot forever. Defaults to
+	// OpenTimeout.
+	ProbeTimeout time.Duration
+
+	// IsFailure decides which errors count against the breaker.
+	// Defaults to retry.DefaultRetryable, ignoring context.Canceled
+	// (the caller gave up; the dependency did nothing wrong). Only
+	// errors marked with retry.Permanent are then ignored: a plain
+	// sentinel such as the lesson's ErrPermanentFailure counts as a
+	// failure unless IsFailure says otherwise.
+	IsFailure retry.Classifier
+
+	// OnStateChange, if set, is called after every transition, outside
+	// the breaker's lock.
+	OnStateChange func(name string, from, to State)
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c Config) withDefaults() Config {
+	if c.ConsecutiveFailures == 0 {
+		c.ConsecutiveFailures = 5
+	}
+	if c.MinCalls <= 0 {
+		c.MinCalls = 10
+	}
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.Buckets <= 0 {
+		c.Buckets = 10
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = 1
+	}
+	if c.ProbeTimeout <= 0 {
+		c.ProbeTimeout = c.OpenTimeout
+	}
+	if c.IsFailure == nil {
+		c.IsFailure = defaultIsFailure
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+func defaultIsFailure(err error) bool {
+	return !errors.Is(err, context.Canceled) && retry.DefaultRetryable(err)
+}
+
+// errPanicked is recorded for a call that panicked. It always counts
+// as a failure, whatever IsFailure says.
+var errPanicked = errors.New("breaker: call panicked")
+
+// ==========================================================
+// 3. BREAKER
+// ==========================================================
+
+// Breaker guards calls to one dependency. It is safe for concurrent
+// use.
+type Breaker struct {
+	cfg Config
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64 // bumped on every transition; stale results are ignored
+	openedAt    time.Time
+	consecutive int                  // consecutive failures while closed
+	probes      map[uint64]time.Time // start of each probe in flight while half-open
+	probeSeq    uint64
+	probeOK     int             // successful probes while half-open
+	calls       *window.Counter // outcomes while closed, over Config.Window
+	failures    *window.Counter
+	pending     []transition // callbacks to fire once unlocked
+}
+
+type transition struct{ from, to State }
+
+// New returns a closed Breaker.
+func New(cfg Config) *Breaker {
+	cfg = cfg.withDefaults()
+	return &Breaker{
+		cfg:      cfg,
+		calls:    window.New(cfg.Window, cfg.Buckets),
+		failures: window.New(cfg.Window, cfg.Buckets),
+		probes:   make(map[uint64]time.Time),
+	}
+}
+
+// State returns the current state, moving open to half-open once
+// OpenTimeout has passed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	b.refreshLocked(b.cfg.Clock.Now())
+	s := b.state
+	b.unlock()
+	return s
+}
+
+// Do runs fn if the breaker allows it and records the outcome. A
+// panic in fn is recorded as a failure and then propagates.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	returned := false
+	defer func() {
+		if !returned {
+			done(errPanicked)
+		}
+	}()
+	err = fn(ctx)
+	returned = true
+	done(err)
+	return err
+}
+
+// Allow reserves a call. On success the caller must run the call and
+// pass its error to done exactly once. A half-open probe whose done is
+// not called within ProbeTimeout counts as failed.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	now := b.cfg.Clock.Now()
+	b.refreshLocked(now)
+	var probe uint64
+	switch b.state {
+	case StateOpen:
+		return nil, b.openError()
+	case StateHalfOpen:
+		if len(b.probes) >= b.cfg.HalfOpenProbes {
+			return nil, b.openError()
+		}
+		b.probeSeq++
+		probe = b.probeSeq
+		b.probes[probe] = now
+	}
+
+	gen := b.generation
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.record(gen, probe, err) })
+	}, nil
+}
+
+func (b *Breaker) openError() error {
+	if b.cfg.Name == "" {
+		return ErrCircuitOpen
+	}
+	return fmt.Errorf("%w: %s", ErrCircuitOpen, b.cfg.Name)
+}
+
+func (b *Breaker) record(gen, probe uint64, err error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	now := b.cfg.Clock.Now()
+	b.refreshLocked(now)
+	if gen != b.generation {
+		// The call started in an earlier state; its outcome says
+		// nothing about the current one.
+		return
+	}
+	failed := err == errPanicked || (err != nil && b.cfg.IsFailure(err))
+
+	switch b.state {
+	case StateClosed:
+		b.calls.Add(now, 1)
+		if failed {
+			b.failures.Add(now, 1)
+		}
+		if !failed {
+			b.consecutive = 0
+			return
+		}
+		b.consecutive++
+		if b.shouldTripLocked(now) {
+			b.setStateLocked(StateOpen, now)
+		}
+
+	case StateHalfOpen:
+		delete(b.probes, probe)
+		if failed {
+			b.setStateLocked(StateOpen, now)
+			return
+		}
+		b.probeOK++
+		if b.probeOK >= b.cfg.HalfOpenProbes {
+			b.setStateLocked(StateClosed, now)
+		}
+	}
+}
+
+func (b *Breaker) shouldTripLocked(now time.Time) bool {
+	if b.cfg.ConsecutiveFailures > 0 && b.consecutive >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+	if b.cfg.FailureRatio <= 0 {
+		return false
+	}
+	calls, failures := b.calls.Sum(now), b.failures.Sum(now)
+	return calls >= float64(b.cfg.MinCalls) && failures/calls >= b.cfg.FailureRatio
+}
+
+// refreshLocked applies the time-based transitions: open → half-open
+// after OpenTimeout, and half-open → open when a probe outlives
+// ProbeTimeout.
+func (b *Breaker) refreshLocked(now time.Time) {
+	switch b.state {
+	case StateOpen:
+		if !now.Before(b.openedAt.Add(b.cfg.OpenTimeout)) {
+			b.setStateLocked(StateHalfOpen, now)
+		}
+	case StateHalfOpen:
+		for _, started := range b.probes {
+			if !now.Before(started.Add(b.cfg.ProbeTimeout)) {
+				b.setStateLocked(StateOpen, now)
+				return
+			}
+		}
+	}
+}
+
+func (b *Breaker) setStateLocked(to State, now time.Time) {
+	from := b.state
+	if from == to {
+		return
+	}
+
+	b.state = to
+	b.generation++
+	b.consecutive, b.probeOK = 0, 0
+	clear(b.probes)
+	b.calls.Reset()
+	b.failures.Reset()
+	if to == StateOpen {
+		b.openedAt = now
+	}
+	b.pending = append(b.pending, transition{from: from, to: to})
+}
+
+// unlock releases the lock and then fires queued callbacks, so a
+// callback may call back into the breaker.
+func (b *Breaker) unlock() {
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if b.cfg.OnStateChange == nil {
+		return
+	}
+	for _, t := range pending {
+		b.cfg.OnStateChange(b.cfg.Name, t.from, t.to)
+	}
+}
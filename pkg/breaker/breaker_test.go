@@ -0,0 +1,222 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/retry"
+)
+
+var (
+	epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	errTemporary = errors.New("temporary failure")
+	errNotFound  = retry.Permanent(errors.New("not found"))
+)
+
+func call(b *Breaker, err error) error {
+	return b.Do(context.Background(), func(context.Context) error { return err })
+}
+
+func TestBreaker_TripsOnConsecutiveFailures(t *testing.T) {
+	b := New(Config{ConsecutiveFailures: 3, Clock: clock.NewFakeClock(epoch)})
+
+	call(b, errTemporary)
+	call(b, errTemporary)
+	call(b, nil) // resets the run
+	call(b, errTemporary)
+	call(b, errTemporary)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("State after broken run = %v; want %v", got, StateClosed)
+	}
+
+	call(b, errTemporary)
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("State after 3 failures = %v; want %v", got, StateOpen)
+	}
+
+	ran := false
+	err := b.Do(context.Background(), func(context.Context) error { ran = true; return nil })
+	if !errors.Is(err, ErrCircuitOpen) || ran {
+		t.Fatalf("Do while open = %v (ran %v); want %v without running", err, ran, ErrCircuitOpen)
+	}
+}
+
+func TestBreaker_TripsOnFailureRatio(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	b := New(Config{
+		ConsecutiveFailures: -1,
+		FailureRatio:        0.5,
+		MinCalls:            4,
+		Window:              10 * time.Second,
+		Buckets:             10,
+		Clock:               fake,
+	})
+
+	// Half the calls fail, but the old ones slide out of the window.
+	call(b, errTemporary)
+	call(b, errTemporary)
+	fake.Step(20 * time.Second)
+	call(b, nil)
+	call(b, nil)
+	call(b, errTemporary)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("State at 1/3 failures = %v; want %v", got, StateClosed)
+	}
+
+	call(b, errTemporary) // 2 of 4
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("State at 2/4 failures = %v; want %v", got, StateOpen)
+	}
+}
+
+func TestBreaker_OnlyClassifiedFailuresCount(t *testing.T) {
+	b := New(Config{ConsecutiveFailures: 2, Clock: clock.NewFakeClock(epoch)})
+
+	for i := 0; i < 5; i++ {
+		call(b, errNotFound)
+		call(b, context.Canceled)
+	}
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("State after permanent errors = %v; want %v", got, StateClosed)
+	}
+}
+
+func TestBreaker_HalfOpenProbes(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	var transitions []string
+	b := New(Config{
+		Name:                "db",
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Minute,
+		HalfOpenProbes:      2,
+		Clock:               fake,
+		OnStateChange: func(name string, from, to State) {
+			transitions = append(transitions, fmt.Sprintf("%s:%v->%v", name, from, to))
+		},
+	})
+
+	call(b, errTemporary)
+	fake.Step(time.Minute)
+	if got := b.State(); got != StateHalfOpen {
+		t.Fatalf("State after OpenTimeout = %v; want %v", got, StateHalfOpen)
+	}
+
+	// Only two probes may be in flight.
+	done1, err1 := b.Allow()
+	done2, err2 := b.Allow()
+	if err1 != nil || err2 != nil {
+		t.Fatalf("probe Allow = %v, %v; want both allowed", err1, err2)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("third Allow = %v; want %v", err, ErrCircuitOpen)
+	}
+
+	done1(nil)
+	if got := b.State(); got != StateHalfOpen {
+		t.Fatalf("State after one probe = %v; want %v", got, StateHalfOpen)
+	}
+	done2(nil)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("State after two probes = %v; want %v", got, StateClosed)
+	}
+
+	want := []string{"db:Closed->Open", "db:Open->HalfOpen", "db:HalfOpen->Closed"}
+	if fmt.Sprint(transitions) != fmt.Sprint(want) {
+		t.Fatalf("transitions = %v; want %v", transitions, want)
+	}
+}
+
+func TestBreaker_FailedProbeReopens(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	b := New(Config{ConsecutiveFailures: 1, OpenTimeout: time.Minute, Clock: fake})
+
+	call(b, errTemporary)
+	fake.Step(time.Minute)
+	call(b, errTemporary)
+
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("State after failed probe = %v; want %v", got, StateOpen)
+	}
+	// The open period restarts from the failed probe.
+	fake.Step(59 * time.Second)
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("State before new timeout = %v; want %v", got, StateOpen)
+	}
+}
+
+func TestBreaker_IgnoresResultsFromEarlierState(t *testing.T) {
+	b := New(Config{ConsecutiveFailures: 1, Clock: clock.NewFakeClock(epoch)})
+
+	slow, _ := b.Allow()
+	call(b, errTemporary) // trips
+	slow(nil)             // started while closed; must not count
+
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("State = %v; want %v", got, StateOpen)
+	}
+}
+
+func TestBreaker_PanickingProbeReopens(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	b := New(Config{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Minute,
+		IsFailure:           func(err error) bool { return errors.Is(err, errTemporary) },
+		Clock:               fake,
+	})
+	call(b, errTemporary)
+	fake.Step(time.Minute)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recovered %v; want the probe's panic to propagate", r)
+			}
+		}()
+		b.Do(context.Background(), func(context.Context) error { panic("boom") })
+	}()
+
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("State after panicking probe = %v; want %v", got, StateOpen)
+	}
+	fake.Step(time.Minute)
+	if err := call(b, nil); err != nil || b.State() != StateClosed {
+		t.Fatalf("probe after reopening = %v, state %v; want the breaker to recover", err, b.State())
+	}
+}
+
+func TestBreaker_AbandonedProbeExpires(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	b := New(Config{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Minute,
+		ProbeTimeout:        10 * time.Second,
+		Clock:               fake,
+	})
+	call(b, errTemporary)
+	fake.Step(time.Minute)
+
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe Allow = %v", err)
+	}
+	_ = done // never called
+
+	fake.Step(9 * time.Second)
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) || b.State() != StateHalfOpen {
+		t.Fatalf("Allow with probe in flight = %v, state %v; want %v while half-open", err, b.State(), ErrCircuitOpen)
+	}
+	fake.Step(time.Second)
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("State after ProbeTimeout = %v; want %v", got, StateOpen)
+	}
+	fake.Step(time.Minute)
+	if err := call(b, nil); err != nil || b.State() != StateClosed {
+		t.Fatalf("probe after expiry = %v, state %v; want the breaker to recover", err, b.State())
+	}
+}
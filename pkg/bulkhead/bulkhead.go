@@ -0,0 +1,343 @@
+// Package bulkhead isolates dependencies from each other, like the
+// watertight compartments of a ship.
+//
+// Without it, every worker goroutine can end up blocked on one slow
+// database while calls to healthy dependencies starve. A bulkhead gives
+// each dependency its own WEIGHTED SEMAPHORE:
+//
+//   - at most Capacity units of work run against it at once
+//   - at most MaxQueue callers wait for a slot, for at most QueueTimeout
+//   - everyone else is rejected at once with ErrBulkheadFull
+//
+// Rejection is fast and typed, so a retry policy or breaker can see it
+// and back off instead of piling more goroutines onto the dependency.
+// A full queue or a queue timeout is transient and retried by
+// retry.DefaultRetryable; a call heavier than the whole bulkhead can
+// never fit, so that rejection is marked retry.Permanent.
+package bulkhead
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/retry"
+)
+
+// ==========================================================
+// 1. ERRORS
+// ==========================================================
+
+// ErrBulkheadFull is matched by every rejection.
+var ErrBulkheadFull = errors.New("bulkhead: full")
+
+// ErrInvalidWeight is returned for a weight that is not positive. A
+// negative weight would otherwise free capacity nobody holds.
+var ErrInvalidWeight = errors.New("bulkhead: weight must be positive")
+
+// Rejection reasons.
+const (
+	ReasonQueueFull    = "queue-full"
+	ReasonQueueTimeout = "queue-timeout"
+	ReasonTooHeavy     = "weight-exceeds-capacity"
+)
+
+// FullError describes a rejection. It matches ErrBulkheadFull.
+type FullError struct {
+	Name   string
+	Reason string
+	InUse  int64
+	Queued int
+}
+
+func (e *FullError) Error() string {
+	return fmt.Sprintf("bulkhead %q: full (%s, in use %d, queued %d)", e.Name, e.Reason, e.InUse, e.Queued)
+}
+
+func (e *FullError) Is(target error) bool { return target == ErrBulkheadFull }
+
+// ==========================================================
+// 2. CONFIGURATION
+// ==========================================================
+
+// Config configures a Bulkhead.
+type Config struct {
+	// Capacity is the total weight that may run at once. Defaults to 10.
+	Capacity int64
+
+	// MaxQueue is how many callers may wait for capacity. Zero means
+	// nobody waits: callers that do not fit are rejected at once.
+	MaxQueue int
+
+	// QueueTimeout bounds the wait. Zero means wait until ctx is done.
+	QueueTimeout time.Duration
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c Config) withDefaults() Config {
+	if c.Capacity <= 0 {
+		c.Capacity = 10
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+// Stats is a live snapshot, for metrics.
+type Stats struct {
+	Name     string
+	Capacity int64
+	InUse    int64
+	Queued   int
+	Rejected uint64
+}
+
+// ==========================================================
+// 3. BULKHEAD
+// ==========================================================
+
+type waiter struct {
+	weight int64
+	ready  chan struct{} // closed when the weight has been granted
+}
+
+// Bulkhead is a named weighted semaphore with a bounded FIFO queue. It
+// is safe for concurrent use.
+type Bulkhead struct {
+	name string
+	cfg  Config
+
+	mu       sync.Mutex
+	inUse    int64
+	waiters  list.List // of *waiter, oldest first
+	rejected uint64
+}
+
+// New returns an empty Bulkhead.
+func New(name string, cfg Config) *Bulkhead {
+	return &Bulkhead{name: name, cfg: cfg.withDefaults()}
+}
+
+// Name returns the dependency this bulkhead guards.
+func (b *Bulkhead) Name() string {
+	return b.name
+}
+
+// Do runs fn holding weight units of capacity.
+func (b *Bulkhead) Do(ctx context.Context, weight int64, fn func(ctx context.Context) error) error {
+	release, err := b.Acquire(ctx, weight)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(ctx)
+}
+
+// TryAcquire takes weight units only if they are free right now and
+// nobody is queued ahead. A weight that is not positive is never
+// granted.
+func (b *Bulkhead) TryAcquire(weight int64) (release func(), ok bool) {
+	if weight <= 0 {
+		return nil, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.fitsLocked(weight) {
+		b.inUse += weight
+		return b.releaser(weight), true
+	}
+	return nil, false
+}
+
+// Acquire takes weight units, queueing if allowed. The returned
+// release must be called exactly once. A weight that is not positive
+// fails with ErrInvalidWeight, marked permanent.
+func (b *Bulkhead) Acquire(ctx context.Context, weight int64) (release func(), err error) {
+	if weight <= 0 {
+		return nil, retry.Permanent(fmt.Errorf("%w: got %d", ErrInvalidWeight, weight))
+	}
+	b.mu.Lock()
+
+	if weight > b.cfg.Capacity {
+		err := b.rejectLocked(ReasonTooHeavy)
+		b.mu.Unlock()
+		return nil, retry.Permanent(err)
+	}
+	if b.fitsLocked(weight) {
+		b.inUse += weight
+		b.mu.Unlock()
+		return b.releaser(weight), nil
+	}
+	if b.waiters.Len() >= b.cfg.MaxQueue {
+		err := b.rejectLocked(ReasonQueueFull)
+		b.mu.Unlock()
+		return nil, err
+	}
+
+	w := &waiter{weight: weight, ready: make(chan struct{})}
+	elem := b.waiters.PushBack(w)
+	b.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if b.cfg.QueueTimeout > 0 {
+		timer := b.cfg.Clock.NewTimer(b.cfg.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	select {
+	case <-w.ready:
+		return b.releaser(weight), nil
+	case <-timeout:
+		return nil, b.abandon(elem, w, ReasonQueueTimeout, nil)
+	case <-ctx.Done():
+		return nil, b.abandon(elem, w, "", ctx.Err())
+	}
+}
+
+// abandon removes a waiter that gave up. If it was granted in the
+// meantime, the grant wins and is handed back.
+func (b *Bulkhead) abandon(elem *list.Element, w *waiter, reason string, ctxErr error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		// Granted just as we gave up: give it back.
+		b.inUse -= w.weight
+		b.notifyLocked()
+	default:
+		isFront := b.waiters.Front() == elem
+		b.waiters.Remove(elem)
+		// A heavy waiter at the front may have been blocking lighter
+		// ones behind it.
+		if isFront {
+			b.notifyLocked()
+		}
+	}
+
+	if ctxErr != nil {
+		return ctxErr
+	}
+	return b.rejectLocked(reason)
+}
+
+// fitsLocked keeps the queue FIFO: nobody jumps ahead of a waiter.
+func (b *Bulkhead) fitsLocked(weight int64) bool {
+	return b.waiters.Len() == 0 && b.inUse+weight <= b.cfg.Capacity
+}
+
+func (b *Bulkhead) rejectLocked(reason string) error {
+	b.rejected++
+	return &FullError{Name: b.name, Reason: reason, InUse: b.inUse, Queued: b.waiters.Len()}
+}
+
+func (b *Bulkhead) releaser(weight int64) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.inUse -= weight
+			b.notifyLocked()
+		})
+	}
+}
+
+// notifyLocked grants waiters in order while the oldest one fits.
+func (b *Bulkhead) notifyLocked() {
+	for {
+		front := b.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*waiter)
+		if b.inUse+w.weight > b.cfg.Capacity {
+			return
+		}
+		b.inUse += w.weight
+		b.waiters.Remove(front)
+		close(w.ready)
+	}
+}
+
+// Stats returns live counts.
+func (b *Bulkhead) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{
+		Name:     b.name,
+		Capacity: b.cfg.Capacity,
+		InUse:    b.inUse,
+		Queued:   b.waiters.Len(),
+		Rejected: b.rejected,
+	}
+}
+
+// ==========================================================
+// 4. REGISTRY
+// ==========================================================
+
+// Registry hands out one Bulkhead per dependency name, created on first
+// use. It is safe for concurrent use.
+type Registry struct {
+	defaults  Config
+	overrides map[string]Config
+
+	mu        sync.Mutex
+	bulkheads map[string]*Bulkhead
+}
+
+// NewRegistry uses overrides[name] for named dependencies and defaults
+// for everything else.
+func NewRegistry(defaults Config, overrides map[string]Config) *Registry {
+	return &Registry{
+		defaults:  defaults,
+		overrides: overrides,
+		bulkheads: make(map[string]*Bulkhead),
+	}
+}
+
+// Get returns the bulkhead for name.
+func (r *Registry) Get(name string) *Bulkhead {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok := r.bulkheads[name]; ok {
+		return b
+	}
+	cfg, ok := r.overrides[name]
+	if !ok {
+		cfg = r.defaults
+	}
+	b := New(name, cfg)
+	r.bulkheads[name] = b
+	return b
+}
+
+// Stats returns every bulkhead's counts, sorted by name.
+func (r *Registry) Stats() []Stats {
+	r.mu.Lock()
+	all := make([]*Bulkhead, 0, len(r.bulkheads))
+	for _, b := range r.bulkheads {
+		all = append(all, b)
+	}
+	r.mu.Unlock()
+
+	stats := make([]Stats, len(all))
+	for i, b := range all {
+		stats[i] = b.Stats()
+	}
+	slices.SortFunc(stats, func(a, b Stats) int { return strings.Compare(a.Name, b.Name) })
+	return stats
+}
@@ -0,0 +1,215 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/retry"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func waitForWaiters(t *testing.T, c *clock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a clock waiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForQueued polls until n callers are queued on b.
+func waitForQueued(t *testing.T, b *Bulkhead, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for b.Stats().Queued != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Queued = %d; want %d", b.Stats().Queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBulkhead_RejectsWhenQueueFull(t *testing.T) {
+	b := New("db", Config{Capacity: 2, MaxQueue: 1})
+	ctx := context.Background()
+
+	r1, _ := b.Acquire(ctx, 1)
+	r2, _ := b.Acquire(ctx, 1)
+
+	queued := make(chan error, 1)
+	go func() {
+		release, err := b.Acquire(ctx, 1)
+		if err == nil {
+			release()
+		}
+		queued <- err
+	}()
+	waitForQueued(t, b, 1)
+
+	_, err := b.Acquire(ctx, 1)
+	var full *FullError
+	if !errors.Is(err, ErrBulkheadFull) || !errors.As(err, &full) || full.Reason != ReasonQueueFull {
+		t.Fatalf("Acquire with full queue = %v; want *FullError %s", err, ReasonQueueFull)
+	}
+	if s := b.Stats(); s.InUse != 2 || s.Queued != 1 || s.Rejected != 1 {
+		t.Fatalf("Stats = %+v; want InUse 2, Queued 1, Rejected 1", s)
+	}
+
+	r1()
+	if err := <-queued; err != nil {
+		t.Fatalf("queued Acquire = %v; want success after a release", err)
+	}
+	r2()
+	r2() // release is idempotent
+	if s := b.Stats(); s.InUse != 0 || s.Queued != 0 {
+		t.Fatalf("Stats after releases = %+v; want empty", s)
+	}
+}
+
+func TestBulkhead_QueueTimeout(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	b := New("search", Config{Capacity: 1, MaxQueue: 5, QueueTimeout: time.Second, Clock: fake})
+	release, _ := b.Acquire(context.Background(), 1)
+	defer release()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.Acquire(context.Background(), 1)
+		done <- err
+	}()
+
+	waitForWaiters(t, fake)
+	fake.Step(time.Second)
+
+	var full *FullError
+	if err := <-done; !errors.As(err, &full) || full.Reason != ReasonQueueTimeout {
+		t.Fatalf("Acquire = %v; want *FullError %s", err, ReasonQueueTimeout)
+	}
+	if q := b.Stats().Queued; q != 0 {
+		t.Fatalf("Queued after timeout = %d; want 0", q)
+	}
+}
+
+func TestBulkhead_WeightsAndFIFO(t *testing.T) {
+	b := New("cache", Config{Capacity: 4, MaxQueue: 10})
+	ctx := context.Background()
+
+	release3, _ := b.Acquire(ctx, 3)
+
+	// A heavy waiter queues first; a light caller that would fit must
+	// not jump ahead of it.
+	heavy := make(chan func(), 1)
+	go func() {
+		r, _ := b.Acquire(ctx, 4)
+		heavy <- r
+	}()
+	waitForQueued(t, b, 1)
+
+	if _, ok := b.TryAcquire(1); ok {
+		t.Fatal("TryAcquire jumped ahead of a queued waiter")
+	}
+	if _, err := b.Acquire(ctx, 5); !errors.Is(err, ErrBulkheadFull) || !retry.IsPermanent(err) {
+		t.Fatalf("Acquire(5) with capacity 4 = %v; want permanent %v", err, ErrBulkheadFull)
+	}
+
+	release3()
+	r := <-heavy
+	if s := b.Stats(); s.InUse != 4 {
+		t.Fatalf("InUse = %d; want 4", s.InUse)
+	}
+	r()
+}
+
+func TestBulkhead_RejectsNonPositiveWeight(t *testing.T) {
+	b := New("db", Config{Capacity: 2})
+	ctx := context.Background()
+
+	for _, w := range []int64{0, -3} {
+		if _, err := b.Acquire(ctx, w); !errors.Is(err, ErrInvalidWeight) || !retry.IsPermanent(err) {
+			t.Fatalf("Acquire(%d) = %v; want permanent %v", w, err, ErrInvalidWeight)
+		}
+		if err := b.Do(ctx, w, func(context.Context) error { return nil }); !errors.Is(err, ErrInvalidWeight) {
+			t.Fatalf("Do(%d) = %v; want %v", w, err, ErrInvalidWeight)
+		}
+		if _, ok := b.TryAcquire(w); ok {
+			t.Fatalf("TryAcquire(%d) succeeded", w)
+		}
+	}
+
+	// Capacity is untouched: exactly 2 units fit.
+	r, err := b.Acquire(ctx, 2)
+	if err != nil {
+		t.Fatalf("Acquire(2) = %v", err)
+	}
+	defer r()
+	if _, ok := b.TryAcquire(1); ok || b.Stats().InUse != 2 {
+		t.Fatalf("InUse = %d after invalid weights; want capacity unchanged", b.Stats().InUse)
+	}
+}
+
+func TestBulkhead_CancelledWaiterUnblocksQueue(t *testing.T) {
+	b := New("api", Config{Capacity: 2, MaxQueue: 10})
+	ctx := context.Background()
+	release, _ := b.Acquire(ctx, 1)
+
+	// The front waiter needs everything; the one behind needs only the
+	// free slot and should run once the front gives up.
+	heavyCtx, cancel := context.WithCancel(ctx)
+	heavyErr := make(chan error, 1)
+	go func() {
+		_, err := b.Acquire(heavyCtx, 2)
+		heavyErr <- err
+	}()
+	waitForQueued(t, b, 1)
+
+	light := make(chan error, 1)
+	go func() {
+		r, err := b.Acquire(ctx, 1)
+		if err == nil {
+			r()
+		}
+		light <- err
+	}()
+	waitForQueued(t, b, 2)
+
+	cancel()
+	if err := <-heavyErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Acquire = %v; want %v", err, context.Canceled)
+	}
+	if err := <-light; err != nil {
+		t.Fatalf("light Acquire = %v; want success", err)
+	}
+	release()
+}
+
+func TestRegistry_IsolatesDependencies(t *testing.T) {
+	reg := NewRegistry(Config{Capacity: 1}, map[string]Config{"db": {Capacity: 3}})
+	ctx := context.Background()
+
+	if reg.Get("db") != reg.Get("db") {
+		t.Fatal("Get returned different bulkheads for the same name")
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := reg.Get("db").Acquire(ctx, 1); err != nil {
+			t.Fatalf("db Acquire %d = %v", i, err)
+		}
+	}
+	if err := reg.Get("db").Do(ctx, 1, func(context.Context) error { return nil }); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Do on saturated db = %v; want %v", err, ErrBulkheadFull)
+	}
+	if err := reg.Get("email").Do(ctx, 1, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Do on email with db saturated = %v; want success", err)
+	}
+
+	stats := reg.Stats()
+	if len(stats) != 2 || stats[0].Name != "db" || stats[0].InUse != 3 || stats[1].Capacity != 1 {
+		t.Fatalf("Stats = %+v; want db (3 in use) then email (capacity 1)", stats)
+	}
+}
@@ -0,0 +1,193 @@
+// Package clock abstracts time so that code which waits, backs off or
+// measures durations can be tested without sleeping.
+//
+// It follows the same idea as k8s.io/utils/clock:
+//   - production code takes a Clock and uses RealClock
+//   - tests pass a *FakeClock and move time forward with Step
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// ==========================================================
+// 1. INTERFACES
+// ==========================================================
+
+// Clock is the subset of the time package that our packages use.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	Sleep(d time.Duration)
+}
+
+// Timer mirrors *time.Timer but exposes the channel as a method so it
+// can be faked.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// ==========================================================
+// 2. REAL CLOCK
+// ==========================================================
+
+// RealClock delegates to the time package.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time                         { return time.Now() }
+func (RealClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (RealClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{t: time.NewTimer(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (r *realTimer) C() <-chan time.Time        { return r.t.C }
+func (r *realTimer) Stop() bool                 { return r.t.Stop() }
+func (r *realTimer) Reset(d time.Duration) bool { return r.t.Reset(d) }
+
+// ==========================================================
+// 3. FAKE CLOCK
+// ==========================================================
+
+// FakeClock only moves when the test tells it to.
+//
+// Every After, Sleep and Timer registers a waiter that fires once the
+// fake time reaches its deadline.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFakeClock returns a FakeClock set to t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *FakeClock) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep blocks until another goroutine steps the clock past d.
+func (f *FakeClock) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+func (f *FakeClock) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTimer{clock: f, ch: make(chan time.Time, 1)}
+	t.waiter = f.addWaiterLocked(d, t.ch)
+	return t
+}
+
+// Step advances the clock by d and fires every waiter that is due.
+func (f *FakeClock) Step(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setTimeLocked(f.now.Add(d))
+}
+
+// SetTime moves the clock to t and fires every waiter that is due.
+func (f *FakeClock) SetTime(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setTimeLocked(t)
+}
+
+// HasWaiters reports whether anything is blocked on the clock. Tests
+// use it to know a goroutine has reached its wait before calling Step.
+func (f *FakeClock) HasWaiters() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters) > 0
+}
+
+func (f *FakeClock) addWaiterLocked(d time.Duration, ch chan time.Time) *fakeWaiter {
+	w := &fakeWaiter{deadline: f.now.Add(d), ch: ch}
+	if d <= 0 {
+		ch <- f.now
+		return w
+	}
+	f.waiters = append(f.waiters, w)
+	return w
+}
+
+func (f *FakeClock) removeWaiterLocked(w *fakeWaiter) bool {
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FakeClock) setTimeLocked(t time.Time) {
+	f.now = t
+
+	remaining := f.waiters[:0]
+	for _, w := range f.waiters {
+		if !w.deadline.After(t) {
+			w.ch <- t
+			continue
+		}
+		remaining = append(remaining, w)
+	}
+	f.waiters = remaining
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	waiter *fakeWaiter
+	ch     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.removeWaiterLocked(t.waiter)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.clock.removeWaiterLocked(t.waiter)
+	// Drop a stale tick so the next receive sees the new deadline.
+	select {
+	case <-t.ch:
+	default:
+	}
+	t.waiter = t.clock.addWaiterLocked(d, t.ch)
+	return active
+}
@@ -0,0 +1,78 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock_AfterFiresOnStep(t *testing.T) {
+	c := NewFakeClock(epoch)
+	ch := c.After(time.Second)
+
+	c.Step(999 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("After fired before its deadline")
+	default:
+	}
+
+	c.Step(time.Millisecond)
+	select {
+	case got := <-ch:
+		if want := epoch.Add(time.Second); !got.Equal(want) {
+			t.Fatalf("After delivered %v; want %v", got, want)
+		}
+	default:
+		t.Fatal("After did not fire at its deadline")
+	}
+
+	if c.HasWaiters() {
+		t.Fatal("HasWaiters = true after the only waiter fired")
+	}
+}
+
+func TestFakeClock_TimerStopAndReset(t *testing.T) {
+	c := NewFakeClock(epoch)
+	timer := c.NewTimer(time.Second)
+
+	if !timer.Stop() {
+		t.Fatal("Stop on an active timer = false; want true")
+	}
+	c.Step(time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+
+	timer.Reset(2 * time.Second)
+	c.Step(2 * time.Second)
+	select {
+	case <-timer.C():
+	default:
+		t.Fatal("reset timer did not fire")
+	}
+}
+
+func TestFakeClock_SleepUnblocksOnStep(t *testing.T) {
+	c := NewFakeClock(epoch)
+	done := make(chan struct{})
+
+	go func() {
+		c.Sleep(time.Minute)
+		close(done)
+	}()
+
+	for !c.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+	c.Step(time.Minute)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep did not return after Step (possible deadlock)")
+	}
+}
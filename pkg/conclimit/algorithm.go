@@ -0,0 +1,173 @@
+package conclimit
+
+import (
+	"math"
+	"time"
+)
+
+// ==========================================================
+// 4. ALGORITHMS
+// ==========================================================
+
+// Sample is what the Limiter learned from one finished call.
+type Sample struct {
+	// RTT is how long the call took.
+	RTT time.Duration
+
+	// InFlight is how many calls were in flight when it started,
+	// including itself.
+	InFlight int
+
+	// Dropped is true when the dependency shed or timed out the call.
+	Dropped bool
+}
+
+// Algorithm computes the next limit from the current one and a sample.
+// The Limiter calls Update with its lock held and clamps the result to
+// [MinLimit, MaxLimit], so implementations need no locking of their own.
+type Algorithm interface {
+	Update(limit float64, s Sample) float64
+}
+
+// AIMD is additive-increase/multiplicative-decrease, as in TCP: the
+// limit grows by Increase per round trip (Increase/limit per success)
+// and is multiplied by Backoff on a drop. It only reacts once something
+// is dropped, so it keeps probing up to the point of overload and saws
+// back and forth just below it.
+type AIMD struct {
+	// Increase is added per limit's worth of successes. Defaults to 1.
+	Increase float64
+
+	// Backoff multiplies the limit on a drop. Defaults to 0.9.
+	Backoff float64
+
+	// Timeout, if set, treats calls at least this slow as drops, so the
+	// limit backs off before the caller's own deadline fires.
+	Timeout time.Duration
+}
+
+func (a AIMD) Update(limit float64, s Sample) float64 {
+	inc, backoff := a.Increase, a.Backoff
+	if inc <= 0 {
+		inc = 1
+	}
+	if backoff <= 0 || backoff >= 1 {
+		backoff = 0.9
+	}
+
+	if s.Dropped || (a.Timeout > 0 && s.RTT >= a.Timeout) {
+		return limit * backoff
+	}
+	// Only grow when the limit is actually in use. Otherwise a quiet
+	// period would ratchet it up to MaxLimit and the next burst would
+	// flood the dependency.
+	if float64(s.InFlight)*2 >= limit {
+		return limit + inc/limit
+	}
+	return limit
+}
+
+// Gradient compares the average RTT of the latest window of samples
+// (short) with a long-term average of those windows (long). Their ratio
+// is the gradient: about 1 when latency is steady, below 1 when queues
+// are building. Once per window the limit is scaled by the gradient
+// and topped up with a small allowance for queueing:
+//
+//	gradient = clamp(Tolerance × long / short, 0.5, 1)
+//	target   = limit × gradient + QueueSize(limit)
+//	limit    = limit × (1 − Smoothing) + target × Smoothing
+//
+// A drop halves the target at once. This is a simplified form of
+// Netflix's Gradient2. Its zero value is ready to use; use a pointer,
+// since it keeps state between samples.
+type Gradient struct {
+	// Tolerance is how much the short RTT may exceed the long one
+	// before the limit shrinks. Defaults to 1.5.
+	Tolerance float64
+
+	// Smoothing is how far each window moves the limit toward the
+	// target, in (0, 1]. Defaults to 0.2.
+	Smoothing float64
+
+	// Window is how many samples make one update. Defaults to 100.
+	Window int
+
+	// LongWindows is how many windows the long average spans.
+	// Defaults to 600.
+	LongWindows int
+
+	// QueueSize is the headroom added to the target. Defaults to
+	// sqrt(limit).
+	QueueSize func(limit float64) float64
+
+	// The current window.
+	sum         time.Duration
+	n           int
+	maxInFlight int
+
+	long float64 // average RTT in nanoseconds; zero until the first window
+}
+
+func (g *Gradient) Update(limit float64, s Sample) float64 {
+	tolerance, smoothing := g.Tolerance, g.Smoothing
+	if tolerance <= 0 {
+		tolerance = 1.5
+	}
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = 0.2
+	}
+	window, longWindows := g.Window, g.LongWindows
+	if window <= 0 {
+		window = 100
+	}
+	if longWindows <= 0 {
+		longWindows = 600
+	}
+	queueSize := g.QueueSize
+	if queueSize == nil {
+		queueSize = math.Sqrt
+	}
+
+	if s.Dropped {
+		g.sum, g.n, g.maxInFlight = 0, 0, 0
+		return limit*(1-smoothing) + limit*0.5*smoothing
+	}
+
+	g.sum += s.RTT
+	g.n++
+	g.maxInFlight = max(g.maxInFlight, s.InFlight)
+	if g.n < window {
+		return limit
+	}
+	short := float64(g.sum) / float64(g.n)
+	maxInFlight := g.maxInFlight
+	g.sum, g.n, g.maxInFlight = 0, 0, 0
+
+	g.long = ewma(g.long, short, longWindows)
+	// After a sustained slowdown the long average has caught up with
+	// it. Let it drift back down so the limit can recover when the
+	// slowdown ends.
+	if g.long > 2*short {
+		g.long *= 0.95
+	}
+
+	gradient := min(max(tolerance*g.long/short, 0.5), 1)
+
+	// As in AIMD, an idle limiter learns nothing about higher limits.
+	if gradient == 1 && float64(maxInFlight)*2 < limit {
+		return limit
+	}
+
+	target := limit*gradient + queueSize(limit)
+	return limit*(1-smoothing) + target*smoothing
+}
+
+// ewma folds x into an exponentially weighted average over roughly
+// window samples. The first sample seeds the average.
+func ewma(avg, x float64, window int) float64 {
+	if avg == 0 {
+		return x
+	}
+	alpha := 2 / float64(window+1)
+	return avg + alpha*(x-avg)
+}
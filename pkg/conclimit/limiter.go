@@ -0,0 +1,228 @@
+// Package conclimit limits how many calls to a dependency run at once,
+// and learns the limit from how the dependency responds.
+//
+// A worker pool's Workers and a rate limiter's Limit are guesses made
+// once. Too low wastes capacity; too high queues requests inside the
+// dependency until they time out. By Little's law the right number of
+// requests in flight is throughput × latency, and both change with
+// deploys, load and noisy neighbours. So instead of configuring it, a
+// Limiter measures every call's round-trip time and whether it was
+// dropped, and an Algorithm moves the limit:
+//
+//   - AIMD: grow by one while calls succeed, cut by a factor on a drop
+//     (TCP's congestion window)
+//   - Gradient: compare recent RTT with the long-term RTT; when latency
+//     rises, queues are forming, so shrink before anything is dropped
+//
+// Calls over the limit are rejected at once with ErrLimitExceeded,
+// which is cheaper for everyone than waiting in the dependency's queue.
+package conclimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. ERRORS
+// ==========================================================
+
+// ErrLimitExceeded is matched by every rejection.
+var ErrLimitExceeded = errors.New("conclimit: concurrency limit exceeded")
+
+// LimitError describes a rejection. It matches ErrLimitExceeded.
+type LimitError struct {
+	Name     string
+	Limit    int
+	InFlight int
+}
+
+func (e *LimitError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("conclimit: concurrency limit exceeded (%d in flight, limit %d)", e.InFlight, e.Limit)
+	}
+	return fmt.Sprintf("conclimit %q: concurrency limit exceeded (%d in flight, limit %d)", e.Name, e.InFlight, e.Limit)
+}
+
+func (e *LimitError) Is(target error) bool { return target == ErrLimitExceeded }
+
+// ErrPanicked is the result Do records for a call that panicked. Like
+// any other error it shrinks the limit only if IsDrop says so.
+var ErrPanicked = errors.New("conclimit: call panicked")
+
+// ==========================================================
+// 2. CONFIGURATION
+// ==========================================================
+
+// Config configures a Limiter.
+type Config struct {
+	// Name appears in rejection errors.
+	Name string
+
+	// Algorithm moves the limit. Defaults to AIMD{}. Stateful
+	// algorithms such as *Gradient must not be shared between limiters.
+	Algorithm Algorithm
+
+	// InitialLimit defaults to 20. MinLimit defaults to 1 and MaxLimit
+	// to 1000.
+	InitialLimit int
+	MinLimit     int
+	MaxLimit     int
+
+	// IsDrop reports whether a failed call was dropped because the
+	// dependency is overloaded. Drops shrink the limit; other errors
+	// are ignored, since their RTT says nothing about load. Defaults to
+	// context.DeadlineExceeded and ErrLimitExceeded from a limiter
+	// further down.
+	IsDrop func(err error) bool
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c Config) withDefaults() Config {
+	if c.Algorithm == nil {
+		c.Algorithm = AIMD{}
+	}
+	if c.MinLimit <= 0 {
+		c.MinLimit = 1
+	}
+	if c.MaxLimit <= 0 {
+		c.MaxLimit = 1000
+	}
+	if c.MaxLimit < c.MinLimit {
+		c.MaxLimit = c.MinLimit
+	}
+	if c.InitialLimit <= 0 {
+		c.InitialLimit = 20
+	}
+	c.InitialLimit = min(max(c.InitialLimit, c.MinLimit), c.MaxLimit)
+	if c.IsDrop == nil {
+		c.IsDrop = DefaultIsDrop
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+// DefaultIsDrop treats timeouts and downstream rejections as drops.
+func DefaultIsDrop(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrLimitExceeded)
+}
+
+// ==========================================================
+// 3. LIMITER
+// ==========================================================
+
+// Limiter admits calls while fewer than Limit are in flight. It is safe
+// for concurrent use.
+type Limiter struct {
+	cfg Config
+
+	mu       sync.Mutex
+	limit    float64 // fractional, so small steps accumulate
+	inFlight int
+
+	// lastDrop is when a drop last shrank the limit. Calls that started
+	// before it were admitted under the old limit.
+	lastDrop time.Time
+}
+
+// New returns a Limiter starting at cfg.InitialLimit.
+func New(cfg Config) *Limiter {
+	cfg = cfg.withDefaults()
+	return &Limiter{cfg: cfg, limit: float64(cfg.InitialLimit)}
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limitLocked()
+}
+
+// InFlight returns how many admitted calls have not finished.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+func (l *Limiter) limitLocked() int {
+	return int(l.limit)
+}
+
+// Do runs fn if the limit allows, and feeds its outcome back. If fn
+// panics, its slot is released with ErrPanicked before the panic
+// continues up the stack.
+func (l *Limiter) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := l.Acquire()
+	if err != nil {
+		return err
+	}
+	returned := false
+	defer func() {
+		if !returned {
+			done(ErrPanicked)
+		}
+	}()
+	err = fn(ctx)
+	returned = true
+	done(err)
+	return err
+}
+
+// Acquire admits one call. The caller must call done with the call's
+// result exactly once; later calls are ignored.
+func (l *Limiter) Acquire() (done func(err error), err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight >= l.limitLocked() {
+		return nil, &LimitError{Name: l.cfg.Name, Limit: l.limitLocked(), InFlight: l.inFlight}
+	}
+	l.inFlight++
+
+	start := l.cfg.Clock.Now()
+	inFlight := l.inFlight
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { l.record(start, inFlight, err) })
+	}, nil
+}
+
+func (l *Limiter) record(start time.Time, inFlight int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+
+	dropped := err != nil && l.cfg.IsDrop(err)
+	if err != nil && !dropped {
+		return
+	}
+	// One overload shows up as a burst of drops. React to the first
+	// and ignore the rest of the burst, as TCP backs off once per
+	// window; otherwise the limit collapses to MinLimit.
+	if dropped && start.Before(l.lastDrop) {
+		return
+	}
+
+	now := l.cfg.Clock.Now()
+	s := Sample{RTT: now.Sub(start), InFlight: inFlight, Dropped: dropped}
+	next := l.cfg.Algorithm.Update(l.limit, s)
+	if math.IsNaN(next) {
+		return
+	}
+	if dropped {
+		l.lastDrop = now
+	}
+	l.limit = min(max(next, float64(l.cfg.MinLimit)), float64(l.cfg.MaxLimit))
+}
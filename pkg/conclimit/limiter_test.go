@@ -0,0 +1,193 @@
+package conclimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fixed is an Algorithm that always answers the same limit.
+type fixed float64
+
+func (f fixed) Update(float64, Sample) float64 { return float64(f) }
+
+func TestLimiter_RejectsOverLimit(t *testing.T) {
+	l := New(Config{Name: "db", InitialLimit: 2, Algorithm: fixed(2)})
+
+	d1, _ := l.Acquire()
+	d2, _ := l.Acquire()
+	_, err := l.Acquire()
+
+	var le *LimitError
+	if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &le) || le.Limit != 2 || le.InFlight != 2 {
+		t.Fatalf("Acquire over limit = %v; want *LimitError{Limit: 2, InFlight: 2}", err)
+	}
+
+	d1(nil)
+	d1(nil) // ignored
+	if n := l.InFlight(); n != 1 {
+		t.Fatalf("InFlight = %d; want 1", n)
+	}
+	if _, err := l.Acquire(); err != nil {
+		t.Fatalf("Acquire after a release = %v; want success", err)
+	}
+	d2(nil)
+}
+
+func TestLimiter_FeedsSamples(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	var got []Sample
+	alg := algorithmFunc(func(limit float64, s Sample) float64 {
+		got = append(got, s)
+		return limit
+	})
+	l := New(Config{Algorithm: alg, Clock: fake})
+
+	errBadRequest := errors.New("bad request")
+	tests := []struct {
+		err       error
+		wantCount int
+		wantDrop  bool
+	}{
+		{nil, 1, false},
+		{context.DeadlineExceeded, 2, true},
+		{errBadRequest, 2, false}, // says nothing about load: ignored
+	}
+	for _, tt := range tests {
+		done, _ := l.Acquire()
+		fake.Step(30 * time.Millisecond)
+		done(tt.err)
+
+		if len(got) != tt.wantCount {
+			t.Fatalf("after %v: %d samples; want %d", tt.err, len(got), tt.wantCount)
+		}
+		if tt.err == errBadRequest {
+			continue
+		}
+		if s := got[len(got)-1]; s.RTT != 30*time.Millisecond || s.InFlight != 1 || s.Dropped != tt.wantDrop {
+			t.Fatalf("after %v: sample %+v; want 30ms, 1 in flight, dropped %v", tt.err, s, tt.wantDrop)
+		}
+	}
+}
+
+type algorithmFunc func(limit float64, s Sample) float64
+
+func (f algorithmFunc) Update(limit float64, s Sample) float64 { return f(limit, s) }
+
+func TestLimiter_ClampsAndBacksOffOncePerBurst(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	l := New(Config{InitialLimit: 10, MinLimit: 2, MaxLimit: 12, Algorithm: AIMD{Backoff: 0.5}, Clock: fake})
+
+	// Ten calls in flight all time out together: one backoff, not ten.
+	var dones []func(error)
+	for i := 0; i < 10; i++ {
+		d, _ := l.Acquire()
+		dones = append(dones, d)
+	}
+	fake.Step(time.Second)
+	for _, d := range dones {
+		d(context.DeadlineExceeded)
+	}
+	if got := l.Limit(); got != 5 {
+		t.Fatalf("Limit after a burst of drops = %d; want 5", got)
+	}
+
+	// A later burst backs off again, but not below MinLimit.
+	for i := 0; i < 3; i++ {
+		d, _ := l.Acquire()
+		fake.Step(time.Millisecond)
+		d(context.DeadlineExceeded)
+	}
+	if got := l.Limit(); got != 2 {
+		t.Fatalf("Limit after repeated drops = %d; want MinLimit 2", got)
+	}
+}
+
+func TestAIMD_Update(t *testing.T) {
+	a := AIMD{Timeout: 100 * time.Millisecond}
+	tests := []struct {
+		name string
+		s    Sample
+		want float64
+	}{
+		{"success at full use grows by 1/limit", Sample{RTT: time.Millisecond, InFlight: 10}, 10.1},
+		{"success while mostly idle holds", Sample{RTT: time.Millisecond, InFlight: 2}, 10},
+		{"drop backs off", Sample{Dropped: true, InFlight: 10}, 9},
+		{"slow call backs off", Sample{RTT: 100 * time.Millisecond, InFlight: 10}, 9},
+	}
+	for _, tt := range tests {
+		if got := a.Update(10, tt.s); got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Fatalf("%s: Update = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGradient_ShrinksWhenLatencyRises(t *testing.T) {
+	g := &Gradient{Window: 10}
+	limit := 20.0
+	feed := func(rtt time.Duration, windows int) {
+		for i := 0; i < windows*10; i++ {
+			limit = g.Update(limit, Sample{RTT: rtt, InFlight: int(limit)})
+		}
+	}
+
+	feed(10*time.Millisecond, 5)
+	if limit <= 20 {
+		t.Fatalf("limit with steady latency = %v; want growth above 20", limit)
+	}
+
+	before := limit
+	feed(40*time.Millisecond, 5)
+	if limit >= before {
+		t.Fatalf("limit after latency quadrupled = %v; want below %v", limit, before)
+	}
+}
+
+func TestLimiter_Do(t *testing.T) {
+	l := New(Config{InitialLimit: 1, Algorithm: fixed(1)})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go l.Do(context.Background(), func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	if err := l.Do(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Do at the limit = %v; want %v", err, ErrLimitExceeded)
+	}
+	close(release)
+}
+
+func TestLimiter_DoReleasesOnPanic(t *testing.T) {
+	var got error
+	l := New(Config{InitialLimit: 1, Algorithm: fixed(1), IsDrop: func(err error) bool {
+		got = err
+		return false
+	}})
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recovered %v; want the original panic", r)
+			}
+		}()
+		l.Do(context.Background(), func(context.Context) error { panic("boom") })
+	}()
+
+	if n := l.InFlight(); n != 0 {
+		t.Fatalf("InFlight after panic = %d; want 0", n)
+	}
+	if !errors.Is(got, ErrPanicked) {
+		t.Fatalf("recorded %v; want %v", got, ErrPanicked)
+	}
+	if err := l.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Do after panic = %v; want nil", err)
+	}
+}
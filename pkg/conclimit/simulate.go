@@ -0,0 +1,184 @@
+package conclimit
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 5. SIMULATION
+// ==========================================================
+
+/*
+Adaptive limits are hard to judge from unit tests of one Update call:
+what matters is where the limit settles under load. Simulate runs a
+Limiter against a synthetic Backend in virtual time, one event at a
+time, so a run of minutes finishes in milliseconds and is exactly
+repeatable.
+*/
+
+// Backend is a synthetic dependency. It serves up to Capacity calls in
+// parallel in Latency each. Beyond that, calls share the capacity, so
+// latency grows in proportion to the number in flight and throughput
+// stays at Capacity/Latency. A call that would take longer than Timeout
+// is dropped with context.DeadlineExceeded after Timeout.
+type Backend struct {
+	Capacity int
+	Latency  time.Duration
+	Timeout  time.Duration
+}
+
+func (b Backend) serve(inFlight int) (time.Duration, error) {
+	d := b.Latency
+	if inFlight > b.Capacity {
+		d = b.Latency * time.Duration(inFlight) / time.Duration(b.Capacity)
+	}
+	if b.Timeout > 0 && d > b.Timeout {
+		return b.Timeout, context.DeadlineExceeded
+	}
+	return d, nil
+}
+
+// SimConfig configures Simulate.
+type SimConfig struct {
+	Backend Backend
+
+	// Rate is the offered load in calls per second, evenly spaced. It
+	// must be positive and at most one call per nanosecond.
+	Rate float64
+
+	// Duration is how much virtual time to simulate.
+	Duration time.Duration
+
+	// Limiter configures the limiter under test; its Clock is replaced
+	// by the simulation's. Nil runs without a limiter, as a baseline.
+	Limiter *Config
+}
+
+// SimResult summarizes a simulation.
+type SimResult struct {
+	Offered   int
+	Rejected  int // by the limiter
+	Succeeded int
+	Dropped   int // by the backend
+
+	MaxInFlight int
+	FinalLimit  int // zero without a limiter
+
+	// MeanLatency is over succeeded calls.
+	MeanLatency time.Duration
+}
+
+// completion is a call that will finish at a virtual time.
+type completion struct {
+	at   time.Time
+	err  error
+	done func(error)
+}
+
+type completions []completion
+
+func (c completions) Len() int           { return len(c) }
+func (c completions) Less(i, j int) bool { return c[i].at.Before(c[j].at) }
+func (c completions) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c *completions) Push(x any)        { *c = append(*c, x.(completion)) }
+func (c *completions) Pop() any {
+	old := *c
+	x := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return x
+}
+
+// Simulate offers cfg.Rate calls per second to cfg.Backend for
+// cfg.Duration of virtual time and reports what happened. It returns an
+// error, and simulates nothing, if cfg cannot describe a finite run.
+func Simulate(cfg SimConfig) (SimResult, error) {
+	if err := cfg.validate(); err != nil {
+		return SimResult{}, err
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFakeClock(start)
+
+	var lim *Limiter
+	if cfg.Limiter != nil {
+		lc := *cfg.Limiter
+		lc.Clock = fake
+		lim = New(lc)
+	}
+
+	var (
+		res      SimResult
+		pending  completions
+		inFlight int
+		latency  time.Duration
+	)
+	finish := func(c completion) {
+		fake.SetTime(c.at)
+		inFlight--
+		if c.err != nil {
+			res.Dropped++
+		} else {
+			res.Succeeded++
+		}
+		c.done(c.err)
+	}
+
+	interval := time.Duration(float64(time.Second) / cfg.Rate)
+	end := start.Add(cfg.Duration)
+	for next := start; next.Before(end); next = next.Add(interval) {
+		// Finish everything due before this arrival.
+		for pending.Len() > 0 && !pending[0].at.After(next) {
+			finish(heap.Pop(&pending).(completion))
+		}
+		fake.SetTime(next)
+		res.Offered++
+
+		done := func(error) {}
+		if lim != nil {
+			d, err := lim.Acquire()
+			if err != nil {
+				res.Rejected++
+				continue
+			}
+			done = d
+		}
+
+		inFlight++
+		res.MaxInFlight = max(res.MaxInFlight, inFlight)
+		d, err := cfg.Backend.serve(inFlight)
+		if err == nil {
+			latency += d
+		}
+		heap.Push(&pending, completion{at: next.Add(d), err: err, done: done})
+	}
+	for pending.Len() > 0 {
+		finish(heap.Pop(&pending).(completion))
+	}
+
+	if res.Succeeded > 0 {
+		res.MeanLatency = latency / time.Duration(res.Succeeded)
+	}
+	if lim != nil {
+		res.FinalLimit = lim.Limit()
+	}
+	return res, nil
+}
+
+func (c SimConfig) validate() error {
+	// The negated comparisons also reject NaN.
+	if !(c.Rate > 0 && c.Rate <= float64(time.Second)) {
+		return fmt.Errorf("conclimit: simulation Rate %v is not in (0, 1e9] calls per second", c.Rate)
+	}
+	if c.Duration <= 0 {
+		return fmt.Errorf("conclimit: simulation Duration %v is not positive", c.Duration)
+	}
+	if c.Backend.Capacity <= 0 || c.Backend.Latency <= 0 {
+		return fmt.Errorf("conclimit: simulation Backend needs a positive Capacity and Latency, got %d and %v",
+			c.Backend.Capacity, c.Backend.Latency)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package conclimit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// The backend serves 1000 calls/s at 10ms; callers give up after 50ms.
+var backend = Backend{Capacity: 10, Latency: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}
+
+func simulate(t *testing.T, cfg SimConfig) SimResult {
+	t.Helper()
+	res, err := Simulate(cfg)
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	return res
+}
+
+func TestSimulate_LightLoadIsUntouched(t *testing.T) {
+	res := simulate(t, SimConfig{Backend: backend, Rate: 500, Duration: 10 * time.Second, Limiter: &Config{}})
+	if res.Rejected != 0 || res.Dropped != 0 || res.Succeeded != res.Offered {
+		t.Fatalf("Simulate at half capacity = %+v; want every call to succeed", res)
+	}
+	if res.MeanLatency != backend.Latency {
+		t.Fatalf("MeanLatency = %v; want %v", res.MeanLatency, backend.Latency)
+	}
+}
+
+func TestSimulate_OverloadKeepsGoodput(t *testing.T) {
+	const (
+		duration = time.Minute
+		capacity = 60 * 1000 // calls the backend can serve in a minute
+	)
+
+	// Without a limiter, twice the capacity queues up in the backend
+	// until every call times out.
+	baseline := simulate(t, SimConfig{Backend: backend, Rate: 2000, Duration: duration})
+	if baseline.Succeeded > capacity/100 {
+		t.Fatalf("baseline succeeded %d times; want the backend to collapse", baseline.Succeeded)
+	}
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"aimd", Config{}},
+		{"aimd with timeout", Config{Algorithm: AIMD{Timeout: 30 * time.Millisecond}}},
+		{"gradient", Config{Algorithm: &Gradient{}}},
+	}
+	for _, tt := range tests {
+		res := simulate(t, SimConfig{Backend: backend, Rate: 2000, Duration: duration, Limiter: &tt.cfg})
+		if res.Succeeded < capacity*8/10 {
+			t.Fatalf("%s: succeeded %d of a possible %d; want at least 80%%: %+v", tt.name, res.Succeeded, capacity, res)
+		}
+		if res.Dropped > res.Succeeded/5 {
+			t.Fatalf("%s: %d drops for %d successes; want the limiter to shed load instead", tt.name, res.Dropped, res.Succeeded)
+		}
+	}
+}
+
+func TestSimulate_GradientKeepsLatencyDown(t *testing.T) {
+	slow := Backend{Capacity: 50, Latency: 100 * time.Millisecond, Timeout: time.Second}
+	run := func(cfg Config) SimResult {
+		return simulate(t, SimConfig{Backend: slow, Rate: 1000, Duration: 2 * time.Minute, Limiter: &cfg})
+	}
+
+	aimd := run(Config{})
+	gradient := run(Config{Algorithm: &Gradient{}})
+	if gradient.Succeeded < aimd.Succeeded*9/10 {
+		t.Fatalf("gradient succeeded %d vs aimd %d; want comparable goodput", gradient.Succeeded, aimd.Succeeded)
+	}
+	if gradient.MeanLatency >= aimd.MeanLatency {
+		t.Fatalf("gradient latency %v vs aimd %v; want gradient lower", gradient.MeanLatency, aimd.MeanLatency)
+	}
+}
+
+func TestSimulate_RejectsEndlessRuns(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  SimConfig
+	}{
+		{"zero rate", SimConfig{Backend: backend, Duration: time.Second}},
+		{"negative rate", SimConfig{Backend: backend, Rate: -1, Duration: time.Second}},
+		{"rate above 1/ns", SimConfig{Backend: backend, Rate: 2e9, Duration: time.Second}},
+		{"NaN rate", SimConfig{Backend: backend, Rate: math.NaN(), Duration: time.Second}},
+		{"zero duration", SimConfig{Backend: backend, Rate: 100}},
+		{"zero capacity", SimConfig{Backend: Backend{Latency: time.Millisecond}, Rate: 100, Duration: time.Second}},
+	}
+	for _, tt := range tests {
+		if res, err := Simulate(tt.cfg); err == nil {
+			t.Fatalf("%s: Simulate = %+v, nil; want an error", tt.name, res)
+		}
+	}
+}
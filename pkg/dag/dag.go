@@ -0,0 +1,386 @@
+// Package dag runs jobs that depend on each other's outputs.
+//
+// The flat jobs channel of the worker-pool lesson cannot say "job 7
+// needs the outputs of jobs 2 and 3". Here every job declares its
+// dependencies, and the executor:
+//
+//   - validates the graph (unknown dependencies, cycles with their path)
+//   - runs ready jobs in parallel on a bounded pkg/workerpool pool
+//   - skips everything downstream of a failed job
+//   - returns a summary in topological order
+//
+// Job outputs are typed, so a graph of one payload type reads naturally:
+//
+//	g := dag.New[string]()
+//	g.Add(dag.Job[string]{ID: "fetch", Run: fetch})
+//	g.Add(dag.Job[string]{ID: "parse", DependsOn: []string{"fetch"}, Run: parse})
+//	summary, err := g.Run(ctx, 4)
+package dag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-systems-learning/pkg/workerpool"
+)
+
+// ==========================================================
+// 1. JOBS & ERRORS
+// ==========================================================
+
+// Job is one node of the graph. Run receives the outputs of every job
+// in DependsOn, keyed by job ID.
+type Job[T any] struct {
+	ID        string
+	DependsOn []string
+	Run       func(ctx context.Context, inputs map[string]T) (T, error)
+}
+
+// CycleError reports a dependency cycle. Path starts and ends with the
+// same job, e.g. [a b c a] for a → b → c → a.
+type CycleError struct {
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "dag: dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// MissingDependencyError reports a dependency on a job that was never
+// added.
+type MissingDependencyError struct {
+	Job        string
+	Dependency string
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf("dag: job %q depends on unknown job %q", e.Job, e.Dependency)
+}
+
+// ==========================================================
+// 2. RUN SUMMARY
+// ==========================================================
+
+// Status is the outcome of one job in a run.
+type Status int
+
+const (
+	StatusSucceeded Status = iota
+	StatusFailed
+	// StatusSkipped means a dependency did not succeed.
+	StatusSkipped
+	// StatusCancelled means the run context ended before the job started.
+	StatusCancelled
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusSucceeded:
+		return "Succeeded"
+	case StatusFailed:
+		return "Failed"
+	case StatusSkipped:
+		return "Skipped"
+	case StatusCancelled:
+		return "Cancelled"
+	default:
+		return "Unknown"
+	}
+}
+
+// JobResult is the outcome of one job.
+type JobResult[T any] struct {
+	ID       string
+	Status   Status
+	Output   T
+	Err      error
+	Duration time.Duration
+
+	// SkippedBecause is the failed job that caused a skip.
+	SkippedBecause string
+}
+
+// Summary lists every job's outcome in topological order.
+type Summary[T any] struct {
+	Results []JobResult[T]
+}
+
+// Result returns the outcome of the job with id.
+func (s *Summary[T]) Result(id string) (JobResult[T], bool) {
+	for _, r := range s.Results {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return JobResult[T]{}, false
+}
+
+// Count returns how many jobs ended with status.
+func (s *Summary[T]) Count(status Status) int {
+	n := 0
+	for _, r := range s.Results {
+		if r.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// ==========================================================
+// 3. GRAPH
+// ==========================================================
+
+// Graph is a set of jobs and their dependencies.
+type Graph[T any] struct {
+	jobs  map[string]*Job[T]
+	order []string // insertion order, for deterministic output
+}
+
+// New returns an empty Graph.
+func New[T any]() *Graph[T] {
+	return &Graph[T]{jobs: make(map[string]*Job[T])}
+}
+
+// Add registers job. IDs must be unique.
+func (g *Graph[T]) Add(job Job[T]) error {
+	if job.ID == "" {
+		return errors.New("dag: job ID must not be empty")
+	}
+	if _, dup := g.jobs[job.ID]; dup {
+		return fmt.Errorf("dag: duplicate job %q", job.ID)
+	}
+	if job.Run == nil {
+		return fmt.Errorf("dag: job %q has no Run function", job.ID)
+	}
+
+	g.jobs[job.ID] = &job
+	g.order = append(g.order, job.ID)
+	return nil
+}
+
+// Validate reports the first unknown dependency or cycle.
+func (g *Graph[T]) Validate() error {
+	for _, id := range g.order {
+		for _, dep := range g.jobs[id].DependsOn {
+			if _, ok := g.jobs[dep]; !ok {
+				return &MissingDependencyError{Job: id, Dependency: dep}
+			}
+		}
+	}
+
+	if path := g.findCycle(); path != nil {
+		return &CycleError{Path: path}
+	}
+	return nil
+}
+
+// findCycle runs a depth-first search and returns the first cycle it
+// meets, or nil.
+func (g *Graph[T]) findCycle() []string {
+	const (
+		white = iota // not visited
+		grey         // on the current DFS stack
+		black        // fully explored
+	)
+	color := make(map[string]int, len(g.jobs))
+	var stack []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		color[id] = grey
+		stack = append(stack, id)
+
+		for _, dep := range g.jobs[id].DependsOn {
+			switch color[dep] {
+			case grey:
+				// Back edge: the cycle is the stack from dep to here.
+				// The stack follows "depends on" edges, so reverse it
+				// to read in execution order.
+				start := 0
+				for i, s := range stack {
+					if s == dep {
+						start = i
+					}
+				}
+				cycle := append([]string(nil), stack[start:]...)
+				cycle = append(cycle, dep)
+				for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+					cycle[i], cycle[j] = cycle[j], cycle[i]
+				}
+				return cycle
+			case white:
+				if c := visit(dep); c != nil {
+					return c
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		color[id] = black
+		return nil
+	}
+
+	for _, id := range g.order {
+		if color[id] == white {
+			if c := visit(id); c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// topoOrder returns the jobs in Kahn order, breaking ties by insertion
+// order. The graph must already be validated.
+func (g *Graph[T]) topoOrder() []string {
+	indegree := make(map[string]int, len(g.jobs))
+	dependents := g.dependents()
+	for _, id := range g.order {
+		indegree[id] = len(g.jobs[id].DependsOn)
+	}
+
+	var order, ready []string
+	for _, id := range g.order {
+		if indegree[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, d := range dependents[id] {
+			indegree[d]--
+			if indegree[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	return order
+}
+
+func (g *Graph[T]) dependents() map[string][]string {
+	out := make(map[string][]string, len(g.jobs))
+	for _, id := range g.order {
+		for _, dep := range g.jobs[id].DependsOn {
+			out[dep] = append(out[dep], id)
+		}
+	}
+	return out
+}
+
+// ==========================================================
+// 4. EXECUTION
+// ==========================================================
+
+// Run validates the graph and executes it with at most workers jobs in
+// parallel. The returned error joins every job failure; the summary is
+// returned whenever validation passed.
+func (g *Graph[T]) Run(ctx context.Context, workers int) (*Summary[T], error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]*JobResult[T], len(g.jobs))
+	for _, id := range g.order {
+		results[id] = &JobResult[T]{ID: id}
+	}
+	dependents := g.dependents()
+	remaining := make(map[string]int, len(g.jobs))
+	for _, id := range g.order {
+		remaining[id] = len(g.jobs[id].DependsOn)
+	}
+
+	type task struct {
+		job    *Job[T]
+		inputs map[string]T
+	}
+
+	// Every job can be queued at once, so Submit below never blocks
+	// while this goroutine is also the one reading results.
+	pool := workerpool.New(func(ctx context.Context, t task) (T, error) {
+		return t.job.Run(ctx, t.inputs)
+	}, workerpool.Options{Workers: workers, QueueSize: len(g.jobs)})
+
+	byPoolID := make(map[int]string, len(g.jobs))
+	inFlight := 0
+
+	// skip marks every job downstream of id as skipped.
+	var skip func(id, cause string)
+	skip = func(id, cause string) {
+		for _, d := range dependents[id] {
+			r := results[d]
+			if r.SkippedBecause != "" {
+				continue
+			}
+			r.Status = StatusSkipped
+			r.SkippedBecause = cause
+			skip(d, cause)
+		}
+	}
+
+	submit := func(id string) {
+		job := g.jobs[id]
+		inputs := make(map[string]T, len(job.DependsOn))
+		for _, dep := range job.DependsOn {
+			inputs[dep] = results[dep].Output
+		}
+
+		poolID, err := pool.Submit(ctx, task{job: job, inputs: inputs})
+		if err != nil {
+			results[id].Status = StatusCancelled
+			results[id].Err = err
+			skip(id, id)
+			return
+		}
+		byPoolID[poolID] = id
+		inFlight++
+	}
+
+	for _, id := range g.order {
+		if remaining[id] == 0 {
+			submit(id)
+		}
+	}
+
+	for inFlight > 0 {
+		res := <-pool.Results()
+		inFlight--
+		id := byPoolID[res.JobID]
+		r := results[id]
+		r.Output, r.Err, r.Duration = res.Value, res.Err, res.Duration
+
+		switch res.Status {
+		case workerpool.StatusSucceeded:
+			r.Status = StatusSucceeded
+			for _, d := range dependents[id] {
+				remaining[d]--
+				if remaining[d] == 0 && results[d].SkippedBecause == "" {
+					submit(d)
+				}
+			}
+		case workerpool.StatusCancelled:
+			r.Status = StatusCancelled
+			skip(id, id)
+		default:
+			r.Status = StatusFailed
+			skip(id, id)
+		}
+	}
+
+	pool.Close()
+
+	summary := &Summary[T]{}
+	var errs []error
+	for _, id := range g.topoOrder() {
+		r := *results[id]
+		summary.Results = append(summary.Results, r)
+		if r.Status == StatusFailed {
+			errs = append(errs, fmt.Errorf("dag: job %q: %w", id, r.Err))
+		}
+	}
+	return summary, errors.Join(errs...)
+}
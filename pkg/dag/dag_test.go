@@ -0,0 +1,225 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func constant(v int) func(context.Context, map[string]int) (int, error) {
+	return func(context.Context, map[string]int) (int, error) { return v, nil }
+}
+
+func sum(_ context.Context, inputs map[string]int) (int, error) {
+	total := 0
+	for _, v := range inputs {
+		total += v
+	}
+	return total, nil
+}
+
+func mustAdd(t *testing.T, g *Graph[int], jobs ...Job[int]) {
+	t.Helper()
+	for _, j := range jobs {
+		if err := g.Add(j); err != nil {
+			t.Fatalf("Add(%q) returned %v", j.ID, err)
+		}
+	}
+}
+
+func TestGraph_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		jobs      []Job[int]
+		wantCycle []string
+		wantMiss  *MissingDependencyError
+	}{
+		{
+			name: "acyclic",
+			jobs: []Job[int]{
+				{ID: "a", Run: sum},
+				{ID: "b", DependsOn: []string{"a"}, Run: sum},
+			},
+		},
+		{
+			name: "self loop",
+			jobs: []Job[int]{
+				{ID: "a", DependsOn: []string{"a"}, Run: sum},
+			},
+			wantCycle: []string{"a", "a"},
+		},
+		{
+			name: "three-job cycle behind an entry job",
+			jobs: []Job[int]{
+				{ID: "entry", DependsOn: []string{"c"}, Run: sum},
+				{ID: "a", DependsOn: []string{"c"}, Run: sum},
+				{ID: "b", DependsOn: []string{"a"}, Run: sum},
+				{ID: "c", DependsOn: []string{"b"}, Run: sum},
+			},
+			wantCycle: []string{"c", "a", "b", "c"},
+		},
+		{
+			name: "unknown dependency",
+			jobs: []Job[int]{
+				{ID: "a", DependsOn: []string{"ghost"}, Run: sum},
+			},
+			wantMiss: &MissingDependencyError{Job: "a", Dependency: "ghost"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New[int]()
+			mustAdd(t, g, tt.jobs...)
+			err := g.Validate()
+
+			var cErr *CycleError
+			var mErr *MissingDependencyError
+			switch {
+			case tt.wantCycle != nil:
+				if !errors.As(err, &cErr) {
+					t.Fatalf("Validate() = %v; want *CycleError", err)
+				}
+				if !reflect.DeepEqual(cErr.Path, tt.wantCycle) {
+					t.Fatalf("cycle path = %v; want %v", cErr.Path, tt.wantCycle)
+				}
+			case tt.wantMiss != nil:
+				if !errors.As(err, &mErr) || *mErr != *tt.wantMiss {
+					t.Fatalf("Validate() = %v; want %v", err, tt.wantMiss)
+				}
+			case err != nil:
+				t.Fatalf("Validate() = %v; want nil", err)
+			}
+		})
+	}
+}
+
+func TestGraph_AddRejectsDuplicates(t *testing.T) {
+	g := New[int]()
+	mustAdd(t, g, Job[int]{ID: "a", Run: sum})
+	if err := g.Add(Job[int]{ID: "a", Run: sum}); err == nil {
+		t.Fatal("Add of a duplicate ID returned nil")
+	}
+}
+
+func TestGraph_RunPassesOutputsInTopologicalOrder(t *testing.T) {
+	g := New[int]()
+	// Added out of order on purpose: the summary must still be topological.
+	mustAdd(t, g,
+		Job[int]{ID: "total", DependsOn: []string{"left", "right"}, Run: sum},
+		Job[int]{ID: "left", DependsOn: []string{"root"}, Run: sum},
+		Job[int]{ID: "right", DependsOn: []string{"root"}, Run: sum},
+		Job[int]{ID: "root", Run: constant(5)},
+	)
+
+	summary, err := g.Run(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+
+	var order []string
+	for _, r := range summary.Results {
+		order = append(order, r.ID)
+		if r.Status != StatusSucceeded {
+			t.Fatalf("job %q status = %v; want %v", r.ID, r.Status, StatusSucceeded)
+		}
+	}
+	if want := []string{"root", "left", "right", "total"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("summary order = %v; want %v", order, want)
+	}
+	if r, _ := summary.Result("total"); r.Output != 10 {
+		t.Fatalf("total output = %d; want 10", r.Output)
+	}
+}
+
+func TestGraph_RunSkipsDownstreamOfFailure(t *testing.T) {
+	errBoom := errors.New("boom")
+	var downstreamRan atomic.Bool
+
+	g := New[int]()
+	mustAdd(t, g,
+		Job[int]{ID: "ok", Run: constant(1)},
+		Job[int]{ID: "bad", Run: func(context.Context, map[string]int) (int, error) { return 0, errBoom }},
+		Job[int]{ID: "child", DependsOn: []string{"bad", "ok"}, Run: func(context.Context, map[string]int) (int, error) {
+			downstreamRan.Store(true)
+			return 0, nil
+		}},
+		Job[int]{ID: "grandchild", DependsOn: []string{"child"}, Run: sum},
+		Job[int]{ID: "sibling", DependsOn: []string{"ok"}, Run: sum},
+	)
+
+	summary, err := g.Run(context.Background(), 4)
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Run error = %v; want it to wrap %v", err, errBoom)
+	}
+	if downstreamRan.Load() {
+		t.Fatal("a job downstream of a failure ran")
+	}
+
+	want := map[string]Status{
+		"ok":         StatusSucceeded,
+		"bad":        StatusFailed,
+		"child":      StatusSkipped,
+		"grandchild": StatusSkipped,
+		"sibling":    StatusSucceeded,
+	}
+	for id, status := range want {
+		r, ok := summary.Result(id)
+		if !ok || r.Status != status {
+			t.Fatalf("job %q status = %v; want %v", id, r.Status, status)
+		}
+	}
+	if r, _ := summary.Result("grandchild"); r.SkippedBecause != "bad" {
+		t.Fatalf("grandchild SkippedBecause = %q; want %q", r.SkippedBecause, "bad")
+	}
+}
+
+func TestGraph_RunBoundsParallelism(t *testing.T) {
+	const workers = 2
+	var running, peak atomic.Int32
+
+	slow := func(context.Context, map[string]int) (int, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return 0, nil
+	}
+
+	g := New[int]()
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		mustAdd(t, g, Job[int]{ID: id, Run: slow})
+	}
+
+	if _, err := g.Run(context.Background(), workers); err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+	if got := peak.Load(); got > workers {
+		t.Fatalf("peak parallelism = %d; want <= %d", got, workers)
+	}
+}
+
+func TestGraph_RunRejectsCycle(t *testing.T) {
+	g := New[int]()
+	mustAdd(t, g,
+		Job[int]{ID: "a", DependsOn: []string{"b"}, Run: sum},
+		Job[int]{ID: "b", DependsOn: []string{"a"}, Run: sum},
+	)
+
+	summary, err := g.Run(context.Background(), 1)
+	var cErr *CycleError
+	if !errors.As(err, &cErr) {
+		t.Fatalf("Run error = %v; want *CycleError", err)
+	}
+	if summary != nil {
+		t.Fatal("Run returned a summary for an invalid graph")
+	}
+}
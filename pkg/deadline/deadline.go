@@ -0,0 +1,167 @@
+// Package deadline carries a context's deadline across HTTP calls.
+//
+// makeRequest in 05-concurrency/05-context stops when its ctx is done,
+// but a ctx does not cross the network. Without help, a server keeps
+// working for a client that gave up long ago, and calls the next
+// service with no deadline at all. This package closes that gap:
+//
+//   - the client Transport sends the time remaining on the request's
+//     ctx in the X-Request-Timeout header
+//   - the server Middleware rebuilds a deadline from it, so the
+//     handler's r.Context() expires when the client's does
+//
+// The header holds a duration ("1.5s"), not a timestamp. A timestamp
+// would only mean the same thing on both sides if their clocks agreed.
+// A duration is wrong only by the time the request spent in transit,
+// and the server takes a further Skew off it so it gives up a little
+// before the client does, not a little after.
+//
+// The handler's ctx is derived from r.Context(), which net/http
+// cancels when the client disconnects, so a hung-up client cancels the
+// handler too.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Header carries the remaining time as a Go duration string.
+const Header = "X-Request-Timeout"
+
+// ErrHeaderDeadline is the context.Cause of a handler ctx whose
+// deadline came from the request header.
+var ErrHeaderDeadline = errors.New("deadline: deadline from " + Header + " exceeded")
+
+// ==========================================================
+// 1. CLIENT
+// ==========================================================
+
+// Transport sets Header from each request's context deadline.
+type Transport struct {
+	// Base performs the request. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	dl, ok := ctx.Deadline()
+	if !ok {
+		return t.base().RoundTrip(req)
+	}
+
+	remaining := time.Until(dl)
+	if remaining <= 0 {
+		// No point sending a request that is already late. RoundTrip
+		// must close the body even when it does not send it.
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, context.DeadlineExceeded
+	}
+
+	// A RoundTripper must not modify the caller's request.
+	req = req.Clone(ctx)
+	req.Header.Set(Header, Format(remaining))
+	return t.base().RoundTrip(req)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// Format renders d for Header, at millisecond precision.
+func Format(d time.Duration) string {
+	return d.Truncate(time.Millisecond).String()
+}
+
+// Parse reads Header. ok is false when it is absent.
+func Parse(h http.Header) (d time.Duration, ok bool, err error) {
+	v := h.Get(Header)
+	if v == "" {
+		return 0, false, nil
+	}
+	d, err = time.ParseDuration(v)
+	if err != nil {
+		return 0, false, fmt.Errorf("deadline: bad %s %q: %w", Header, v, err)
+	}
+	if d < 0 {
+		return 0, false, fmt.Errorf("deadline: bad %s %q: negative", Header, v)
+	}
+	return d, true, nil
+}
+
+// ==========================================================
+// 2. SERVER
+// ==========================================================
+
+// ServerConfig configures Middleware.
+type ServerConfig struct {
+	// Skew is taken off the client's timeout to allow for transit time
+	// and clocks that run at slightly different rates. Defaults to
+	// 10ms; negative means none.
+	Skew time.Duration
+
+	// Max caps the timeout a client may ask for. Zero means no cap.
+	Max time.Duration
+
+	// Default applies when the header is absent. Zero means no
+	// deadline.
+	Default time.Duration
+}
+
+func (c ServerConfig) withDefaults() ServerConfig {
+	if c.Skew == 0 {
+		c.Skew = 10 * time.Millisecond
+	}
+	if c.Skew < 0 {
+		c.Skew = 0
+	}
+	return c
+}
+
+// Middleware gives each request's context the deadline its client
+// sent. A request whose time is already up gets 504 Gateway Timeout
+// without reaching next; a malformed header gets 400 Bad Request.
+func Middleware(cfg ServerConfig) func(http.Handler) http.Handler {
+	cfg = cfg.withDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout, ok, err := Parse(r.Header)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var cause error
+			switch {
+			case ok:
+				timeout -= cfg.Skew
+				cause = ErrHeaderDeadline
+				if timeout <= 0 {
+					http.Error(w, "deadline: request arrived after its deadline", http.StatusGatewayTimeout)
+					return
+				}
+			case cfg.Default > 0:
+				timeout = cfg.Default
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if cfg.Max > 0 && timeout > cfg.Max {
+				timeout = cfg.Max
+				cause = nil
+			}
+
+			ctx, cancel := context.WithTimeoutCause(r.Context(), timeout, cause)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
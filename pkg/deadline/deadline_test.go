@@ -0,0 +1,206 @@
+package deadline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// deadlineServer records the deadline each handler ctx carries.
+func deadlineServer(t *testing.T, cfg ServerConfig) (*httptest.Server, <-chan time.Duration) {
+	t.Helper()
+	got := make(chan time.Duration, 1)
+	srv := httptest.NewServer(Middleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dl, ok := r.Context().Deadline()
+		if !ok {
+			got <- 0
+			return
+		}
+		got <- time.Until(dl)
+	})))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func get(t *testing.T, ctx context.Context, url string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := (&http.Client{Transport: &Transport{}}).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp
+}
+
+func TestPropagatesDeadline(t *testing.T) {
+	srv, got := deadlineServer(t, ServerConfig{Skew: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	get(t, ctx, srv.URL)
+
+	// 2s minus the skew, minus a little for the trip.
+	if d := <-got; d > 1950*time.Millisecond || d < 1500*time.Millisecond {
+		t.Fatalf("handler deadline in %v; want just under 1.95s", d)
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      ServerConfig
+		header   string
+		wantCode int
+		wantMin  time.Duration
+		wantMax  time.Duration
+	}{
+		{"no header, no default", ServerConfig{}, "", http.StatusOK, 0, 0},
+		{"default applies", ServerConfig{Default: time.Second}, "", http.StatusOK, 900 * time.Millisecond, time.Second},
+		{"max caps", ServerConfig{Max: time.Second}, "1h", http.StatusOK, 900 * time.Millisecond, time.Second},
+		{"already late", ServerConfig{Skew: 10 * time.Millisecond}, "5ms", http.StatusGatewayTimeout, 0, 0},
+		{"malformed", ServerConfig{}, "soon", http.StatusBadRequest, 0, 0},
+		{"negative", ServerConfig{}, "-1s", http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, got := deadlineServer(t, tt.cfg)
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("status = %d; want %d", resp.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				select {
+				case <-got:
+					t.Fatal("handler ran for a rejected request")
+				default:
+				}
+				return
+			}
+			if d := <-got; d < tt.wantMin || d > tt.wantMax {
+				t.Fatalf("handler deadline in %v; want between %v and %v", d, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestHandlerCtxExpiresWithHeaderCause(t *testing.T) {
+	cause := make(chan error, 1)
+	srv := httptest.NewServer(Middleware(ServerConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		cause <- context.Cause(r.Context())
+	})))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set(Header, "50ms")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := <-cause; !errors.Is(err, ErrHeaderDeadline) {
+		t.Fatalf("context.Cause = %v; want %v", err, ErrHeaderDeadline)
+	}
+}
+
+func TestClientDisconnectCancelsHandler(t *testing.T) {
+	started := make(chan struct{})
+	handlerErr := make(chan error, 1)
+	srv := httptest.NewServer(Middleware(ServerConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+			handlerErr <- r.Context().Err()
+		case <-time.After(5 * time.Second):
+			handlerErr <- errors.New("handler was never cancelled")
+		}
+	})))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	go func() {
+		<-started
+		cancel()
+	}()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := (&http.Client{Transport: &Transport{}}).Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("client Do = %v; want %v", err, context.Canceled)
+	}
+
+	if err := <-handlerErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("handler ctx = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestDeadlineShrinksAcrossHops(t *testing.T) {
+	back, got := deadlineServer(t, ServerConfig{Skew: 100 * time.Millisecond})
+
+	// The front service forwards its own request context downstream.
+	front := httptest.NewServer(Middleware(ServerConfig{Skew: 100 * time.Millisecond})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, back.URL, nil)
+		resp, err := (&http.Client{Transport: &Transport{}}).Do(req)
+		if err != nil {
+			t.Errorf("downstream request failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+	})))
+	defer front.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	get(t, ctx, front.URL)
+
+	// 1s minus two hops' skew.
+	if d := <-got; d > 800*time.Millisecond || d < 400*time.Millisecond {
+		t.Fatalf("backend deadline in %v; want just under 800ms", d)
+	}
+}
+
+func TestTransport_ExpiredContextIsNotSent(t *testing.T) {
+	srv, got := deadlineServer(t, ServerConfig{})
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	body := &closeRecorder{Reader: strings.NewReader("payload")}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, body)
+	if _, err := (&Transport{}).RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RoundTrip = %v; want %v", err, context.DeadlineExceeded)
+	}
+	if !body.closed {
+		t.Fatalf("RoundTrip did not close the request body")
+	}
+	select {
+	case <-got:
+		t.Fatal("request reached the server")
+	default:
+	}
+}
+
+// closeRecorder is a request body that records Close.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
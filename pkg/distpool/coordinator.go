@@ -0,0 +1,380 @@
+package distpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. CONFIGURATION
+// ==========================================================
+
+// ErrCoordinatorClosed is returned by Submit and Serve after Close.
+var ErrCoordinatorClosed = errors.New("distpool: coordinator is closed")
+
+// Config configures a Coordinator.
+type Config struct {
+	// LeaseDuration is how long a job stays with a worker without a
+	// heartbeat. Workers should heartbeat several times per lease.
+	// Defaults to 10s.
+	LeaseDuration time.Duration
+
+	// Clock drives lease expiry. Defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c Config) withDefaults() Config {
+	if c.LeaseDuration <= 0 {
+		c.LeaseDuration = 10 * time.Second
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+// Result is the recorded outcome of one job.
+type Result struct {
+	JobID   string
+	Worker  string
+	Attempt int
+	Output  json.RawMessage
+
+	// Err is the job's error message, empty on success. Errors do not
+	// cross the socket as values, only as text.
+	Err string
+}
+
+// ==========================================================
+// 2. COORDINATOR STATE
+// ==========================================================
+
+// jobState tracks one job from Submit to its single Result.
+type jobState struct {
+	job Job
+
+	// Set while leased.
+	lease   uint64
+	worker  string
+	expires time.Time
+
+	// grants records every lease ever issued for the job, current or
+	// expired, so a report can be checked against the one it names.
+	grants map[uint64]grant
+
+	done bool
+}
+
+type grant struct {
+	worker  string
+	attempt int
+}
+
+// Coordinator owns the job queue and every lease.
+type Coordinator struct {
+	cfg Config
+
+	mu        sync.Mutex
+	jobs      map[string]*jobState
+	queue     []string // job IDs waiting for a worker, FIFO
+	leases    map[uint64]*jobState
+	nextLease uint64
+	results   map[string]Result
+	changed   chan struct{} // closed and replaced on every result
+	closed    bool
+
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+// NewCoordinator returns a Coordinator with an empty queue.
+func NewCoordinator(cfg Config) *Coordinator {
+	return &Coordinator{
+		cfg:       cfg.withDefaults(),
+		jobs:      make(map[string]*jobState),
+		leases:    make(map[uint64]*jobState),
+		results:   make(map[string]Result),
+		changed:   make(chan struct{}),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Submit queues a job. payload is marshalled to JSON. Job IDs must be
+// unique for the life of the coordinator.
+func (c *Coordinator) Submit(id string, payload any) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("distpool: marshal payload of job %q: %w", id, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrCoordinatorClosed
+	}
+	if _, dup := c.jobs[id]; dup {
+		return fmt.Errorf("distpool: duplicate job %q", id)
+	}
+	c.jobs[id] = &jobState{job: Job{ID: id, Payload: raw}}
+	c.queue = append(c.queue, id)
+	return nil
+}
+
+// Result returns the result of job id, if it has one.
+func (c *Coordinator) Result(id string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.results[id]
+	return r, ok
+}
+
+// Wait blocks until every submitted job has a result, or ctx is done.
+func (c *Coordinator) Wait(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		finished := len(c.results) == len(c.jobs)
+		changed := c.changed
+		c.mu.Unlock()
+
+		if finished {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ==========================================================
+// 3. LEASES
+// ==========================================================
+
+// reapLocked puts every job whose lease has expired back at the front
+// of the queue, so a reassigned job does not wait behind new ones.
+func (c *Coordinator) reapLocked(now time.Time) {
+	var ids []uint64
+	for id, st := range c.leases {
+		if !st.expires.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	// Oldest lease first, so requeueing is deterministic.
+	slices.Sort(ids)
+
+	expired := make([]string, len(ids))
+	for i, id := range ids {
+		st := c.leases[id]
+		delete(c.leases, id)
+		st.lease, st.worker = 0, ""
+		expired[i] = st.job.ID
+	}
+	c.queue = append(expired, c.queue...)
+}
+
+func (c *Coordinator) lease(worker string) *Lease {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.cfg.Clock.Now()
+	c.reapLocked(now)
+
+	for len(c.queue) > 0 {
+		st := c.jobs[c.queue[0]]
+		c.queue = c.queue[1:]
+		if st.done {
+			// A late result from an expired lease already finished it.
+			continue
+		}
+
+		c.nextLease++
+		st.lease = c.nextLease
+		st.worker = worker
+		st.expires = now.Add(c.cfg.LeaseDuration)
+		st.job.Attempt++
+		if st.grants == nil {
+			st.grants = make(map[uint64]grant)
+		}
+		st.grants[st.lease] = grant{worker: worker, attempt: st.job.Attempt}
+		c.leases[st.lease] = st
+		return &Lease{ID: st.lease, Job: st.job}
+	}
+	return nil
+}
+
+func (c *Coordinator) heartbeat(worker string, ids []uint64) (lost []uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.cfg.Clock.Now()
+	c.reapLocked(now)
+
+	for _, id := range ids {
+		st, ok := c.leases[id]
+		if !ok || st.worker != worker {
+			lost = append(lost, id)
+			continue
+		}
+		st.expires = now.Add(c.cfg.LeaseDuration)
+	}
+	return lost
+}
+
+// report records the first result for a job and ignores the rest.
+//
+// The report must name a lease that was issued for the job to the
+// reporting worker; anything else is rejected. A result is accepted
+// even if its lease has expired: the work was done, and nobody else has
+// finished it yet. Any newer lease on the job is revoked, and that
+// worker learns so on its next heartbeat.
+func (c *Coordinator) report(worker string, req request) (duplicate bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st, ok := c.jobs[req.JobID]
+	if !ok {
+		return false, fmt.Errorf("unknown job %q", req.JobID)
+	}
+	g, ok := st.grants[req.Lease]
+	if !ok || g.worker != worker {
+		return false, fmt.Errorf("lease %d on job %q was not issued to worker %q", req.Lease, req.JobID, worker)
+	}
+	if st.done {
+		return true, nil
+	}
+
+	st.done = true
+	if st.lease != 0 {
+		delete(c.leases, st.lease)
+	}
+	c.results[req.JobID] = Result{
+		JobID:   req.JobID,
+		Worker:  worker,
+		Attempt: g.attempt,
+		Output:  req.Output,
+		Err:     req.Error,
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return false, nil
+}
+
+// ==========================================================
+// 4. SERVING
+// ==========================================================
+
+// Serve accepts worker connections on l until Close. It may be called
+// for several listeners, e.g. one TCP and one Unix socket.
+func (c *Coordinator) Serve(l net.Listener) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		l.Close()
+		return ErrCoordinatorClosed
+	}
+	c.listeners[l] = struct{}{}
+	c.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			c.mu.Lock()
+			closed := c.closed
+			c.mu.Unlock()
+			if closed {
+				return ErrCoordinatorClosed
+			}
+			return fmt.Errorf("distpool: accept: %w", err)
+		}
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			conn.Close()
+			return ErrCoordinatorClosed
+		}
+		c.conns[conn] = struct{}{}
+		c.wg.Add(1)
+		c.mu.Unlock()
+
+		go c.handle(conn)
+	}
+}
+
+// handle answers one worker's requests until it disconnects. A dropped
+// connection does not release leases: the worker may reconnect, and if
+// it does not, expiry reassigns its jobs.
+func (c *Coordinator) handle(conn net.Conn) {
+	defer c.wg.Done()
+	defer func() {
+		c.mu.Lock()
+		delete(c.conns, conn)
+		c.mu.Unlock()
+		conn.Close()
+	}()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+
+		var resp response
+		switch req.Type {
+		case msgLease:
+			resp.Lease = c.lease(req.Worker)
+		case msgHeartbeat:
+			resp.Lost = c.heartbeat(req.Worker, req.Leases)
+		case msgResult:
+			dup, err := c.report(req.Worker, req)
+			if err != nil {
+				resp.Error = err.Error()
+			}
+			resp.Duplicate = dup
+		default:
+			resp.Error = fmt.Sprintf("unknown message type %q", req.Type)
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// Close stops every listener, drops every connection and waits for the
+// connection goroutines to exit.
+func (c *Coordinator) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	for l := range c.listeners {
+		l.Close()
+	}
+	for conn := range c.conns {
+		conn.Close()
+	}
+	c.mu.Unlock()
+
+	c.wg.Wait()
+	return nil
+}
@@ -0,0 +1,374 @@
+package distpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// startCoordinator serves c on a fresh Unix socket and returns its path.
+func startCoordinator(t *testing.T, c *Coordinator) string {
+	t.Helper()
+	addr := filepath.Join(t.TempDir(), "coord.sock")
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatalf("Listen returned %v", err)
+	}
+	go c.Serve(l)
+	t.Cleanup(func() { c.Close() })
+	return addr
+}
+
+func dial(t *testing.T, addr, worker string) *Client {
+	t.Helper()
+	client, err := Dial("unix", addr, worker)
+	if err != nil {
+		t.Fatalf("Dial returned %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func mustLease(t *testing.T, client *Client) *Lease {
+	t.Helper()
+	lease, err := client.Lease()
+	if err != nil {
+		t.Fatalf("Lease returned %v", err)
+	}
+	if lease == nil {
+		t.Fatal("Lease returned no job")
+	}
+	return lease
+}
+
+func TestCoordinator_ReassignsExpiredLease(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	c := NewCoordinator(Config{LeaseDuration: 10 * time.Second, Clock: fake})
+	if err := c.Submit("job-1", map[string]int{"n": 1}); err != nil {
+		t.Fatalf("Submit returned %v", err)
+	}
+	addr := startCoordinator(t, c)
+	a, b := dial(t, addr, "a"), dial(t, addr, "b")
+
+	first := mustLease(t, a)
+	if none, err := b.Lease(); err != nil || none != nil {
+		t.Fatalf("second Lease = %v, %v; want no job while leased", none, err)
+	}
+
+	// A heartbeat inside the lease keeps it.
+	fake.Step(8 * time.Second)
+	if lost, err := a.Heartbeat(first.ID); err != nil || len(lost) != 0 {
+		t.Fatalf("Heartbeat = %v, %v; want nothing lost", lost, err)
+	}
+	fake.Step(8 * time.Second)
+	if none, _ := b.Lease(); none != nil {
+		t.Fatal("job was reassigned while its lease was renewed")
+	}
+
+	// Silence past the lease hands the job to b.
+	fake.Step(10 * time.Second)
+	second := mustLease(t, b)
+	if second.Job.ID != "job-1" || second.Job.Attempt != 2 {
+		t.Fatalf("reassigned job = %+v; want job-1 attempt 2", second.Job)
+	}
+	if lost, _ := a.Heartbeat(first.ID); !slices.Equal(lost, []uint64{first.ID}) {
+		t.Fatalf("old holder's Heartbeat lost = %v; want [%d]", lost, first.ID)
+	}
+}
+
+func TestCoordinator_RecordsResultAtMostOnce(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	c := NewCoordinator(Config{LeaseDuration: time.Second, Clock: fake})
+	c.Submit("job-1", nil)
+	addr := startCoordinator(t, c)
+	a, b := dial(t, addr, "a"), dial(t, addr, "b")
+
+	first := mustLease(t, a)
+	fake.Step(2 * time.Second)
+	second := mustLease(t, b)
+
+	// The slow original holder finishes first: its result wins.
+	if dup, err := a.Report(first.ID, "job-1", json.RawMessage(`"from a"`), nil); err != nil || dup {
+		t.Fatalf("first Report = %v, %v; want accepted", dup, err)
+	}
+	if dup, err := b.Report(second.ID, "job-1", json.RawMessage(`"from b"`), nil); err != nil || !dup {
+		t.Fatalf("second Report = %v, %v; want duplicate", dup, err)
+	}
+	// b's lease was revoked by the accepted result.
+	if lost, _ := b.Heartbeat(second.ID); !slices.Equal(lost, []uint64{second.ID}) {
+		t.Fatalf("revoked Heartbeat lost = %v; want [%d]", lost, second.ID)
+	}
+
+	res, ok := c.Result("job-1")
+	if !ok || res.Worker != "a" || res.Attempt != 1 || string(res.Output) != `"from a"` {
+		t.Fatalf("Result = %+v, %v; want a's output", res, ok)
+	}
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+}
+
+func TestCoordinator_RejectsUnknownAndDuplicateJobs(t *testing.T) {
+	c := NewCoordinator(Config{})
+	if err := c.Submit("job-1", nil); err != nil {
+		t.Fatalf("Submit returned %v", err)
+	}
+	if err := c.Submit("job-1", nil); err == nil {
+		t.Fatal("Submit of a duplicate ID returned nil")
+	}
+
+	addr := startCoordinator(t, c)
+	client := dial(t, addr, "a")
+	if _, err := client.Report(1, "ghost", nil, nil); err == nil {
+		t.Fatal("Report for an unknown job returned nil")
+	}
+}
+
+func TestCoordinator_RejectsReportsForOtherLeases(t *testing.T) {
+	c := NewCoordinator(Config{})
+	c.Submit("job-1", nil)
+	c.Submit("job-2", nil)
+	addr := startCoordinator(t, c)
+	a, b := dial(t, addr, "a"), dial(t, addr, "b")
+
+	first, second := mustLease(t, a), mustLease(t, a)
+	tests := []struct {
+		name   string
+		client *Client
+		lease  uint64
+		job    string
+	}{
+		{"never issued", a, 99, first.Job.ID},
+		{"issued for another job", a, second.ID, first.Job.ID},
+		{"issued to another worker", b, first.ID, first.Job.ID},
+	}
+	for _, tt := range tests {
+		if _, err := tt.client.Report(tt.lease, tt.job, json.RawMessage(`"forged"`), nil); err == nil {
+			t.Fatalf("%s: Report returned nil; want it rejected", tt.name)
+		}
+	}
+	if res, ok := c.Result(first.Job.ID); ok {
+		t.Fatalf("Result = %+v; want none after rejected reports", res)
+	}
+
+	if dup, err := a.Report(first.ID, first.Job.ID, nil, nil); err != nil || dup {
+		t.Fatalf("holder's Report = %v, %v; want accepted", dup, err)
+	}
+}
+
+func TestRunWorker_ProcessesJobs(t *testing.T) {
+	c := NewCoordinator(Config{LeaseDuration: time.Second})
+	const numJobs = 10
+	for i := 0; i < numJobs; i++ {
+		c.Submit(fmt.Sprintf("job-%d", i), i)
+	}
+	addr := startCoordinator(t, c)
+
+	square := func(_ context.Context, job Job) (any, error) {
+		var n int
+		if err := json.Unmarshal(job.Payload, &n); err != nil {
+			return nil, err
+		}
+		if n == 3 {
+			return nil, errors.New("three is unlucky")
+		}
+		return n * n, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	workers := make(chan error, 2)
+	for _, id := range []string{"w1", "w2"} {
+		cfg := WorkerConfig{ID: id, Network: "unix", Address: addr, PollInterval: 5 * time.Millisecond}
+		go func() { workers <- RunWorker(ctx, cfg, square) }()
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	if err := c.Wait(waitCtx); err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+
+	for i := 0; i < numJobs; i++ {
+		res, _ := c.Result(fmt.Sprintf("job-%d", i))
+		switch {
+		case i == 3 && res.Err != "three is unlucky":
+			t.Fatalf("job-3 Err = %q; want the handler's error", res.Err)
+		case i != 3 && string(res.Output) != fmt.Sprint(i*i):
+			t.Fatalf("job-%d output = %s; want %d", i, res.Output, i*i)
+		}
+	}
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		if err := <-workers; !errors.Is(err, context.Canceled) {
+			t.Fatalf("RunWorker returned %v; want %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestRunWorker_StopsWhileCoordinatorIsSilent(t *testing.T) {
+	// A coordinator that accepts and reads but never answers.
+	addr := filepath.Join(t.TempDir(), "silent.sock")
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatalf("Listen returned %v", err)
+	}
+	defer l.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+		io.Copy(io.Discard, conn)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- RunWorker(ctx, WorkerConfig{ID: "w", Network: "unix", Address: addr}, nil)
+	}()
+	conn := <-accepted
+	defer conn.Close()
+
+	cancel()
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("RunWorker returned %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunWorker still blocked on the silent coordinator after ctx was cancelled")
+	}
+}
+
+// ==========================================================
+// MULTI-PROCESS INTEGRATION
+// ==========================================================
+
+// The test binary re-executes itself as a worker process when these
+// variables are set (the os/exec "helper process" pattern).
+const (
+	envHelperAddr = "DISTPOOL_HELPER_ADDR"
+	envHelperID   = "DISTPOOL_HELPER_ID"
+	envHelperMode = "DISTPOOL_HELPER_MODE"
+)
+
+func TestHelperWorkerProcess(t *testing.T) {
+	addr := os.Getenv(envHelperAddr)
+	if addr == "" {
+		t.Skip("only runs as a subprocess of TestMultiProcess_*")
+	}
+
+	cfg := WorkerConfig{
+		ID:                os.Getenv(envHelperID),
+		Network:           "unix",
+		Address:           addr,
+		HeartbeatInterval: 20 * time.Millisecond,
+		PollInterval:      5 * time.Millisecond,
+	}
+	err := RunWorker(context.Background(), cfg, func(ctx context.Context, job Job) (any, error) {
+		switch os.Getenv(envHelperMode) {
+		case "crash":
+			// Die holding the lease, as a killed process would.
+			os.Exit(3)
+		case "slow":
+			// Outlive several leases; heartbeats must keep the job.
+			time.Sleep(300 * time.Millisecond)
+		}
+		return "done by " + os.Getenv(envHelperID), nil
+	})
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func startWorkerProcess(t *testing.T, addr, id, mode string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperWorkerProcess$")
+	cmd.Env = append(os.Environ(), envHelperAddr+"="+addr, envHelperID+"="+id, envHelperMode+"="+mode)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting worker %s: %v", id, err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd
+}
+
+func waitResult(t *testing.T, c *Coordinator, id string) Result {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.Wait(ctx); err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+	res, _ := c.Result(id)
+	return res
+}
+
+func TestMultiProcess_CrashedWorkerJobIsReassigned(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns worker processes")
+	}
+	c := NewCoordinator(Config{LeaseDuration: 100 * time.Millisecond})
+	c.Submit("job-1", nil)
+	addr := startCoordinator(t, c)
+
+	crasher := startWorkerProcess(t, addr, "crasher", "crash")
+	if err := crasher.Wait(); err == nil {
+		t.Fatal("crashing worker exited cleanly")
+	}
+
+	startWorkerProcess(t, addr, "survivor", "ok")
+	res := waitResult(t, c, "job-1")
+	if res.Worker != "survivor" || res.Attempt != 2 {
+		t.Fatalf("Result = %+v; want survivor on attempt 2", res)
+	}
+}
+
+func TestMultiProcess_HeartbeatsKeepLongJob(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns worker processes")
+	}
+	c := NewCoordinator(Config{LeaseDuration: 100 * time.Millisecond})
+	c.Submit("job-1", nil)
+	addr := startCoordinator(t, c)
+
+	startWorkerProcess(t, addr, "slow", "slow")
+	// Give the slow worker the job before anyone else can ask.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		leased := len(c.leases) == 1
+		c.mu.Unlock()
+		if leased {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("slow worker never leased the job")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	startWorkerProcess(t, addr, "idle", "ok")
+
+	res := waitResult(t, c, "job-1")
+	if res.Worker != "slow" || res.Attempt != 1 {
+		t.Fatalf("Result = %+v; want slow on attempt 1", res)
+	}
+}
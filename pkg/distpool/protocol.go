@@ -0,0 +1,180 @@
+// Package distpool spreads jobs across worker PROCESSES instead of
+// worker goroutines.
+//
+// The worker pool of 05-concurrency/06-patterns shares a jobs channel
+// inside one binary. Across processes there is no shared channel, and a
+// worker can die holding a job. distpool replaces the channel with a
+// coordinator that hands out LEASES over a TCP or Unix socket:
+//
+//	worker                       coordinator
+//	  |--- lease ------------------>|  job moves queued → leased
+//	  |<-- job + lease ID ----------|
+//	  |--- heartbeat(lease IDs) --->|  extends every lease it names
+//	  |--- result(lease, output) -->|  first result per job wins
+//
+// If heartbeats stop (the process crashed, the box froze), the lease
+// expires and the job goes back to the queue for another worker. A
+// result is recorded at most once per job, so a slow worker finishing
+// after its job was reassigned cannot produce a duplicate.
+//
+// Messages are newline-delimited JSON, one request and one response at
+// a time per connection.
+package distpool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// ==========================================================
+// 1. WIRE MESSAGES
+// ==========================================================
+
+const (
+	msgLease     = "lease"
+	msgHeartbeat = "heartbeat"
+	msgResult    = "result"
+)
+
+// Job is one unit of work. Payload is opaque JSON chosen by Submit.
+type Job struct {
+	ID      string          `json:"id"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	// Attempt counts leases of this job, starting at 1. A value above 1
+	// means an earlier worker lost its lease.
+	Attempt int `json:"attempt"`
+}
+
+// Lease is a time-limited claim on one job.
+type Lease struct {
+	ID  uint64 `json:"id"`
+	Job Job    `json:"job"`
+}
+
+type request struct {
+	Type   string          `json:"type"`
+	Worker string          `json:"worker"`
+	Leases []uint64        `json:"leases,omitempty"`
+	Lease  uint64          `json:"lease,omitempty"`
+	JobID  string          `json:"job_id,omitempty"`
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type response struct {
+	Lease     *Lease   `json:"lease,omitempty"`
+	Lost      []uint64 `json:"lost,omitempty"`
+	Duplicate bool     `json:"duplicate,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// ==========================================================
+// 2. CLIENT
+// ==========================================================
+
+// ErrClientClosed is returned by Client calls after Close.
+var ErrClientClosed = errors.New("distpool: client is closed")
+
+// Client is one worker's connection to the coordinator. It is safe for
+// concurrent use; calls are serialized on the connection.
+type Client struct {
+	worker string
+	conn   net.Conn
+	closed atomic.Bool // not under mu, so Close can interrupt a call
+
+	mu  sync.Mutex // serializes calls
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+// Dial connects to a coordinator. network is "tcp" or "unix".
+func Dial(network, address, workerID string) (*Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("distpool: dial %s %s: %w", network, address, err)
+	}
+	return &Client{
+		worker: workerID,
+		conn:   conn,
+		enc:    json.NewEncoder(conn),
+		dec:    json.NewDecoder(conn),
+	}, nil
+}
+
+// Lease asks for a job. It returns nil, nil when the queue is empty.
+func (c *Client) Lease() (*Lease, error) {
+	resp, err := c.call(request{Type: msgLease})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Lease, nil
+}
+
+// Heartbeat extends the named leases and returns the ones this worker
+// no longer holds. Work on a lost lease should stop.
+func (c *Client) Heartbeat(leases ...uint64) (lost []uint64, err error) {
+	resp, err := c.call(request{Type: msgHeartbeat, Leases: leases})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Lost, nil
+}
+
+// Report sends the outcome of a leased job. duplicate is true when the
+// job already had a result, in which case this one was discarded.
+func (c *Client) Report(lease uint64, jobID string, output json.RawMessage, jobErr error) (duplicate bool, err error) {
+	req := request{Type: msgResult, Lease: lease, JobID: jobID, Output: output}
+	if jobErr != nil {
+		req.Error = jobErr.Error()
+	}
+	resp, err := c.call(req)
+	if err != nil {
+		return false, err
+	}
+	return resp.Duplicate, nil
+}
+
+// Close closes the connection. A call waiting for the coordinator
+// returns ErrClientClosed at once.
+func (c *Client) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *Client) call(req request) (response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Load() {
+		return response{}, ErrClientClosed
+	}
+
+	req.Worker = c.worker
+	if err := c.enc.Encode(req); err != nil {
+		return response{}, c.connErr("send", req.Type, err)
+	}
+	var resp response
+	if err := c.dec.Decode(&resp); err != nil {
+		return response{}, c.connErr("receive", req.Type, err)
+	}
+	if resp.Error != "" {
+		return response{}, fmt.Errorf("distpool: coordinator rejected %s: %s", req.Type, resp.Error)
+	}
+	return resp, nil
+}
+
+// connErr reports a failed send or receive, as ErrClientClosed if
+// Close caused it.
+func (c *Client) connErr(op, msgType string, err error) error {
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+	return fmt.Errorf("distpool: %s %s: %w", op, msgType, err)
+}
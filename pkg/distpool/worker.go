@@ -0,0 +1,161 @@
+package distpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// Handler processes one job. ctx is cancelled when the worker learns it
+// lost the lease, or when the worker itself is stopping. The returned
+// value is marshalled to JSON as the job's output.
+type Handler func(ctx context.Context, job Job) (any, error)
+
+// WorkerConfig configures RunWorker.
+type WorkerConfig struct {
+	// ID names this worker in leases and results. Required.
+	ID string
+
+	// Network and Address locate the coordinator, e.g. "unix" and
+	// "/run/distpool.sock", or "tcp" and "127.0.0.1:7070".
+	Network string
+	Address string
+
+	// HeartbeatInterval is how often leases are renewed while a job
+	// runs. Keep it well below the coordinator's LeaseDuration.
+	// Defaults to 1s.
+	HeartbeatInterval time.Duration
+
+	// PollInterval is how long to wait after finding the queue empty.
+	// Defaults to 100ms.
+	PollInterval time.Duration
+
+	// Clock drives heartbeats and polling. Defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c WorkerConfig) withDefaults() WorkerConfig {
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = time.Second
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 100 * time.Millisecond
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+// RunWorker connects to the coordinator and processes jobs one at a
+// time until ctx is done or the connection fails. Start several
+// processes (or several RunWorker calls) for parallelism.
+func RunWorker(ctx context.Context, cfg WorkerConfig, handle Handler) error {
+	if cfg.ID == "" {
+		return errors.New("distpool: worker ID must not be empty")
+	}
+	cfg = cfg.withDefaults()
+
+	client, err := Dial(cfg.Network, cfg.Address, cfg.ID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// Unblock a pending call when ctx ends.
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	for {
+		lease, err := client.Lease()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		if lease == nil {
+			select {
+			case <-cfg.Clock.After(cfg.PollInterval):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := runLeased(ctx, cfg, client, lease, handle); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+	}
+}
+
+// runLeased runs one job while heartbeating its lease, then reports
+// the outcome unless the lease was lost along the way.
+func runLeased(ctx context.Context, cfg WorkerConfig, client *Client, lease *Lease, handle Handler) error {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lost := make(chan struct{})
+	beatDone := make(chan struct{})
+	go func() {
+		defer close(beatDone)
+		for {
+			select {
+			case <-cfg.Clock.After(cfg.HeartbeatInterval):
+			case <-jobCtx.Done():
+				return
+			}
+			gone, err := client.Heartbeat(lease.ID)
+			if err != nil || slices.Contains(gone, lease.ID) {
+				// Without a confirmed lease, another worker may already
+				// own the job: stop working on it.
+				close(lost)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	out, jobErr := safeHandle(jobCtx, handle, lease.Job)
+	cancel()
+	<-beatDone
+
+	select {
+	case <-lost:
+		return nil
+	default:
+	}
+	if ctx.Err() != nil {
+		// The worker is stopping, not the job failing: let the lease
+		// expire so the job is reassigned.
+		return ctx.Err()
+	}
+
+	raw, err := json.Marshal(out)
+	if err != nil && jobErr == nil {
+		jobErr = fmt.Errorf("distpool: marshal output: %w", err)
+		raw = nil
+	}
+	_, err = client.Report(lease.ID, lease.Job.ID, raw, jobErr)
+	return err
+}
+
+// safeHandle turns a handler panic into a job error so one bad job
+// does not take the worker process down.
+func safeHandle(ctx context.Context, handle Handler, job Job) (out any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, fmt.Errorf("distpool: job %q panicked: %v", job.ID, r)
+		}
+	}()
+	return handle(ctx, job)
+}
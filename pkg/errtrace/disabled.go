@@ -0,0 +1,10 @@
+//go:build errtrace_off
+
+package errtrace
+
+// Enabled reports whether new errors record traces. Built with
+// errtrace_off, it never does.
+func Enabled() bool { return false }
+
+// SetEnabled has no effect when built with errtrace_off.
+func SetEnabled(bool) {}
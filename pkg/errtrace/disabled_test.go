@@ -0,0 +1,24 @@
+//go:build errtrace_off
+
+package errtrace
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBuildTagDisables(t *testing.T) {
+	SetEnabled(true)
+	if Enabled() {
+		t.Fatalf("Enabled() = true with errtrace_off")
+	}
+	base := errors.New("permission denied")
+	err := Errorf("load: %w", base)
+	if _, ok := err.(*traced); ok || !errors.Is(err, base) {
+		t.Fatalf("Errorf = %T; want the plain fmt.Errorf error", err)
+	}
+	if got := fmt.Sprintf("%+v", err); got != "load: permission denied" {
+		t.Fatalf("%%+v = %q; want the message only", got)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !errtrace_off
+
+package errtrace
+
+import "sync/atomic"
+
+var enabled atomic.Bool
+
+// Enabled reports whether new errors record traces.
+func Enabled() bool { return enabled.Load() }
+
+// SetEnabled turns tracing on or off for errors created from now on.
+func SetEnabled(on bool) { enabled.Store(on) }
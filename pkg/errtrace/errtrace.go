@@ -0,0 +1,179 @@
+// Package errtrace records where an error was created and wrapped.
+//
+// deepErrorChain in 07-error-handling/03-error-is-as.go returns
+// "startup failed: failed to load config: permission denied". That
+// says what went wrong, but not where: the messages are all that
+// survive. This package adds call frames to an error, opt-in:
+//
+//	errtrace.SetEnabled(true)
+//	err := errtrace.Errorf("failed to load config: %w", ErrPermissionDenied)
+//	fmt.Printf("%+v\n", err) // message, then file:line for each frame
+//
+// The first traced error in a chain records the full stack. Wrapping
+// it again with Errorf or Wrap records only the wrap site, so a deep
+// chain stays cheap and the trace reads from the top-level call down.
+//
+// Tracing is off until SetEnabled(true). While off, New and Errorf
+// return exactly what errors.New and fmt.Errorf would and Wrap returns
+// its argument, so the cost is one atomic load. Building with
+// -tags errtrace_off makes that a constant false and removes even the
+// load.
+//
+// Traced errors unwrap to the error they carry, so errors.Is,
+// errors.As and errors.Unwrap behave exactly as without tracing.
+package errtrace
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+)
+
+// maxDepth bounds a full stack capture.
+const maxDepth = 32
+
+// ==========================================================
+// 1. CONSTRUCTORS
+// ==========================================================
+
+// New is errors.New, plus a stack trace when tracing is enabled.
+func New(msg string) error {
+	err := errors.New(msg)
+	if !Enabled() {
+		return err
+	}
+	return &traced{err: err, pcs: callers(maxDepth)}
+}
+
+// Errorf is fmt.Errorf, plus a trace when tracing is enabled. If the
+// result already wraps a trace (via %w), only the caller's frame is
+// recorded; an error formatted with %v or %s is not in the chain, so
+// its trace does not count.
+func Errorf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	if !Enabled() {
+		return err
+	}
+	depth := maxDepth
+	if hasTrace(err) {
+		depth = 1
+	}
+	return &traced{err: err, pcs: callers(depth)}
+}
+
+// Wrap attaches a trace to err without changing its message. It
+// returns nil for nil, and err itself when tracing is disabled.
+func Wrap(err error) error {
+	if err == nil || !Enabled() {
+		return err
+	}
+	depth := maxDepth
+	if hasTrace(err) {
+		depth = 1
+	}
+	return &traced{err: err, pcs: callers(depth)}
+}
+
+// callers returns up to depth program counters, starting at the
+// caller of the exported constructor.
+func callers(depth int) []uintptr {
+	pcs := make([]uintptr, depth)
+	// Skip runtime.Callers, callers and the constructor.
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// ==========================================================
+// 2. TRACED ERRORS
+// ==========================================================
+
+// traced is an error with the program counters of where it was made.
+type traced struct {
+	err error
+	pcs []uintptr
+}
+
+func (t *traced) Error() string { return t.err.Error() }
+func (t *traced) Unwrap() error { return t.err }
+
+// Format prints the message for %s, %v and %q, and the message plus
+// every trace in the chain, outermost first, for %+v.
+func (t *traced) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, t.Error())
+		for _, tr := range traces(t) {
+			for _, f := range tr.frames() {
+				fmt.Fprintf(s, "\n    %s\n        %s:%d", f.Function, f.File, f.Line)
+			}
+		}
+	case verb == 'q':
+		io.WriteString(s, strconv.Quote(t.Error()))
+	default:
+		io.WriteString(s, t.Error())
+	}
+}
+
+func (t *traced) frames() []Frame {
+	var out []Frame
+	frames := runtime.CallersFrames(t.pcs)
+	for {
+		f, more := frames.Next()
+		out = append(out, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		if !more {
+			return out
+		}
+	}
+}
+
+// Frame is one call site.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (f Frame) String() string {
+	return f.Function + " " + f.File + ":" + strconv.Itoa(f.Line)
+}
+
+// StackTrace returns the full stack recorded where err's chain was
+// first traced, or nil if it never was.
+func StackTrace(err error) []Frame {
+	all := traces(err)
+	if len(all) == 0 {
+		return nil
+	}
+	return all[len(all)-1].frames()
+}
+
+// traces lists every traced error in err's chain, outermost first.
+func traces(err error) []*traced {
+	var out []*traced
+	var walk func(error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		if t, ok := err.(*traced); ok {
+			out = append(out, t)
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				walk(e)
+			}
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		}
+	}
+	walk(err)
+	return out
+}
+
+func hasTrace(err error) bool {
+	var t *traced
+	return errors.As(err, &t)
+}
@@ -0,0 +1,193 @@
+//go:build !errtrace_off
+
+package errtrace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+var errPermissionDenied = errors.New("permission denied")
+
+type pathError struct{ Path string }
+
+func (e *pathError) Error() string { return "bad path " + e.Path }
+
+func enable(t *testing.T) {
+	t.Helper()
+	SetEnabled(true)
+	t.Cleanup(func() { SetEnabled(false) })
+}
+
+// The lesson's chain, with each layer traced.
+func readConfig() error { return Errorf("failed to read config: %w", errPermissionDenied) }
+func loadConfig() error { return Errorf("failed to load config: %w", readConfig()) }
+func startup() error    { return Errorf("startup failed: %w", loadConfig()) }
+
+func TestDisabledReturnsPlainErrors(t *testing.T) {
+	SetEnabled(false)
+
+	err := Errorf("load: %w", errPermissionDenied)
+	if _, ok := err.(*traced); ok {
+		t.Fatalf("Errorf returned %T while disabled; want the fmt.Errorf error", err)
+	}
+	if got := fmt.Sprintf("%+v", err); got != "load: permission denied" {
+		t.Fatalf("%%+v = %q; want the message only", got)
+	}
+	if Wrap(errPermissionDenied) != errPermissionDenied {
+		t.Fatalf("Wrap changed the error while disabled")
+	}
+	if StackTrace(err) != nil {
+		t.Fatalf("StackTrace = %v; want nil", StackTrace(err))
+	}
+}
+
+func TestChainMatchingIsPreserved(t *testing.T) {
+	enable(t)
+
+	pe := &pathError{Path: "/etc/app.yaml"}
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"errorf", Errorf("open: %w", errPermissionDenied)},
+		{"wrap", Wrap(fmt.Errorf("open: %w", errPermissionDenied))},
+		{"multiple %w", Errorf("open: %w; %w", errPermissionDenied, pe)},
+		{"nested", startup()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.err.(*traced); !ok {
+				t.Fatalf("got %T; want a traced error", tt.err)
+			}
+			if !errors.Is(tt.err, errPermissionDenied) {
+				t.Fatalf("errors.Is(%v, errPermissionDenied) = false", tt.err)
+			}
+			if errors.Is(tt.err, fs.ErrNotExist) {
+				t.Fatalf("errors.Is(%v, fs.ErrNotExist) = true", tt.err)
+			}
+		})
+	}
+
+	var target *pathError
+	if !errors.As(tests[2].err, &target) || target != pe {
+		t.Fatalf("errors.As did not find the *pathError")
+	}
+	if errors.Unwrap(Wrap(errPermissionDenied)) != errPermissionDenied {
+		t.Fatalf("Unwrap(Wrap(err)) is not err")
+	}
+	if Wrap(nil) != nil {
+		t.Fatalf("Wrap(nil) != nil")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	enable(t)
+	err := startup()
+
+	want := "startup failed: failed to load config: failed to read config: permission denied"
+	for _, verb := range []string{"%s", "%v"} {
+		if got := fmt.Sprintf(verb, err); got != want {
+			t.Fatalf("%s = %q; want %q", verb, got, want)
+		}
+	}
+	if got := fmt.Sprintf("%q", err); got != fmt.Sprintf("%q", want) {
+		t.Fatalf("%%q = %s", got)
+	}
+
+	got := fmt.Sprintf("%+v", err)
+	if !strings.HasPrefix(got, want+"\n") {
+		t.Fatalf("%%+v = %q; want it to start with the message", got)
+	}
+	// Outermost trace first: each wrap site, then the origin's stack.
+	order := []string{"errtrace.startup", "errtrace.loadConfig", "errtrace.readConfig", "errtrace.TestFormat"}
+	rest := got
+	for _, fn := range order {
+		i := strings.Index(rest, fn+"\n")
+		if i < 0 {
+			t.Fatalf("%%+v missing %s in order:\n%s", fn, got)
+		}
+		rest = rest[i:]
+	}
+	if !strings.Contains(got, "errtrace_test.go:") {
+		t.Fatalf("%%+v has no file:line:\n%s", got)
+	}
+}
+
+func TestWrapSitesRecordOneFrame(t *testing.T) {
+	enable(t)
+	err := startup()
+
+	all := traces(err)
+	if len(all) != 3 {
+		t.Fatalf("traces = %d; want 3", len(all))
+	}
+	for _, tr := range all[:2] {
+		if len(tr.pcs) != 1 {
+			t.Fatalf("wrap site recorded %d frames; want 1", len(tr.pcs))
+		}
+	}
+
+	st := StackTrace(err)
+	if len(st) < 2 || !strings.HasSuffix(st[0].Function, "errtrace.readConfig") {
+		t.Fatalf("StackTrace = %v; want it to start at readConfig", st)
+	}
+	if !strings.HasSuffix(st[1].Function, "errtrace.loadConfig") {
+		t.Fatalf("StackTrace[1] = %v; want loadConfig", st[1])
+	}
+}
+
+func TestErrorfWithoutWrapRecordsFullStack(t *testing.T) {
+	enable(t)
+
+	// %v flattens the inner error to text: its trace is gone from the
+	// chain, so this one must be complete.
+	err := Errorf("load: %v", readConfig())
+	all := traces(err)
+	if len(all) != 1 || len(all[0].pcs) < 2 {
+		t.Fatalf("traces = %d, frames = %d; want one full stack", len(all), len(all[0].pcs))
+	}
+	st := StackTrace(err)
+	if !strings.HasSuffix(st[0].Function, "TestErrorfWithoutWrapRecordsFullStack") {
+		t.Fatalf("StackTrace = %v; want it to start in the test", st)
+	}
+}
+
+func TestThroughPlainWrapping(t *testing.T) {
+	enable(t)
+
+	// A plain fmt.Errorf layer hides the trace from %+v, but not from
+	// StackTrace or from a traced layer above it.
+	inner := New("connection refused")
+	plain := fmt.Errorf("dial: %w", inner)
+	if got := fmt.Sprintf("%+v", plain); got != "dial: connection refused" {
+		t.Fatalf("%%+v on fmt error = %q", got)
+	}
+	if st := StackTrace(plain); len(st) == 0 || !strings.HasSuffix(st[0].Function, "TestThroughPlainWrapping") {
+		t.Fatalf("StackTrace = %v; want it to start in the test", st)
+	}
+
+	outer := Wrap(plain)
+	if n := len(outer.(*traced).pcs); n != 1 {
+		t.Fatalf("Wrap over a traced chain recorded %d frames; want 1", n)
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", outer), "TestThroughPlainWrapping") {
+		t.Fatalf("%%+v lost the inner trace")
+	}
+}
+
+func BenchmarkErrorf(b *testing.B) {
+	for _, on := range []bool{false, true} {
+		b.Run(fmt.Sprintf("enabled=%v", on), func(b *testing.B) {
+			SetEnabled(on)
+			defer SetEnabled(false)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = Errorf("load: %w", errPermissionDenied)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+// Package fairqueue is a fair-queuing front end for a worker pool,
+// modeled on Kubernetes API Priority and Fairness (APF).
+//
+// In the worker-pool lesson every producer shares one jobs channel, so
+// one noisy producer can fill it and starve everyone else. Here:
+//   - every request names a PRIORITY LEVEL and a FLOW (tenant)
+//   - each level gets a share of the total concurrency and its own queues
+//   - flows are shuffle-sharded onto queues, and queues are served by
+//     fair queuing, so a flow that sends more waits longer, not everyone
+//   - when a queue is full the request is rejected with *RejectedError
+//
+// Callers acquire a seat before doing the work:
+//
+//	finish, err := ctrl.Acquire(ctx, fairqueue.Request{Flow: tenant, PriorityLevel: "workload"})
+//	if err != nil {
+//		return err // *RejectedError: shed load, ask the caller to retry later
+//	}
+//	defer finish()
+//	process(job)
+package fairqueue
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. ERRORS
+// ==========================================================
+
+// ErrRejected matches every *RejectedError with errors.Is.
+var ErrRejected = errors.New("fairqueue: request rejected")
+
+// Reasons a request can be rejected, as reported by APF.
+const (
+	ReasonQueueFull        = "queue-full"
+	ReasonConcurrencyLimit = "concurrency-limit"
+	ReasonTimeout          = "time-out"
+	ReasonCancelled        = "cancelled"
+)
+
+// RejectedError reports why a request did not get a seat.
+type RejectedError struct {
+	PriorityLevel string
+	Flow          string
+	Reason        string
+
+	// Err is the context error for ReasonCancelled.
+	Err error
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("fairqueue: request from flow %q at priority level %q rejected: %s",
+		e.Flow, e.PriorityLevel, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrRejected) true.
+func (e *RejectedError) Is(target error) bool {
+	return target == ErrRejected
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// ==========================================================
+// 2. CONFIGURATION
+// ==========================================================
+
+// PriorityLevel configures one isolation class of requests.
+type PriorityLevel struct {
+	Name string
+
+	// ConcurrencyShares is this level's weight when Config.TotalConcurrency
+	// is split between levels.
+	ConcurrencyShares int
+
+	// Queues is how many fair queues the level has. Zero means requests
+	// that cannot run immediately are rejected instead of queued.
+	Queues int
+
+	// QueueLengthLimit is how many requests each queue holds. Defaults
+	// to 50, as in APF.
+	QueueLengthLimit int
+
+	// HandSize is how many queues each flow is shuffle-sharded onto.
+	// A request joins the shortest queue in its hand. Defaults to
+	// min(Queues, 8).
+	HandSize int
+}
+
+// Config configures a Controller.
+type Config struct {
+	// TotalConcurrency is the number of seats shared by all levels.
+	TotalConcurrency int
+
+	Levels []PriorityLevel
+
+	// QueueWaitTimeout bounds how long a request may wait in a queue.
+	// Zero means no limit beyond the caller's context.
+	QueueWaitTimeout time.Duration
+
+	// EstimatedServiceTime is the work charged to a flow when one of its
+	// requests starts. It is corrected with the measured duration when
+	// the request finishes. Defaults to 1s.
+	EstimatedServiceTime time.Duration
+
+	// Clock defaults to clock.RealClock. Tests use a *clock.FakeClock.
+	Clock clock.Clock
+}
+
+// Request identifies who is asking for a seat.
+type Request struct {
+	Flow          string
+	PriorityLevel string
+}
+
+// LevelStats is a snapshot of one priority level.
+type LevelStats struct {
+	ConcurrencyLimit int
+	Executing        int
+	Waiting          int
+	Rejected         int
+}
+
+// ==========================================================
+// 3. CONTROLLER
+// ==========================================================
+
+// Controller hands out seats across priority levels.
+type Controller struct {
+	clock     clock.Clock
+	timeout   time.Duration
+	estimated time.Duration
+
+	mu     sync.Mutex
+	levels map[string]*level
+}
+
+// New validates cfg and builds a Controller.
+func New(cfg Config) (*Controller, error) {
+	if cfg.TotalConcurrency <= 0 {
+		return nil, errors.New("fairqueue: TotalConcurrency must be positive")
+	}
+	if len(cfg.Levels) == 0 {
+		return nil, errors.New("fairqueue: at least one priority level is required")
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.RealClock{}
+	}
+	if cfg.EstimatedServiceTime <= 0 {
+		cfg.EstimatedServiceTime = time.Second
+	}
+
+	totalShares := 0
+	for _, pl := range cfg.Levels {
+		if pl.ConcurrencyShares <= 0 {
+			return nil, fmt.Errorf("fairqueue: priority level %q needs positive ConcurrencyShares", pl.Name)
+		}
+		totalShares += pl.ConcurrencyShares
+	}
+
+	c := &Controller{
+		clock:     cfg.Clock,
+		timeout:   cfg.QueueWaitTimeout,
+		estimated: cfg.EstimatedServiceTime,
+		levels:    make(map[string]*level, len(cfg.Levels)),
+	}
+
+	for _, pl := range cfg.Levels {
+		if _, dup := c.levels[pl.Name]; dup {
+			return nil, fmt.Errorf("fairqueue: duplicate priority level %q", pl.Name)
+		}
+		if pl.HandSize <= 0 || pl.HandSize > pl.Queues {
+			pl.HandSize = min(pl.Queues, 8)
+		}
+		if pl.QueueLengthLimit <= 0 {
+			pl.QueueLengthLimit = 50
+		}
+
+		// Same rounding as APF: every level gets at least one seat.
+		limit := int(math.Ceil(float64(cfg.TotalConcurrency) * float64(pl.ConcurrencyShares) / float64(totalShares)))
+
+		l := &level{config: pl, limit: limit}
+		for i := 0; i < pl.Queues; i++ {
+			l.queues = append(l.queues, &queue{requests: list.New()})
+		}
+		c.levels[pl.Name] = l
+	}
+
+	return c, nil
+}
+
+// Acquire blocks until req gets a seat and returns the function that
+// gives the seat back. finish MUST be called exactly once.
+func (c *Controller) Acquire(ctx context.Context, req Request) (finish func(), err error) {
+	c.mu.Lock()
+
+	l, ok := c.levels[req.PriorityLevel]
+	if !ok {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("fairqueue: unknown priority level %q", req.PriorityLevel)
+	}
+
+	// Fast path: a free seat and nobody waiting ahead of us.
+	if l.executing < l.limit && l.waiting == 0 {
+		q := l.pickQueue(req.Flow)
+		q.virtualFinish = max(q.virtualFinish, l.virtualTime)
+		finish := c.startLocked(l, q)
+		c.mu.Unlock()
+		return finish, nil
+	}
+
+	if len(l.queues) == 0 {
+		l.rejected++
+		c.mu.Unlock()
+		return nil, &RejectedError{PriorityLevel: l.config.Name, Flow: req.Flow, Reason: ReasonConcurrencyLimit}
+	}
+
+	q := l.pickQueue(req.Flow)
+	if q.requests.Len() >= l.config.QueueLengthLimit {
+		l.rejected++
+		c.mu.Unlock()
+		return nil, &RejectedError{PriorityLevel: l.config.Name, Flow: req.Flow, Reason: ReasonQueueFull}
+	}
+
+	w := &waiter{arrived: c.clock.Now(), ready: make(chan struct{})}
+	if q.requests.Len() == 0 {
+		// An idle queue must not bank credit while it had nothing to send.
+		q.virtualFinish = max(q.virtualFinish, l.virtualTime)
+	}
+	w.elem = q.requests.PushBack(w)
+	w.queue = q
+	l.waiting++
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if c.timeout > 0 {
+		timer := c.clock.NewTimer(c.timeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	select {
+	case <-w.ready:
+		return w.finish, nil
+	case <-timeout:
+		return c.abandon(l, w, req, ReasonTimeout, nil)
+	case <-ctx.Done():
+		return c.abandon(l, w, req, ReasonCancelled, ctx.Err())
+	}
+}
+
+// Do runs fn while holding a seat for req.
+func (c *Controller) Do(ctx context.Context, req Request, fn func(ctx context.Context) error) error {
+	finish, err := c.Acquire(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer finish()
+	return fn(ctx)
+}
+
+// Stats returns a snapshot of the named priority level.
+func (c *Controller) Stats(priorityLevel string) (LevelStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, ok := c.levels[priorityLevel]
+	if !ok {
+		return LevelStats{}, false
+	}
+	return LevelStats{
+		ConcurrencyLimit: l.limit,
+		Executing:        l.executing,
+		Waiting:          l.waiting,
+		Rejected:         l.rejected,
+	}, true
+}
+
+// abandon removes w from its queue after a timeout or cancellation,
+// unless it was dispatched in the meantime.
+func (c *Controller) abandon(l *level, w *waiter, req Request, reason string, cause error) (func(), error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if w.finish != nil {
+		// Lost the race: we already hold a seat, so use it.
+		return w.finish, nil
+	}
+
+	w.queue.requests.Remove(w.elem)
+	l.waiting--
+	l.rejected++
+	return nil, &RejectedError{PriorityLevel: l.config.Name, Flow: req.Flow, Reason: reason, Err: cause}
+}
+
+// startLocked occupies a seat charged to q and returns its finish func.
+func (c *Controller) startLocked(l *level, q *queue) func() {
+	l.executing++
+	q.virtualFinish += c.estimated
+	started := c.clock.Now()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			// Replace the estimate with what the request really cost.
+			q.virtualFinish += c.clock.Since(started) - c.estimated
+			l.executing--
+			c.dispatchLocked(l)
+		})
+	}
+}
+
+// dispatchLocked fills free seats from the queues, always serving the
+// queue that has received the least service so far.
+func (c *Controller) dispatchLocked(l *level) {
+	for l.executing < l.limit && l.waiting > 0 {
+		q := l.nextQueue()
+		w := q.requests.Remove(q.requests.Front()).(*waiter)
+		l.waiting--
+
+		l.virtualTime = max(l.virtualTime, q.virtualFinish)
+		w.finish = c.startLocked(l, q)
+		close(w.ready)
+	}
+}
+
+// ==========================================================
+// 4. LEVELS & QUEUES
+// ==========================================================
+
+type level struct {
+	config PriorityLevel
+	limit  int
+	queues []*queue
+
+	executing int
+	waiting   int
+	rejected  int
+
+	// virtualTime is the service mark of the last dispatched queue.
+	// Queues that wake up from idle start from here.
+	virtualTime time.Duration
+}
+
+type queue struct {
+	requests *list.List // of *waiter
+
+	// virtualFinish is the total service this queue has received.
+	virtualFinish time.Duration
+}
+
+type waiter struct {
+	arrived time.Time
+	ready   chan struct{}
+	finish  func()
+	queue   *queue
+	elem    *list.Element
+}
+
+// pickQueue shuffle-shards flow onto HandSize queues and returns the
+// shortest of them. With no queues it returns an empty queue that is
+// not part of the level; Acquire rejects before calling it then.
+func (l *level) pickQueue(flow string) *queue {
+	if len(l.queues) == 0 {
+		return &queue{requests: list.New()}
+	}
+
+	var best *queue
+	for _, idx := range deal(flow, len(l.queues), l.config.HandSize) {
+		q := l.queues[idx]
+		if best == nil || q.requests.Len() < best.requests.Len() {
+			best = q
+		}
+	}
+	return best
+}
+
+// nextQueue returns the non-empty queue with the least service,
+// breaking ties by the arrival time of the head request.
+func (l *level) nextQueue() *queue {
+	var best *queue
+	for _, q := range l.queues {
+		if q.requests.Len() == 0 {
+			continue
+		}
+		if best == nil || q.virtualFinish < best.virtualFinish ||
+			(q.virtualFinish == best.virtualFinish && head(q).arrived.Before(head(best).arrived)) {
+			best = q
+		}
+	}
+	return best
+}
+
+func head(q *queue) *waiter {
+	return q.requests.Front().Value.(*waiter)
+}
+
+// deal picks handSize distinct queue indexes for flow. The same flow
+// always gets the same hand, and two flows rarely share a whole hand.
+func deal(flow string, deckSize, handSize int) []int {
+	h := fnv.New64a()
+	h.Write([]byte(flow))
+	seed := h.Sum64()
+
+	remaining := make([]int, deckSize)
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	hand := make([]int, 0, handSize)
+	for i := 0; i < handSize; i++ {
+		n := uint64(len(remaining))
+		pick := int(seed % n)
+		seed /= n
+		if seed == 0 {
+			// Re-mix so large hands still look random.
+			seed = uint64(i+1) * 0x9E3779B97F4A7C15
+		}
+
+		hand = append(hand, remaining[pick])
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+	}
+
+	sort.Ints(hand)
+	return hand
+}
@@ -0,0 +1,263 @@
+package fairqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newController(t *testing.T, fake *clock.FakeClock, levels ...PriorityLevel) *Controller {
+	t.Helper()
+	c, err := New(Config{
+		TotalConcurrency:     1,
+		Levels:               levels,
+		QueueWaitTimeout:     10 * time.Second,
+		EstimatedServiceTime: time.Second,
+		Clock:                fake,
+	})
+	if err != nil {
+		t.Fatalf("New() returned %v", err)
+	}
+	return c
+}
+
+type started struct {
+	flow   string
+	finish func()
+}
+
+// enqueue starts a goroutine that acquires a seat for flow and waits
+// until the request is visibly queued.
+func enqueue(t *testing.T, c *Controller, level, flow string, out chan<- started) {
+	t.Helper()
+
+	before, _ := c.Stats(level)
+	go func() {
+		finish, err := c.Acquire(context.Background(), Request{Flow: flow, PriorityLevel: level})
+		if err != nil {
+			t.Errorf("Acquire(%s) returned %v", flow, err)
+			return
+		}
+		out <- started{flow: flow, finish: finish}
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		s, _ := c.Stats(level)
+		if s.Waiting == before.Waiting+1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("request from %s was never queued", flow)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestController_NoisyFlowDoesNotStarveQuietFlow(t *testing.T) {
+	// With one queue per flow, holder, noisy and quiet land on
+	// different queues of the eight.
+	if deal("noisy", 8, 1)[0] == deal("tenant-a", 8, 1)[0] {
+		t.Fatal("test flows share a queue; pick different names")
+	}
+
+	fake := clock.NewFakeClock(epoch)
+	c := newController(t, fake, PriorityLevel{
+		Name: "workload", ConcurrencyShares: 1, Queues: 8, QueueLengthLimit: 10, HandSize: 1,
+	})
+
+	holder, err := c.Acquire(context.Background(), Request{Flow: "holder", PriorityLevel: "workload"})
+	if err != nil {
+		t.Fatalf("Acquire(holder) returned %v", err)
+	}
+
+	out := make(chan started)
+	for i := 0; i < 3; i++ {
+		enqueue(t, c, "workload", "noisy", out)
+		fake.Step(time.Millisecond)
+	}
+	enqueue(t, c, "workload", "tenant-a", out)
+
+	fake.Step(time.Second)
+	holder()
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		s := <-out
+		order = append(order, s.flow)
+		fake.Step(time.Second)
+		s.finish()
+	}
+
+	want := []string{"noisy", "tenant-a", "noisy", "noisy"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("dispatch order = %v; want %v", order, want)
+		}
+	}
+}
+
+func TestController_RejectsWhenQueueFull(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	c := newController(t, fake, PriorityLevel{
+		Name: "workload", ConcurrencyShares: 1, Queues: 1, QueueLengthLimit: 1,
+	})
+
+	finish, err := c.Acquire(context.Background(), Request{Flow: "a", PriorityLevel: "workload"})
+	if err != nil {
+		t.Fatalf("Acquire returned %v", err)
+	}
+
+	out := make(chan started, 1)
+	enqueue(t, c, "workload", "a", out)
+
+	_, err = c.Acquire(context.Background(), Request{Flow: "b", PriorityLevel: "workload"})
+	if !errors.Is(err, ErrRejected) {
+		t.Fatalf("Acquire on full queue = %v; want ErrRejected", err)
+	}
+	var rErr *RejectedError
+	if !errors.As(err, &rErr) || rErr.Reason != ReasonQueueFull {
+		t.Fatalf("Acquire on full queue = %v; want reason %q", err, ReasonQueueFull)
+	}
+
+	finish()
+	(<-out).finish()
+
+	if s, _ := c.Stats("workload"); s.Rejected != 1 {
+		t.Fatalf("Stats().Rejected = %d; want 1", s.Rejected)
+	}
+}
+
+func TestController_DefaultQueueLengthLimit(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	c := newController(t, fake, PriorityLevel{Name: "workload", ConcurrencyShares: 1, Queues: 1})
+
+	finish, err := c.Acquire(context.Background(), Request{Flow: "a", PriorityLevel: "workload"})
+	if err != nil {
+		t.Fatalf("Acquire returned %v", err)
+	}
+
+	// With QueueLengthLimit unset, the request queues instead of being
+	// rejected.
+	out := make(chan started, 1)
+	enqueue(t, c, "workload", "a", out)
+
+	finish()
+	(<-out).finish()
+	if s, _ := c.Stats("workload"); s.Rejected != 0 {
+		t.Fatalf("Stats().Rejected = %d; want 0", s.Rejected)
+	}
+}
+
+func TestController_RejectsWithoutQueues(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	c := newController(t, fake, PriorityLevel{Name: "exempt-ish", ConcurrencyShares: 1})
+
+	finish, err := c.Acquire(context.Background(), Request{Flow: "a", PriorityLevel: "exempt-ish"})
+	if err != nil {
+		t.Fatalf("Acquire returned %v", err)
+	}
+	defer finish()
+
+	_, err = c.Acquire(context.Background(), Request{Flow: "a", PriorityLevel: "exempt-ish"})
+	var rErr *RejectedError
+	if !errors.As(err, &rErr) || rErr.Reason != ReasonConcurrencyLimit {
+		t.Fatalf("Acquire over limit = %v; want reason %q", err, ReasonConcurrencyLimit)
+	}
+}
+
+func TestController_QueueWaitTimeout(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	c := newController(t, fake, PriorityLevel{
+		Name: "workload", ConcurrencyShares: 1, Queues: 1, QueueLengthLimit: 1,
+	})
+
+	finish, err := c.Acquire(context.Background(), Request{Flow: "a", PriorityLevel: "workload"})
+	if err != nil {
+		t.Fatalf("Acquire returned %v", err)
+	}
+	defer finish()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.Acquire(context.Background(), Request{Flow: "b", PriorityLevel: "workload"})
+		errCh <- err
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !fake.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("request never started waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	fake.Step(10 * time.Second)
+
+	var rErr *RejectedError
+	if err := <-errCh; !errors.As(err, &rErr) || rErr.Reason != ReasonTimeout {
+		t.Fatalf("Acquire after timeout = %v; want reason %q", err, ReasonTimeout)
+	}
+	if s, _ := c.Stats("workload"); s.Waiting != 0 {
+		t.Fatalf("Stats().Waiting = %d; want 0", s.Waiting)
+	}
+}
+
+func TestController_CancelledWhileQueued(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	c := newController(t, fake, PriorityLevel{
+		Name: "workload", ConcurrencyShares: 1, Queues: 1, QueueLengthLimit: 1,
+	})
+
+	finish, err := c.Acquire(context.Background(), Request{Flow: "a", PriorityLevel: "workload"})
+	if err != nil {
+		t.Fatalf("Acquire returned %v", err)
+	}
+	defer finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = c.Acquire(ctx, Request{Flow: "b", PriorityLevel: "workload"})
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrRejected) {
+		t.Fatalf("Acquire with cancelled ctx = %v; want ErrRejected wrapping context.Canceled", err)
+	}
+}
+
+func TestNew_SplitsConcurrencyByShares(t *testing.T) {
+	c, err := New(Config{
+		TotalConcurrency: 10,
+		Levels: []PriorityLevel{
+			{Name: "system", ConcurrencyShares: 3},
+			{Name: "workload", ConcurrencyShares: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() returned %v", err)
+	}
+
+	tests := []struct {
+		level string
+		want  int
+	}{
+		{"system", 8},
+		{"workload", 3},
+	}
+	for _, tt := range tests {
+		s, ok := c.Stats(tt.level)
+		if !ok {
+			t.Fatalf("Stats(%q) not found", tt.level)
+		}
+		if s.ConcurrencyLimit != tt.want {
+			t.Fatalf("Stats(%q).ConcurrencyLimit = %d; want %d", tt.level, s.ConcurrencyLimit, tt.want)
+		}
+	}
+
+	if _, err := c.Acquire(context.Background(), Request{PriorityLevel: "missing"}); err == nil {
+		t.Fatal("Acquire on unknown level returned nil error")
+	}
+}
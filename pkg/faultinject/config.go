@@ -0,0 +1,143 @@
+package faultinject
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ==========================================================
+// 4. LOADING RULES
+// ==========================================================
+
+/*
+The JSON form mirrors Config:
+
+	{
+	  "seed": 42,
+	  "rules": {
+	    "flaky": {
+	      "faults": [
+	        {"rate": 0.5, "error": "temporary failure"},
+	        {"rate": 0.2, "error": "permanent failure"},
+	        {"calls": [3], "error": "timeout"}
+	      ],
+	      "latency": "20ms",
+	      "jitter": "5ms"
+	    }
+	  }
+	}
+
+Error names are resolved through Config.Errors after loading, so set
+that (and Clock) on the returned Config before calling New.
+*/
+
+// EnvVar is the environment variable FromEnv reads by default.
+const EnvVar = "FAULTINJECT"
+
+// Duration is a time.Duration that reads and writes JSON as "150ms".
+// Plain numbers are taken as nanoseconds.
+type Duration time.Duration
+
+// Duration returns d as a time.Duration.
+func (d Duration) Duration() time.Duration { return time.Duration(d) }
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("faultinject: duration must be a string or nanoseconds: %s", data)
+		}
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("faultinject: %w", err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Parse decodes and validates a JSON config.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("faultinject: decode config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Load reads a JSON config file.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("faultinject: %w", err)
+	}
+	return Parse(data)
+}
+
+// FromEnv reads a config from the environment variable name (EnvVar if
+// empty). The value is either inline JSON or the path of a JSON file.
+// ok is false when the variable is unset or empty.
+func FromEnv(name string) (cfg Config, ok bool, err error) {
+	if name == "" {
+		name = EnvVar
+	}
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return Config{}, false, nil
+	}
+	if strings.HasPrefix(v, "{") {
+		cfg, err = Parse([]byte(v))
+	} else {
+		cfg, err = Load(v)
+	}
+	if err != nil {
+		return Config{}, false, fmt.Errorf("%s: %w", name, err)
+	}
+	return cfg, true, nil
+}
+
+// Validate checks rates and call numbers.
+func (c Config) Validate() error {
+	var errs []error
+	for _, op := range slices.Sorted(maps.Keys(c.Rules)) {
+		r := c.Rules[op]
+		total := 0.0
+		for i, f := range r.Faults {
+			if f.Rate < 0 || f.Rate > 1 {
+				errs = append(errs, fmt.Errorf("faultinject: %s fault %d: rate %v outside [0, 1]", op, i, f.Rate))
+			}
+			total += f.Rate
+			for _, n := range f.Calls {
+				if n < 1 {
+					errs = append(errs, fmt.Errorf("faultinject: %s fault %d: call %d must be >= 1", op, i, n))
+				}
+			}
+		}
+		if total > 1+1e-9 {
+			errs = append(errs, fmt.Errorf("faultinject: %s: rates add up to %v, more than 1", op, total))
+		}
+		if r.Latency < 0 || r.Jitter < 0 {
+			errs = append(errs, fmt.Errorf("faultinject: %s: negative latency", op))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,258 @@
+// Package faultinject makes failures reproducible.
+//
+// flakyOperation in 07-error-handling/05-retry-backoff-patterns rolls
+// rand.Intn(10) on every call, so no two runs fail the same way and a
+// test of the retry loop around it proves little. An Injector replaces
+// the dice: operations call Inject with their name, and a Rule decides
+// whether that call is slowed down, fails, or goes through.
+//
+// Rules can say
+//
+//   - fail with a given error at some rate ("50% temporary, 20% permanent")
+//   - fail exactly the Nth call ("the 3rd write times out")
+//   - add latency, optionally with jitter
+//
+// Every operation gets its own random stream derived from Config.Seed
+// and its name, so the same seed replays the same faults even when
+// operations interleave differently.
+//
+// Rules come from code, a JSON file (Load) or an environment variable
+// (FromEnv). A nil *Injector injects nothing, so production code can
+// keep its Inject calls.
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. ERRORS
+// ==========================================================
+
+// ErrInjected is matched by every injected failure.
+var ErrInjected = errors.New("faultinject: injected fault")
+
+// InjectedError is returned by Inject when a fault fires. It unwraps
+// to the configured error, so errors.Is(err, ErrTemporaryFailure)
+// works as if the real dependency had failed.
+type InjectedError struct {
+	Op   string
+	Call int // 1-based
+	Err  error
+}
+
+func (e *InjectedError) Error() string {
+	return fmt.Sprintf("faultinject: %s call %d: %v", e.Op, e.Call, e.Err)
+}
+
+func (e *InjectedError) Unwrap() error { return e.Err }
+
+func (e *InjectedError) Is(target error) bool { return target == ErrInjected }
+
+// ==========================================================
+// 2. RULES
+// ==========================================================
+
+// Fault is one way an operation can fail.
+type Fault struct {
+	// Rate is the probability of this fault in [0, 1]. The rates of an
+	// operation's faults are slices of one roll, so {0.5, 0.2} means
+	// 50% the first fault, 20% the second and 30% success.
+	Rate float64 `json:"rate,omitempty"`
+
+	// Calls lists 1-based call numbers that always fail with this
+	// fault, regardless of Rate.
+	Calls []int `json:"calls,omitempty"`
+
+	// Err is the error to return. When nil, Error names an error
+	// registered with Config.Errors, or becomes a plain error with that
+	// text.
+	Err   error  `json:"-"`
+	Error string `json:"error,omitempty"`
+}
+
+// Rule configures one named operation.
+type Rule struct {
+	Faults []Fault `json:"faults,omitempty"`
+
+	// Latency is added to every call, plus a uniform random extra of up
+	// to Jitter.
+	Latency Duration `json:"latency,omitempty"`
+	Jitter  Duration `json:"jitter,omitempty"`
+}
+
+// Config configures an Injector.
+type Config struct {
+	// Seed makes runs repeatable. Zero is a valid seed.
+	Seed uint64 `json:"seed"`
+
+	// Rules maps operation names to rules. Operations without a rule
+	// are left alone.
+	Rules map[string]Rule `json:"rules"`
+
+	// Errors resolves Fault.Error names from JSON to real error values,
+	// so injected failures match the sentinels the code checks for.
+	Errors map[string]error `json:"-"`
+
+	// Clock sleeps the latency. Defaults to clock.RealClock.
+	Clock clock.Clock `json:"-"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+// ==========================================================
+// 3. INJECTOR
+// ==========================================================
+
+// opState is the per-operation call counter and random stream.
+type opState struct {
+	calls int
+	rand  *rand.Rand
+}
+
+// Injector applies rules to named operations. It is safe for concurrent
+// use.
+type Injector struct {
+	cfg Config
+
+	mu  sync.Mutex
+	ops map[string]*opState
+}
+
+// New returns an Injector for cfg.
+func New(cfg Config) *Injector {
+	return &Injector{cfg: cfg.withDefaults(), ops: make(map[string]*opState)}
+}
+
+// SetRule replaces the rule for op. The call count is kept.
+func (in *Injector) SetRule(op string, r Rule) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	rules := make(map[string]Rule, len(in.cfg.Rules)+1)
+	for k, v := range in.cfg.Rules {
+		rules[k] = v
+	}
+	rules[op] = r
+	in.cfg.Rules = rules
+}
+
+// Calls returns how many times op has called Inject.
+func (in *Injector) Calls(op string) int {
+	if in == nil {
+		return 0
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if s, ok := in.ops[op]; ok {
+		return s.calls
+	}
+	return 0
+}
+
+// Reset forgets call counts and restarts every random stream from the
+// seed.
+func (in *Injector) Reset() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.ops = make(map[string]*opState)
+}
+
+// Inject is called by op at the point where it would talk to its
+// dependency. It waits out any configured latency, then returns nil or
+// an *InjectedError. If ctx ends during the latency, ctx.Err() is
+// returned.
+func (in *Injector) Inject(ctx context.Context, op string) error {
+	if in == nil {
+		return nil
+	}
+
+	delay, err := in.decide(op)
+	if delay > 0 {
+		timer := in.cfg.Clock.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// decide counts the call and rolls the dice under the lock, so the
+// outcome depends only on the seed and the call number.
+func (in *Injector) decide(op string) (time.Duration, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	s, ok := in.ops[op]
+	if !ok {
+		s = &opState{rand: rand.New(rand.NewPCG(in.cfg.Seed, hashName(op)))}
+		in.ops[op] = s
+	}
+	s.calls++
+
+	rule, ok := in.cfg.Rules[op]
+	if !ok {
+		return 0, nil
+	}
+
+	// Always roll, even when a Calls fault decides the outcome, so the
+	// stream for later calls does not depend on which rule fired.
+	roll := s.rand.Float64()
+	jitter := s.rand.Float64()
+
+	delay := rule.Latency.Duration()
+	if rule.Jitter > 0 {
+		delay += time.Duration(jitter * float64(rule.Jitter))
+	}
+
+	for _, f := range rule.Faults {
+		if slices.Contains(f.Calls, s.calls) {
+			return delay, in.injected(op, s.calls, f)
+		}
+	}
+	cum := 0.0
+	for _, f := range rule.Faults {
+		cum += f.Rate
+		if f.Rate > 0 && roll < cum {
+			return delay, in.injected(op, s.calls, f)
+		}
+	}
+	return delay, nil
+}
+
+func (in *Injector) injected(op string, call int, f Fault) error {
+	err := f.Err
+	if err == nil {
+		if known, ok := in.cfg.Errors[f.Error]; ok {
+			err = known
+		} else if f.Error != "" {
+			err = errors.New(f.Error)
+		} else {
+			err = ErrInjected
+		}
+	}
+	return &InjectedError{Op: op, Call: call, Err: err}
+}
+
+func hashName(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return h.Sum64()
+}
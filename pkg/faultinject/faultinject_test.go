@@ -0,0 +1,237 @@
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/retry"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	errTemporary = errors.New("temporary failure")
+	errPermanent = errors.New("permanent failure")
+)
+
+func waitForWaiters(t *testing.T, c *clock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a clock waiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// flaky is flakyOperation's 50/20/30 split.
+func flaky(seed uint64) Config {
+	return Config{Seed: seed, Rules: map[string]Rule{
+		"flaky": {Faults: []Fault{{Rate: 0.5, Err: errTemporary}, {Rate: 0.2, Err: errPermanent}}},
+	}}
+}
+
+func outcomes(in *Injector, op string, n int) []error {
+	out := make([]error, n)
+	for i := range out {
+		out[i] = errors.Unwrap(in.Inject(context.Background(), op))
+	}
+	return out
+}
+
+func TestInjector_SameSeedSameFaults(t *testing.T) {
+	a := outcomes(New(flaky(7)), "flaky", 200)
+	b := outcomes(New(flaky(7)), "flaky", 200)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("call %d: %v vs %v; want identical runs for one seed", i+1, a[i], b[i])
+		}
+	}
+
+	// Another operation's calls do not shift this operation's stream.
+	in := New(flaky(7))
+	in.SetRule("other", Rule{Faults: []Fault{{Rate: 0.5}}})
+	for i := range a {
+		in.Inject(context.Background(), "other")
+		if got := errors.Unwrap(in.Inject(context.Background(), "flaky")); got != a[i] {
+			t.Fatalf("call %d interleaved = %v; want %v", i+1, got, a[i])
+		}
+	}
+}
+
+func TestInjector_RatesRoughlyHold(t *testing.T) {
+	counts := map[error]int{}
+	for _, err := range outcomes(New(flaky(1)), "flaky", 10000) {
+		counts[err]++
+	}
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errTemporary, 5000},
+		{errPermanent, 2000},
+		{nil, 3000},
+	}
+	for _, tt := range tests {
+		if got := counts[tt.err]; got < tt.want-300 || got > tt.want+300 {
+			t.Fatalf("%v happened %d times in 10000; want about %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestInjector_FailsNthCall(t *testing.T) {
+	in := New(Config{Rules: map[string]Rule{
+		"write": {Faults: []Fault{{Calls: []int{2, 4}, Err: errTemporary}}},
+	}})
+
+	for call := 1; call <= 5; call++ {
+		err := in.Inject(context.Background(), "write")
+		wantFail := call == 2 || call == 4
+
+		var injected *InjectedError
+		if wantFail != errors.As(err, &injected) {
+			t.Fatalf("call %d = %v; want failure %v", call, err, wantFail)
+		}
+		if wantFail && (injected.Call != call || !errors.Is(err, errTemporary) || !errors.Is(err, ErrInjected)) {
+			t.Fatalf("call %d = %#v; want call %d wrapping %v", call, injected, call, errTemporary)
+		}
+	}
+	if n := in.Calls("write"); n != 5 {
+		t.Fatalf("Calls = %d; want 5", n)
+	}
+	if err := in.Inject(context.Background(), "unconfigured"); err != nil {
+		t.Fatalf("Inject without a rule = %v; want nil", err)
+	}
+}
+
+func TestInjector_Latency(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	in := New(Config{Clock: fake, Rules: map[string]Rule{"db": {Latency: Duration(50 * time.Millisecond)}}})
+
+	done := make(chan error, 1)
+	go func() { done <- in.Inject(context.Background(), "db") }()
+
+	waitForWaiters(t, fake)
+	select {
+	case err := <-done:
+		t.Fatalf("Inject returned %v before the latency elapsed", err)
+	default:
+	}
+	fake.Step(50 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("Inject = %v; want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- in.Inject(ctx, "db") }()
+	waitForWaiters(t, fake)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Inject with cancelled ctx = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestInjector_NilInjectsNothing(t *testing.T) {
+	var in *Injector
+	if err := in.Inject(context.Background(), "anything"); err != nil {
+		t.Fatalf("nil Inject = %v; want nil", err)
+	}
+}
+
+func TestInjector_RepeatableRetryRun(t *testing.T) {
+	run := func() (attempts int, err error) {
+		in := New(flaky(42))
+		err = retry.Retry(context.Background(), func(ctx context.Context) error {
+			attempts++
+			err := in.Inject(ctx, "flaky")
+			if errors.Is(err, errPermanent) {
+				return retry.Permanent(err)
+			}
+			return err
+		}, retry.Policy{MaxAttempts: 10, Backoff: retry.Constant(0)})
+		return attempts, err
+	}
+
+	a1, e1 := run()
+	a2, e2 := run()
+	if a1 != a2 || errors.Is(e1, errPermanent) != errors.Is(e2, errPermanent) {
+		t.Fatalf("runs differ: %d attempts (%v) vs %d attempts (%v)", a1, e1, a2, e2)
+	}
+}
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`{
+		"seed": 9,
+		"rules": {"flaky": {
+			"faults": [{"rate": 0.5, "error": "temporary failure"}, {"calls": [1], "error": "boom"}],
+			"latency": "20ms"
+		}}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse returned %v", err)
+	}
+	if r := cfg.Rules["flaky"]; cfg.Seed != 9 || r.Latency.Duration() != 20*time.Millisecond || len(r.Faults) != 2 {
+		t.Fatalf("Parse = %+v; want seed 9, 20ms latency, 2 faults", cfg)
+	}
+
+	// Named errors resolve to the registered values; unknown names
+	// become plain errors.
+	rule := cfg.Rules["flaky"]
+	rule.Latency = 0
+	in := New(Config{
+		Seed:   cfg.Seed,
+		Rules:  map[string]Rule{"flaky": rule},
+		Errors: map[string]error{"temporary failure": errTemporary},
+	})
+	if err := in.Inject(context.Background(), "flaky"); err == nil || errors.Unwrap(err).Error() != "boom" {
+		t.Fatalf("first call = %v; want boom", err)
+	}
+	var sawTemporary bool
+	for i := 0; i < 50 && !sawTemporary; i++ {
+		err := in.Inject(context.Background(), "flaky")
+		if err != nil && !errors.Is(err, errTemporary) {
+			t.Fatalf("rate fault = %v; want %v", err, errTemporary)
+		}
+		sawTemporary = err != nil
+	}
+	if !sawTemporary {
+		t.Fatal("a 50% fault never fired in 50 calls")
+	}
+
+	bad := []string{
+		`{"rules": {"x": {"faults": [{"rate": 0.7}, {"rate": 0.7}]}}}`,
+		`{"rules": {"x": {"faults": [{"calls": [0]}]}}}`,
+		`{"rules": {"x": {"latency": "soon"}}}`,
+		`{"rulez": {}}`,
+	}
+	for _, in := range bad {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Fatalf("Parse(%s) succeeded; want an error", in)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	if _, ok, err := FromEnv("FAULTINJECT_TEST_UNSET"); ok || err != nil {
+		t.Fatalf("FromEnv on unset var = %v, %v; want not ok", ok, err)
+	}
+
+	t.Setenv("FAULTINJECT_TEST", `{"seed": 3}`)
+	if cfg, ok, err := FromEnv("FAULTINJECT_TEST"); !ok || err != nil || cfg.Seed != 3 {
+		t.Fatalf("FromEnv inline = %+v, %v, %v; want seed 3", cfg, ok, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "faults.json")
+	os.WriteFile(path, []byte(`{"seed": 4}`), 0o644)
+	t.Setenv("FAULTINJECT_TEST", path)
+	if cfg, ok, err := FromEnv("FAULTINJECT_TEST"); !ok || err != nil || cfg.Seed != 4 {
+		t.Fatalf("FromEnv file = %+v, %v, %v; want seed 4", cfg, ok, err)
+	}
+}
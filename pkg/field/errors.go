@@ -0,0 +1,206 @@
+package field
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ==========================================================
+// 2. ERROR TYPES
+// ==========================================================
+
+// ErrorType is the kind of problem with a field.
+type ErrorType string
+
+const (
+	// ErrorTypeRequired: a required field is missing or empty.
+	ErrorTypeRequired ErrorType = "FieldValueRequired"
+	// ErrorTypeInvalid: the value is malformed or out of range.
+	ErrorTypeInvalid ErrorType = "FieldValueInvalid"
+	// ErrorTypeDuplicate: the value must be unique and is not.
+	ErrorTypeDuplicate ErrorType = "FieldValueDuplicate"
+	// ErrorTypeNotSupported: the value is not one of a known set.
+	ErrorTypeNotSupported ErrorType = "FieldValueNotSupported"
+	// ErrorTypeTooLong: the value is longer than allowed.
+	ErrorTypeTooLong ErrorType = "FieldValueTooLong"
+	// ErrorTypeForbidden: the field may not be set, at least not here.
+	ErrorTypeForbidden ErrorType = "FieldValueForbidden"
+)
+
+// String returns the human-readable form used in messages.
+func (t ErrorType) String() string {
+	switch t {
+	case ErrorTypeRequired:
+		return "Required value"
+	case ErrorTypeInvalid:
+		return "Invalid value"
+	case ErrorTypeDuplicate:
+		return "Duplicate value"
+	case ErrorTypeNotSupported:
+		return "Unsupported value"
+	case ErrorTypeTooLong:
+		return "Too long"
+	case ErrorTypeForbidden:
+		return "Forbidden"
+	default:
+		return fmt.Sprintf("unrecognized validation error %q", string(t))
+	}
+}
+
+// ==========================================================
+// 3. ERRORS
+// ==========================================================
+
+// Error is one problem with one field.
+type Error struct {
+	Type     ErrorType
+	Field    string
+	BadValue any
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	return e.Field + ": " + e.ErrorBody()
+}
+
+// ErrorBody is the message without the field path.
+func (e *Error) ErrorBody() string {
+	var s string
+	switch e.Type {
+	case ErrorTypeRequired, ErrorTypeForbidden, ErrorTypeTooLong:
+		// The value is either absent or not worth repeating.
+		s = e.Type.String()
+	default:
+		s = e.Type.String() + ": " + formatValue(e.BadValue)
+	}
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	return s
+}
+
+// formatValue quotes strings and dereferences pointers, so messages
+// show the value rather than an address.
+func formatValue(v any) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "null"
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return "null"
+	}
+	if rv.Kind() == reflect.String {
+		return strconv.Quote(rv.String())
+	}
+	return fmt.Sprintf("%v", rv.Interface())
+}
+
+// Required reports a missing value.
+func Required(path *Path, detail string) *Error {
+	return &Error{Type: ErrorTypeRequired, Field: path.String(), Detail: detail}
+}
+
+// Invalid reports a malformed or out-of-range value.
+func Invalid(path *Path, value any, detail string) *Error {
+	return &Error{Type: ErrorTypeInvalid, Field: path.String(), BadValue: value, Detail: detail}
+}
+
+// Duplicate reports a value that must be unique.
+func Duplicate(path *Path, value any) *Error {
+	return &Error{Type: ErrorTypeDuplicate, Field: path.String(), BadValue: value}
+}
+
+// NotSupported reports a value outside validValues.
+func NotSupported(path *Path, value any, validValues []string) *Error {
+	detail := ""
+	if len(validValues) > 0 {
+		quoted := make([]string, len(validValues))
+		for i, v := range validValues {
+			quoted[i] = strconv.Quote(v)
+		}
+		detail = "supported values: " + strings.Join(quoted, ", ")
+	}
+	return &Error{Type: ErrorTypeNotSupported, Field: path.String(), BadValue: value, Detail: detail}
+}
+
+// TooLong reports a value longer than maxLength. A negative maxLength
+// means the limit is not known.
+func TooLong(path *Path, value any, maxLength int) *Error {
+	detail := "value is too long"
+	if maxLength >= 0 {
+		detail = fmt.Sprintf("may not be longer than %d", maxLength)
+	}
+	return &Error{Type: ErrorTypeTooLong, Field: path.String(), BadValue: value, Detail: detail}
+}
+
+// Forbidden reports a field that may not be set.
+func Forbidden(path *Path, detail string) *Error {
+	return &Error{Type: ErrorTypeForbidden, Field: path.String(), Detail: detail}
+}
+
+// ==========================================================
+// 4. ERROR LISTS
+// ==========================================================
+
+// ErrorList collects every problem found in an object. Append to it
+// with append; validators for nested fields return their own lists,
+// which are appended with append(list, nested...).
+type ErrorList []*Error
+
+// Filter returns the errors for which keep returns true.
+func (list ErrorList) Filter(keep func(*Error) bool) ErrorList {
+	var out ErrorList
+	for _, e := range list {
+		if keep(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ToAggregate returns the list as a single error, or nil if it is
+// empty. Errors with identical messages are reported once. errors.Is
+// and errors.As look through every member; errors.As with a **Error
+// target finds the first.
+func (list ErrorList) ToAggregate() error {
+	if len(list) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(list))
+	errs := make([]error, 0, len(list))
+	for _, e := range list {
+		msg := e.Error()
+		if seen[msg] {
+			continue
+		}
+		seen[msg] = true
+		errs = append(errs, e)
+	}
+	return aggregate(errs)
+}
+
+// aggregate is the error ToAggregate returns. It shows one error as
+// itself and several as a list.
+type aggregate []error
+
+func (agg aggregate) Error() string {
+	if len(agg) == 1 {
+		return agg[0].Error()
+	}
+	msgs := make([]string, len(agg))
+	for i, err := range agg {
+		msgs[i] = err.Error()
+	}
+	return "[" + strings.Join(msgs, ", ") + "]"
+}
+
+// Errors returns the members.
+func (agg aggregate) Errors() []error { return []error(agg) }
+
+// Unwrap lets errors.Is and errors.As search every member.
+func (agg aggregate) Unwrap() []error { return []error(agg) }
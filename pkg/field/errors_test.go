@@ -0,0 +1,85 @@
+package field
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestError_Messages(t *testing.T) {
+	image := NewPath("spec", "containers").Index(2).Child("image")
+	replicas := -1
+
+	tests := []struct {
+		err  *Error
+		want string
+	}{
+		{Required(image, ""), `spec.containers[2].image: Required value`},
+		{Invalid(NewPath("spec", "replicas"), &replicas, "must be greater than or equal to 0"),
+			`spec.replicas: Invalid value: -1: must be greater than or equal to 0`},
+		{Invalid(NewPath("metadata", "name"), "Bad_Name", "must be lowercase"),
+			`metadata.name: Invalid value: "Bad_Name": must be lowercase`},
+		{Duplicate(NewPath("spec", "ports").Index(1), 8080), `spec.ports[1]: Duplicate value: 8080`},
+		{NotSupported(NewPath("spec", "restartPolicy"), "Sometimes", []string{"Always", "Never"}),
+			`spec.restartPolicy: Unsupported value: "Sometimes": supported values: "Always", "Never"`},
+		{TooLong(NewPath("metadata", "labels").Key("app"), "xxxx", 3), `metadata.labels[app]: Too long: may not be longer than 3`},
+		{Forbidden(NewPath("spec", "nodeName"), "may not be set on create"), `spec.nodeName: Forbidden: may not be set on create`},
+		{Invalid(NewPath("spec"), nil, ""), `spec: Invalid value: null`},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Fatalf("Error() = %q; want %q", got, tt.want)
+		}
+	}
+}
+
+type container struct {
+	Name  string
+	Image string
+}
+
+// validateContainers is what a real validator looks like: it walks the
+// object, extending the path, and reports every problem it finds.
+func validateContainers(containers []container, path *Path) ErrorList {
+	var errs ErrorList
+	names := map[string]bool{}
+	for i, c := range containers {
+		idx := path.Index(i)
+		if c.Image == "" {
+			errs = append(errs, Required(idx.Child("image"), ""))
+		}
+		if names[c.Name] {
+			errs = append(errs, Duplicate(idx.Child("name"), c.Name))
+		}
+		names[c.Name] = true
+	}
+	return errs
+}
+
+func TestErrorList_ToAggregate(t *testing.T) {
+	if err := (ErrorList{}).ToAggregate(); err != nil {
+		t.Fatalf("empty ToAggregate() = %v; want nil", err)
+	}
+
+	errs := validateContainers([]container{
+		{Name: "web", Image: "nginx"},
+		{Name: "web", Image: "nginx"},
+		{Name: "sidecar"},
+	}, NewPath("spec", "containers"))
+	errs = append(errs, Required(NewPath("spec", "containers").Index(2).Child("image"), "")) // reported twice
+
+	err := errs.ToAggregate()
+	want := `[spec.containers[1].name: Duplicate value: "web", spec.containers[2].image: Required value]`
+	if err == nil || err.Error() != want {
+		t.Fatalf("ToAggregate() = %v; want %s", err, want)
+	}
+
+	var fe *Error
+	if !errors.As(err, &fe) || fe.Type != ErrorTypeDuplicate || fe.BadValue != "web" {
+		t.Fatalf("errors.As found %+v; want the duplicate name error", fe)
+	}
+
+	required := errs.Filter(func(e *Error) bool { return e.Type == ErrorTypeRequired })
+	if len(required) != 2 {
+		t.Fatalf("Filter kept %d errors; want 2", len(required))
+	}
+}
@@ -0,0 +1,95 @@
+// Package field reports validation errors against a path into an
+// object, modeled on k8s.io/apimachinery/pkg/util/validation/field.
+//
+// ValidationError{Field, Rule} in 07-error-handling/04-sentinel-vs-typed-errors
+// names one flat field. Real objects nest, and a validator should
+// report every problem at once, each with where it is, what kind it is
+// and the offending value:
+//
+//	spec.containers[2].image: Required value
+//	spec.replicas: Invalid value: -1: must be greater than or equal to 0
+//	metadata.labels[app]: Too long: may not be longer than 63
+//
+// A validator builds a Path as it walks the object, appends an *Error
+// to an ErrorList for each problem, and hands the caller
+// list.ToAggregate(): one error that errors.As can still take apart.
+package field
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ==========================================================
+// 1. PATHS
+// ==========================================================
+
+// Path is a location in an object, such as spec.containers[2].image.
+// Paths are immutable; Child, Index and Key return new ones.
+type Path struct {
+	name   string // a field name, or empty for an index or key
+	index  string // a list index or map key, if name is empty
+	parent *Path
+}
+
+// NewPath returns the path name.more[0].more[1]...
+func NewPath(name string, more ...string) *Path {
+	p := &Path{name: name}
+	for _, n := range more {
+		p = &Path{name: n, parent: p}
+	}
+	return p
+}
+
+// Root returns the first element of p.
+func (p *Path) Root() *Path {
+	for p.parent != nil {
+		p = p.parent
+	}
+	return p
+}
+
+// Child returns p.name.more[0]...
+func (p *Path) Child(name string, more ...string) *Path {
+	r := NewPath(name, more...)
+	r.Root().parent = p
+	return r
+}
+
+// Index returns p[i], for an element of a list.
+func (p *Path) Index(i int) *Path {
+	return &Path{index: strconv.Itoa(i), parent: p}
+}
+
+// Key returns p[key], for an entry of a map.
+func (p *Path) Key(key string) *Path {
+	return &Path{index: key, parent: p}
+}
+
+// String renders p as a dotted path with bracketed indexes.
+func (p *Path) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+
+	var elems []*Path
+	for ; p != nil; p = p.parent {
+		elems = append(elems, p)
+	}
+
+	var b strings.Builder
+	for i := len(elems) - 1; i >= 0; i-- {
+		e := elems[i]
+		if e.parent != nil && e.name != "" {
+			b.WriteByte('.')
+		}
+		if e.name != "" {
+			b.WriteString(e.name)
+		} else {
+			b.WriteByte('[')
+			b.WriteString(e.index)
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
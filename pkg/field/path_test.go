@@ -0,0 +1,33 @@
+package field
+
+import "testing"
+
+func TestPath_String(t *testing.T) {
+	spec := NewPath("spec")
+
+	tests := []struct {
+		path *Path
+		want string
+	}{
+		{NewPath("metadata"), "metadata"},
+		{NewPath("spec", "template", "spec"), "spec.template.spec"},
+		{spec.Child("containers").Index(2).Child("image"), "spec.containers[2].image"},
+		{NewPath("metadata").Child("labels").Key("app"), "metadata.labels[app]"},
+		{spec.Child("matrix").Index(0).Index(1), "spec.matrix[0][1]"},
+		{spec.Child("a", "b"), "spec.a.b"},
+		{nil, "<nil>"},
+	}
+	for _, tt := range tests {
+		if got := tt.path.String(); got != tt.want {
+			t.Fatalf("String() = %q; want %q", got, tt.want)
+		}
+	}
+
+	// Deriving a child must not change the parent.
+	if got := spec.String(); got != "spec" {
+		t.Fatalf("parent String() = %q after deriving children; want spec", got)
+	}
+	if got := spec.Child("a", "b").Root(); got != spec.Root() {
+		t.Fatalf("Root() = %v; want spec", got)
+	}
+}
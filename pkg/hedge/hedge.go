@@ -0,0 +1,271 @@
+// Package hedge cuts tail latency by sending a backup request when the
+// first one is slow.
+//
+// If one replica in a hundred is slow, one call in a hundred waits on
+// it. A hedged call instead waits only "a bit longer than usual" (a
+// fixed delay, or the observed p95) and then sends a second copy to
+// another replica. Whichever answers first wins and the others are
+// cancelled through their context.
+//
+// Sending a request twice is only safe if doing it twice has the same
+// effect as doing it once: the idempotency rule from
+// 07-error-handling/05-retry-backoff-patterns. Hedge therefore refuses
+// to run unless Options.Idempotent is set.
+package hedge
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. ERRORS
+// ==========================================================
+
+// ErrNotIdempotent is returned when Hedge is asked to run an operation
+// that is not marked idempotent.
+var ErrNotIdempotent = errors.New("hedge: refusing to hedge a non-idempotent operation")
+
+// ==========================================================
+// 2. OBSERVED LATENCY
+// ==========================================================
+
+// LatencyTracker keeps the most recent successful latencies so the
+// hedge delay can follow a percentile instead of a guess. It is safe
+// for concurrent use; share one per operation.
+type LatencyTracker struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// NewLatencyTracker keeps the last size samples. Defaults to 1000.
+func NewLatencyTracker(size int) *LatencyTracker {
+	if size <= 0 {
+		size = 1000
+	}
+	return &LatencyTracker{samples: make([]time.Duration, size)}
+}
+
+// Observe records one latency.
+func (t *LatencyTracker) Observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % len(t.samples)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// Len returns how many samples are held.
+func (t *LatencyTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.full {
+		return len(t.samples)
+	}
+	return t.next
+}
+
+// Percentile returns the p-th percentile (0 < p <= 1) of the held
+// samples, or false when there are none.
+func (t *LatencyTracker) Percentile(p float64) (time.Duration, bool) {
+	t.mu.Lock()
+	n := t.next
+	if t.full {
+		n = len(t.samples)
+	}
+	sorted := slices.Clone(t.samples[:n])
+	t.mu.Unlock()
+
+	if n == 0 {
+		return 0, false
+	}
+	slices.Sort(sorted)
+
+	// Nearest-rank: the smallest sample with at least p of the samples
+	// at or below it.
+	rank := int(p*float64(n)+0.999999) - 1
+	rank = max(0, min(rank, n-1))
+	return sorted[rank], true
+}
+
+// ==========================================================
+// 3. CONCURRENCY CAP
+// ==========================================================
+
+// Limit caps how many backup attempts run at once across every Hedge
+// call sharing it. When the backend is slow for everyone, unlimited
+// hedging would double its load; with a Limit, extra hedges are simply
+// not sent.
+type Limit struct {
+	slots chan struct{}
+}
+
+// NewLimit allows at most n concurrent backup attempts.
+func NewLimit(n int) *Limit {
+	return &Limit{slots: make(chan struct{}, max(n, 1))}
+}
+
+func (l *Limit) tryAcquire() bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *Limit) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// InFlight returns how many backup attempts hold a slot.
+func (l *Limit) InFlight() int {
+	return len(l.slots)
+}
+
+// ==========================================================
+// 4. HEDGE
+// ==========================================================
+
+// Options configures one Hedge call.
+type Options struct {
+	// Idempotent must be true: the operation may run more than once.
+	Idempotent bool
+
+	// Delay is how long to wait for an attempt before sending the next.
+	// It is also the fallback when Latency has no samples yet.
+	// Defaults to 100ms.
+	Delay time.Duration
+
+	// Latency and Percentile, when both set, replace Delay with the
+	// observed percentile (e.g. 0.95). Successful latencies are fed
+	// back into Latency.
+	Latency    *LatencyTracker
+	Percentile float64
+
+	// MaxAttempts is the total number of attempts, including the first.
+	// Defaults to 2.
+	MaxAttempts int
+
+	// Limit, if set, caps backup attempts across calls sharing it.
+	Limit *Limit
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (o Options) withDefaults() Options {
+	if o.Delay <= 0 {
+		o.Delay = 100 * time.Millisecond
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 2
+	}
+	if o.Clock == nil {
+		o.Clock = clock.RealClock{}
+	}
+	return o
+}
+
+func (o Options) hedgeDelay() time.Duration {
+	if o.Latency != nil && o.Percentile > 0 {
+		if d, ok := o.Latency.Percentile(o.Percentile); ok {
+			return d
+		}
+	}
+	return o.Delay
+}
+
+type outcome[T any] struct {
+	val T
+	err error
+}
+
+// Hedge runs op, starting another attempt whenever the attempts so far
+// have been running for the hedge delay, or have all failed. The first
+// success is returned and the remaining attempts are cancelled. If
+// every attempt fails, the last error is returned.
+func Hedge[T any](ctx context.Context, op func(ctx context.Context) (T, error), opts Options) (T, error) {
+	var zero T
+	if !opts.Idempotent {
+		return zero, ErrNotIdempotent
+	}
+	opts = opts.withDefaults()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // cancels the losers
+
+	// Buffered so that losers finishing after we return never block.
+	results := make(chan outcome[T], opts.MaxAttempts)
+	launch := func(backup bool) {
+		start := opts.Clock.Now()
+		go func() {
+			if backup {
+				defer opts.Limit.release()
+			}
+			v, err := op(ctx)
+			if err == nil && opts.Latency != nil {
+				opts.Latency.Observe(opts.Clock.Since(start))
+			}
+			results <- outcome[T]{val: v, err: err}
+		}()
+	}
+
+	launch(false)
+	launched, running := 1, 1
+
+	delay := opts.hedgeDelay()
+	timer := opts.Clock.NewTimer(delay)
+	defer timer.Stop()
+
+	// tryBackup starts one more attempt if allowed.
+	tryBackup := func() bool {
+		if launched >= opts.MaxAttempts || !opts.Limit.tryAcquire() {
+			return false
+		}
+		launch(true)
+		launched++
+		running++
+		return true
+	}
+
+	var lastErr error
+	for {
+		select {
+		case res := <-results:
+			running--
+			if res.err == nil {
+				return res.val, nil
+			}
+			lastErr = res.err
+			// A failure is no reason to keep waiting: hedge right away.
+			if !tryBackup() && running == 0 {
+				return zero, lastErr
+			}
+
+		case <-timer.C():
+			tryBackup()
+			if launched < opts.MaxAttempts {
+				timer.Reset(delay)
+			}
+
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
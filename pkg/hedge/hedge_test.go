@@ -0,0 +1,172 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func waitForWaiters(t *testing.T, c *clock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a clock waiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// replicas returns an op whose n-th call (0-based) behaves as
+// behaviours[n]. A nil behaviour blocks until its context is cancelled
+// and records that it was.
+type replicas struct {
+	calls     atomic.Int32
+	cancelled atomic.Int32
+	behaviour []func() (string, error)
+}
+
+func (r *replicas) op(ctx context.Context) (string, error) {
+	n := int(r.calls.Add(1)) - 1
+	if n < len(r.behaviour) && r.behaviour[n] != nil {
+		return r.behaviour[n]()
+	}
+	<-ctx.Done()
+	r.cancelled.Add(1)
+	return "", ctx.Err()
+}
+
+func answer(s string) func() (string, error) {
+	return func() (string, error) { return s, nil }
+}
+
+func TestHedge_RefusesNonIdempotent(t *testing.T) {
+	r := &replicas{}
+	if _, err := Hedge(context.Background(), r.op, Options{}); !errors.Is(err, ErrNotIdempotent) {
+		t.Fatalf("Hedge = %v; want %v", err, ErrNotIdempotent)
+	}
+	if r.calls.Load() != 0 {
+		t.Fatal("operation ran despite not being idempotent")
+	}
+}
+
+func TestHedge_FastPrimaryNeedsNoBackup(t *testing.T) {
+	r := &replicas{behaviour: []func() (string, error){answer("primary")}}
+	got, err := Hedge(context.Background(), r.op, Options{Idempotent: true, Delay: time.Hour})
+	if err != nil || got != "primary" {
+		t.Fatalf("Hedge = %q, %v; want primary", got, err)
+	}
+	if n := r.calls.Load(); n != 1 {
+		t.Fatalf("op called %d times; want 1", n)
+	}
+}
+
+func TestHedge_SlowPrimaryIsHedgedAndCancelled(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	r := &replicas{behaviour: []func() (string, error){nil, answer("backup")}}
+
+	done := make(chan string, 1)
+	go func() {
+		got, _ := Hedge(context.Background(), r.op, Options{Idempotent: true, Delay: 50 * time.Millisecond, Clock: fake})
+		done <- got
+	}()
+
+	waitForWaiters(t, fake)
+	fake.Step(50 * time.Millisecond)
+
+	if got := <-done; got != "backup" {
+		t.Fatalf("Hedge = %q; want backup", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for r.cancelled.Load() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("slow primary was never cancelled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHedge_FailureHedgesImmediately(t *testing.T) {
+	errDown := errors.New("replica down")
+	fail := func() (string, error) { return "", errDown }
+
+	r := &replicas{behaviour: []func() (string, error){fail, answer("second")}}
+	got, err := Hedge(context.Background(), r.op, Options{Idempotent: true, Delay: time.Hour})
+	if err != nil || got != "second" {
+		t.Fatalf("Hedge = %q, %v; want second", got, err)
+	}
+
+	r = &replicas{behaviour: []func() (string, error){fail, fail, fail}}
+	_, err = Hedge(context.Background(), r.op, Options{Idempotent: true, Delay: time.Hour, MaxAttempts: 3})
+	if !errors.Is(err, errDown) {
+		t.Fatalf("Hedge with every attempt failing = %v; want %v", err, errDown)
+	}
+	if n := r.calls.Load(); n != 3 {
+		t.Fatalf("op called %d times; want 3", n)
+	}
+}
+
+func TestHedge_LimitCapsBackups(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	limit := NewLimit(1)
+	limit.tryAcquire() // another call's hedge is already running
+
+	r := &replicas{behaviour: []func() (string, error){nil}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := Hedge(ctx, r.op, Options{Idempotent: true, Delay: time.Millisecond, Limit: limit, Clock: fake})
+		done <- err
+	}()
+
+	waitForWaiters(t, fake)
+	fake.Step(time.Millisecond)
+	waitForWaiters(t, fake) // the timer re-armed instead of hedging
+
+	if n := r.calls.Load(); n != 1 {
+		t.Fatalf("op called %d times with the limit full; want 1", n)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Hedge = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestLatencyTracker_Percentile(t *testing.T) {
+	tr := NewLatencyTracker(100)
+	if _, ok := tr.Percentile(0.95); ok {
+		t.Fatal("Percentile with no samples reported ok")
+	}
+
+	for i := 100; i >= 1; i-- {
+		tr.Observe(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0.5, 50 * time.Millisecond},
+		{0.95, 95 * time.Millisecond},
+		{1, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got, _ := tr.Percentile(tt.p); got != tt.want {
+			t.Fatalf("Percentile(%v) = %v; want %v", tt.p, got, tt.want)
+		}
+	}
+
+	// The ring keeps only the newest samples.
+	for i := 0; i < 100; i++ {
+		tr.Observe(time.Second)
+	}
+	if got, _ := tr.Percentile(0.5); got != time.Second {
+		t.Fatalf("Percentile after overwrite = %v; want 1s", got)
+	}
+}
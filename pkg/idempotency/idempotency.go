@@ -0,0 +1,242 @@
+// Package idempotency makes retried non-idempotent operations run once.
+//
+// The retry lesson (07-error-handling/05-retry-backoff-patterns) says
+// "charge credit card" and "send email" must not be retried. But the
+// caller often cannot tell whether a timed-out request ran. The usual
+// fix is an IDEMPOTENCY KEY: the client picks a key per logical
+// operation and sends it with every retry, and the server
+//
+//  1. runs the operation the first time it sees the key
+//  2. records the result (or error) under the key, with a TTL
+//  3. answers every later request with that key from the record
+//
+// Requests that arrive while the first one is still running wait for it
+// and share its result, like golang.org/x/sync/singleflight.
+//
+// Records live in a Store. MemoryStore is for tests and single
+// processes; FileStore survives restarts.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. RECORDS & STORES
+// ==========================================================
+
+// Record is the stored outcome of one operation.
+type Record struct {
+	Key string `json:"key"`
+
+	// Value is the JSON-encoded result. Empty when Err is set.
+	Value json.RawMessage `json:"value,omitempty"`
+
+	// Err is the error message of a failed operation.
+	Err string `json:"err,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Store persists records. Get must not return expired records. A
+// Keeper calls it concurrently for different keys, never for the same
+// key.
+type Store interface {
+	Get(key string, now time.Time) (Record, bool, error)
+	Put(rec Record) error
+}
+
+// ErrEmptyKey is returned by Do for an empty key.
+var ErrEmptyKey = errors.New("idempotency: key must not be empty")
+
+// ErrPanicked is returned to duplicates waiting on an operation that
+// panicked. The panic itself propagates in the caller that ran it.
+var ErrPanicked = errors.New("idempotency: operation panicked")
+
+// RecordedError is returned when a stored failure is replayed. Only
+// the message survives storage, not the original error value.
+type RecordedError struct {
+	Key     string
+	Message string
+}
+
+func (e *RecordedError) Error() string {
+	return fmt.Sprintf("idempotency: replayed error for key %q: %s", e.Key, e.Message)
+}
+
+// ==========================================================
+// 2. KEEPER
+// ==========================================================
+
+// Config configures a Keeper.
+type Config struct {
+	// TTL is how long a record answers duplicates. Defaults to 24h.
+	TTL time.Duration
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c Config) withDefaults() Config {
+	if c.TTL <= 0 {
+		c.TTL = 24 * time.Hour
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+// call is one in-flight lookup or execution for a key that duplicates
+// wait on. val and err are its outcome, set before done is
+// closed, whether or not it was recorded. retry is set instead when
+// there is no outcome to share: the store lookup failed, or the run was
+// cut short by its caller's context. Waiters then start over.
+type call[T any] struct {
+	done  chan struct{}
+	val   T
+	err   error
+	retry bool
+}
+
+// Keeper runs operations at most once per key. It is safe for
+// concurrent use.
+type Keeper[T any] struct {
+	store Store
+	cfg   Config
+
+	// mu guards only inflight. Store I/O happens outside it, under the
+	// key's in-flight entry, so slow storage for one key does not hold
+	// up the others.
+	mu       sync.Mutex
+	inflight map[string]*call[T]
+}
+
+// New returns a Keeper recording results in store.
+func New[T any](store Store, cfg Config) *Keeper[T] {
+	return &Keeper[T]{
+		store:    store,
+		cfg:      cfg.withDefaults(),
+		inflight: make(map[string]*call[T]),
+	}
+}
+
+// Do runs fn once per key within the TTL. shared is true when the
+// result came from an earlier or concurrent execution rather than this
+// call running fn.
+//
+// Errors are recorded like results, so a duplicate of a failed request
+// fails the same way. The exception is the caller's own context ending:
+// that says nothing about the operation, so it is not recorded, and
+// duplicates waiting on that run try again instead of sharing it.
+//
+// Otherwise duplicates waiting on a running operation get its outcome,
+// recorded or not: once fn has started it is never run again
+// concurrently. If it panicked they get ErrPanicked; if its result
+// could not be stored they get the store error joined to it.
+func (k *Keeper[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (v T, shared bool, err error) {
+	if key == "" {
+		return v, false, ErrEmptyKey
+	}
+
+	for {
+		k.mu.Lock()
+		c, ok := k.inflight[key]
+		if !ok {
+			c = &call[T]{done: make(chan struct{})}
+			k.inflight[key] = c
+			k.mu.Unlock()
+			return k.execute(ctx, key, c, fn)
+		}
+		k.mu.Unlock()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return v, false, ctx.Err()
+		}
+		if !c.retry {
+			return c.val, true, c.err
+		}
+	}
+}
+
+// execute looks key up and, if it has no record, runs fn and records
+// the outcome. c holds the key meanwhile.
+func (k *Keeper[T]) execute(ctx context.Context, key string, c *call[T], fn func(ctx context.Context) (T, error)) (T, bool, error) {
+	returned := false
+	defer func() {
+		if !returned {
+			c.err = fmt.Errorf("%w: key %q", ErrPanicked, key)
+		}
+		k.mu.Lock()
+		delete(k.inflight, key)
+		k.mu.Unlock()
+		close(c.done)
+	}()
+
+	rec, found, err := k.store.Get(key, k.cfg.Clock.Now())
+	if err != nil {
+		returned, c.retry = true, true
+		return c.val, false, fmt.Errorf("idempotency: get %q: %w", key, err)
+	}
+	if found {
+		c.val, c.err = replay[T](rec)
+		returned = true
+		return c.val, true, c.err
+	}
+
+	c.val, c.err = fn(ctx)
+	returned = true
+	if c.err != nil && ctx.Err() != nil {
+		c.retry = true
+		return c.val, false, c.err
+	}
+
+	if err := k.record(key, c.val, c.err); err != nil {
+		// The operation ran but we could not remember it. Say so,
+		// to the waiters too, rather than risk running it again
+		// silently.
+		c.err = errors.Join(c.err, err)
+	}
+	return c.val, false, c.err
+}
+
+func (k *Keeper[T]) record(key string, v T, opErr error) error {
+	now := k.cfg.Clock.Now()
+	rec := Record{Key: key, CreatedAt: now, ExpiresAt: now.Add(k.cfg.TTL)}
+
+	if opErr != nil {
+		rec.Err = opErr.Error()
+	} else {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("idempotency: marshal result for %q: %w", key, err)
+		}
+		rec.Value = raw
+	}
+
+	if err := k.store.Put(rec); err != nil {
+		return fmt.Errorf("idempotency: put %q: %w", key, err)
+	}
+	return nil
+}
+
+func replay[T any](rec Record) (T, error) {
+	var v T
+	if rec.Err != "" {
+		return v, &RecordedError{Key: rec.Key, Message: rec.Err}
+	}
+	if err := json.Unmarshal(rec.Value, &v); err != nil {
+		return v, fmt.Errorf("idempotency: decode record %q: %w", rec.Key, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,308 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type charge struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+// stores runs a test against every Store implementation.
+func stores(t *testing.T) map[string]Store {
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore returned %v", err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "file": fs}
+}
+
+func TestKeeper_RunsOnceAndReplays(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			fake := clock.NewFakeClock(epoch)
+			k := New[charge](store, Config{TTL: time.Hour, Clock: fake})
+
+			var runs int
+			chargeCard := func(context.Context) (charge, error) {
+				runs++
+				return charge{ID: "ch_1", Amount: 500}, nil
+			}
+
+			first, shared, err := k.Do(context.Background(), "order-42", chargeCard)
+			if err != nil || shared {
+				t.Fatalf("first Do = %v, shared=%v; want a fresh run", err, shared)
+			}
+			second, shared, err := k.Do(context.Background(), "order-42", chargeCard)
+			if err != nil || !shared || second != first {
+				t.Fatalf("retry Do = %+v, shared=%v, %v; want replay of %+v", second, shared, err, first)
+			}
+			if runs != 1 {
+				t.Fatalf("card charged %d times; want 1", runs)
+			}
+
+			// After the TTL the key is forgotten.
+			fake.Step(time.Hour)
+			if _, shared, _ := k.Do(context.Background(), "order-42", chargeCard); shared || runs != 2 {
+				t.Fatalf("Do after TTL: shared=%v runs=%d; want a fresh run", shared, runs)
+			}
+		})
+	}
+}
+
+func TestKeeper_ReplaysErrors(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			k := New[charge](store, Config{Clock: clock.NewFakeClock(epoch)})
+			declined := errors.New("card declined")
+			fn := func(context.Context) (charge, error) { return charge{}, declined }
+
+			if _, _, err := k.Do(context.Background(), "order-7", fn); !errors.Is(err, declined) {
+				t.Fatalf("first Do = %v; want %v", err, declined)
+			}
+
+			_, shared, err := k.Do(context.Background(), "order-7", fn)
+			var rec *RecordedError
+			if !shared || !errors.As(err, &rec) || rec.Message != "card declined" {
+				t.Fatalf("retry Do = %v, shared=%v; want *RecordedError with the original message", err, shared)
+			}
+		})
+	}
+}
+
+func TestKeeper_ConcurrentDuplicatesShareOneRun(t *testing.T) {
+	k := New[int](NewMemoryStore(), Config{})
+	release := make(chan struct{})
+	var runs atomic.Int32
+
+	fn := func(context.Context) (int, error) {
+		runs.Add(1)
+		<-release
+		return 7, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make(chan int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, _, err := k.Do(context.Background(), "send-email-1", fn)
+			if err != nil {
+				t.Errorf("Do returned %v", err)
+			}
+			results <- v
+		}()
+	}
+
+	// Let every caller reach Do before the first run finishes.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for v := range results {
+		if v != 7 {
+			t.Fatalf("caller got %d; want 7", v)
+		}
+	}
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("operation ran %d times; want 1", n)
+	}
+}
+
+func TestKeeper_CancelledRunIsNotRecorded(t *testing.T) {
+	k := New[int](NewMemoryStore(), Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := k.Do(ctx, "key", func(ctx context.Context) (int, error) { return 0, ctx.Err() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do = %v; want %v", err, context.Canceled)
+	}
+
+	v, shared, err := k.Do(context.Background(), "key", func(context.Context) (int, error) { return 1, nil })
+	if err != nil || shared || v != 1 {
+		t.Fatalf("Do after cancelled run = %d, shared=%v, %v; want a fresh run", v, shared, err)
+	}
+}
+
+func TestFileStore_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	fs, _ := NewFileStore(dir)
+	k := New[string](fs, Config{Clock: clock.NewFakeClock(epoch)})
+	k.Do(context.Background(), "a/b:c", func(context.Context) (string, error) { return "done", nil })
+
+	reopened, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore returned %v", err)
+	}
+	k = New[string](reopened, Config{Clock: clock.NewFakeClock(epoch)})
+	v, shared, err := k.Do(context.Background(), "a/b:c", func(context.Context) (string, error) {
+		t.Fatal("operation ran again after reopening the store")
+		return "", nil
+	})
+	if err != nil || !shared || v != "done" {
+		t.Fatalf("Do after reopen = %q, shared=%v, %v; want replay", v, shared, err)
+	}
+
+	if n, err := reopened.Sweep(epoch.Add(48 * time.Hour)); err != nil || n != 1 {
+		t.Fatalf("Sweep = %d, %v; want 1 expired record", n, err)
+	}
+}
+
+// failingStore finds nothing and fails every Put.
+type failingStore struct{ *MemoryStore }
+
+var errDiskFull = errors.New("disk full")
+
+func (s *failingStore) Put(Record) error { return errDiskFull }
+
+func TestKeeper_UnrecordedRunIsSharedNotRepeated(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   Store
+		fn      func(ctx context.Context) (int, error)
+		wantErr error
+	}{
+		{"put fails", &failingStore{NewMemoryStore()},
+			func(context.Context) (int, error) { return 7, nil }, errDiskFull},
+		{"panic", NewMemoryStore(),
+			func(context.Context) (int, error) { panic("boom") }, ErrPanicked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := New[int](tt.store, Config{})
+			release := make(chan struct{})
+			var runs atomic.Int32
+			fn := func(ctx context.Context) (int, error) {
+				runs.Add(1)
+				<-release
+				return tt.fn(ctx)
+			}
+
+			const callers = 10
+			var wg sync.WaitGroup
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() { recover() }() // the runner re-panics
+					if _, _, err := k.Do(context.Background(), "charge-1", fn); !errors.Is(err, tt.wantErr) {
+						t.Errorf("Do = %v; want %v", err, tt.wantErr)
+					}
+				}()
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if n := runs.Load(); n != 1 {
+				t.Fatalf("operation ran %d times; want 1", n)
+			}
+		})
+	}
+}
+
+// blockingStore holds Get for one key until released.
+type blockingStore struct {
+	*MemoryStore
+	key     string
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Get(key string, now time.Time) (Record, bool, error) {
+	if key == s.key {
+		close(s.entered)
+		<-s.release
+	}
+	return s.MemoryStore.Get(key, now)
+}
+
+func TestKeeper_SlowStoreBlocksOnlyItsKey(t *testing.T) {
+	store := &blockingStore{MemoryStore: NewMemoryStore(), key: "slow", entered: make(chan struct{}), release: make(chan struct{})}
+	k := New[int](store, Config{})
+
+	slow := make(chan error, 1)
+	go func() {
+		_, _, err := k.Do(context.Background(), "slow", func(context.Context) (int, error) { return 1, nil })
+		slow <- err
+	}()
+	<-store.entered
+
+	fast := make(chan error, 1)
+	go func() {
+		_, _, err := k.Do(context.Background(), "fast", func(context.Context) (int, error) { return 2, nil })
+		fast <- err
+	}()
+	select {
+	case err := <-fast:
+		if err != nil {
+			t.Fatalf("Do(fast) = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do on another key waited for the slow store lookup")
+	}
+
+	close(store.release)
+	if err := <-slow; err != nil {
+		t.Fatalf("Do(slow) = %v", err)
+	}
+}
+
+func TestKeeper_DuplicatesRetryAfterCancelledRun(t *testing.T) {
+	k := New[int](NewMemoryStore(), Config{})
+	started := make(chan struct{})
+	var runs atomic.Int32
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := k.Do(ctx, "key", func(ctx context.Context) (int, error) {
+			runs.Add(1)
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		first <- err
+	}()
+	<-started
+
+	dup := make(chan int, 1)
+	go func() {
+		v, shared, err := k.Do(context.Background(), "key", func(context.Context) (int, error) {
+			runs.Add(1)
+			return 7, nil
+		})
+		if err != nil || shared {
+			t.Errorf("duplicate Do = %d, shared=%v, %v; want its own run", v, shared, err)
+		}
+		dup <- v
+	}()
+
+	// Let the duplicate start waiting on the first run.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Do = %v; want %v", err, context.Canceled)
+	}
+	if v := <-dup; v != 7 {
+		t.Fatalf("duplicate got %d; want 7", v)
+	}
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("operation ran %d times; want 2", n)
+	}
+}
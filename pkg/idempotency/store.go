@@ -0,0 +1,167 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ==========================================================
+// 3. MEMORY STORE
+// ==========================================================
+
+// MemoryStore keeps records in a map. Expired records are dropped when
+// read, or in bulk by Sweep.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record)}
+}
+
+func (s *MemoryStore) Get(key string, now time.Time) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok {
+		return Record{}, false, nil
+	}
+	if !now.Before(rec.ExpiresAt) {
+		delete(s.records, key)
+		return Record{}, false, nil
+	}
+	return rec, true, nil
+}
+
+func (s *MemoryStore) Put(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.Key] = rec
+	return nil
+}
+
+// Sweep removes every record expired at now and returns how many.
+func (s *MemoryStore) Sweep(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n
+}
+
+// ==========================================================
+// 4. FILE STORE
+// ==========================================================
+
+// FileStore keeps one JSON file per key in a directory, so records
+// survive a restart. File names are hashes of the key, which keeps
+// arbitrary keys (slashes, unicode) safe on disk.
+//
+// Writes go to a temp file that is then renamed over the target, so a
+// crash leaves either the old record or the new one, never half of one.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore uses dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("idempotency: create store dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *FileStore) Get(key string, now time.Time) (Record, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, err
+	}
+
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Record{}, false, fmt.Errorf("decode %s: %w", s.path(key), err)
+	}
+	if !now.Before(rec.ExpiresAt) {
+		os.Remove(s.path(key))
+		return Record{}, false, nil
+	}
+	return rec, true, nil
+}
+
+func (s *FileStore) Put(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".record-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(rec.Key))
+}
+
+// Sweep removes every record expired at now and returns how many.
+func (s *FileStore) Sweep(now time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		p := filepath.Join(s.dir, e.Name())
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var rec Record
+		if json.Unmarshal(data, &rec) == nil && !now.Before(rec.ExpiresAt) {
+			if os.Remove(p) == nil {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
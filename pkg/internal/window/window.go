@@ -0,0 +1,55 @@
+// Package window counts events over a sliding time window. It is the
+// one implementation behind pkg/breaker's failure ratio and
+// pkg/throttle's request and retry counts.
+package window
+
+import "time"
+
+// Counter sums values added over the last span, in fixed-width buckets
+// that are zeroed lazily as time moves past them. It is not safe for
+// concurrent use; callers hold their own lock.
+type Counter struct {
+	width   time.Duration
+	starts  []time.Time
+	buckets []float64
+}
+
+// New returns a Counter over span with n buckets. n is raised to 1
+// and the bucket width to 1ns if they are smaller, so a tiny or zero
+// span still makes a usable, if very short, window.
+func New(span time.Duration, n int) *Counter {
+	n = max(n, 1)
+	return &Counter{
+		width:   max(span/time.Duration(n), 1),
+		starts:  make([]time.Time, n),
+		buckets: make([]float64, n),
+	}
+}
+
+// Add adds v at now.
+func (c *Counter) Add(now time.Time, v float64) {
+	start := now.Truncate(c.width)
+	i := int(start.UnixNano()/int64(c.width)) % len(c.buckets)
+	if !c.starts[i].Equal(start) {
+		c.starts[i], c.buckets[i] = start, 0
+	}
+	c.buckets[i] += v
+}
+
+// Sum returns the total added within the window ending at now.
+func (c *Counter) Sum(now time.Time) float64 {
+	oldest := now.Truncate(c.width).Add(-c.width * time.Duration(len(c.buckets)-1))
+	total := 0.0
+	for i, start := range c.starts {
+		if !start.Before(oldest) {
+			total += c.buckets[i]
+		}
+	}
+	return total
+}
+
+// Reset forgets everything.
+func (c *Counter) Reset() {
+	clear(c.starts)
+	clear(c.buckets)
+}
@@ -0,0 +1,62 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestCounter_Slides(t *testing.T) {
+	c := New(10*time.Second, 10)
+
+	c.Add(epoch, 1)
+	c.Add(epoch.Add(500*time.Millisecond), 2)
+	c.Add(epoch.Add(5*time.Second), 4)
+
+	tests := []struct {
+		at   time.Duration
+		want float64
+	}{
+		{5 * time.Second, 7},
+		{9 * time.Second, 7},
+		{10 * time.Second, 4}, // the first bucket slid out
+		{14 * time.Second, 4},
+		{15 * time.Second, 0},
+	}
+	for _, tt := range tests {
+		if got := c.Sum(epoch.Add(tt.at)); got != tt.want {
+			t.Fatalf("Sum at +%v = %v; want %v", tt.at, got, tt.want)
+		}
+	}
+
+	// A bucket reused a full window later starts from zero.
+	c.Add(epoch.Add(20*time.Second), 1)
+	if got := c.Sum(epoch.Add(20 * time.Second)); got != 1 {
+		t.Fatalf("Sum after reuse = %v; want 1", got)
+	}
+
+	c.Reset()
+	if got := c.Sum(epoch.Add(20 * time.Second)); got != 0 {
+		t.Fatalf("Sum after Reset = %v; want 0", got)
+	}
+}
+
+func TestNew_ClampsDegenerateWindows(t *testing.T) {
+	tests := []struct {
+		name string
+		span time.Duration
+		n    int
+	}{
+		{"span shorter than n nanoseconds", 5 * time.Nanosecond, 10},
+		{"zero span", 0, 10},
+		{"zero buckets", time.Second, 0},
+	}
+	for _, tt := range tests {
+		c := New(tt.span, tt.n)
+		c.Add(epoch, 1)
+		if got := c.Sum(epoch); got != 1 {
+			t.Fatalf("%s: Sum = %v; want 1", tt.name, got)
+		}
+	}
+}
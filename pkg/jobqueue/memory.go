@@ -0,0 +1,94 @@
+package jobqueue
+
+import (
+	"context"
+	"sync"
+)
+
+// ==========================================================
+// IN-MEMORY QUEUE
+// ==========================================================
+
+// Memory is a Queue backed by a buffered channel. Nothing survives a
+// restart, and Ack only checks that the ID was handed out.
+type Memory[T any] struct {
+	ch chan Delivery[T]
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]struct{}
+	closed  bool
+
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+var _ Queue[int] = (*Memory[int])(nil)
+
+// NewMemory returns a Memory queue holding up to capacity jobs.
+func NewMemory[T any](capacity int) *Memory[T] {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &Memory[T]{
+		ch:      make(chan Delivery[T], capacity),
+		nextID:  1,
+		pending: make(map[uint64]struct{}),
+		closing: make(chan struct{}),
+	}
+}
+
+func (m *Memory[T]) Submit(ctx context.Context, item T) (uint64, error) {
+	// Holding the lock while blocked keeps IDs in channel order, the
+	// same trade-off as workerpool.Pool.Submit.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, ErrClosed
+	}
+
+	d := Delivery[T]{ID: m.nextID, Item: item}
+	select {
+	case m.ch <- d:
+		m.nextID++
+		m.pending[d.ID] = struct{}{}
+		return d.ID, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-m.closing:
+		return 0, ErrClosed
+	}
+}
+
+func (m *Memory[T]) Deliveries() <-chan Delivery[T] {
+	return m.ch
+}
+
+func (m *Memory[T]) Ack(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pending[id]; !ok {
+		return ErrUnknownID
+	}
+	delete(m.pending, id)
+	return nil
+}
+
+func (m *Memory[T]) Len() int {
+	return len(m.ch)
+}
+
+// Close stops accepting jobs. Jobs already queued are still delivered,
+// then Deliveries is closed.
+func (m *Memory[T]) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closing)
+		m.mu.Lock()
+		m.closed = true
+		close(m.ch)
+		m.mu.Unlock()
+	})
+	return nil
+}
@@ -0,0 +1,89 @@
+// Package jobqueue is the "jobs" channel of the worker-pool lesson
+// turned into an interface, so the backing store can be swapped.
+//
+// Two implementations share the same submit/consume API:
+//   - NewMemory: a bounded channel, exactly like make(chan Job, numJobs)
+//   - Open:      a write-ahead log on disk that survives restarts
+//
+// Producers call Submit (which blocks when the queue is full, keeping
+// the lesson's backpressure). Consumers range over Deliveries and call
+// Ack once a job is finished:
+//
+//	for d := range q.Deliveries() {
+//		process(d.Item)
+//		q.Ack(d.ID)
+//	}
+//
+// A job that was delivered but never acknowledged is delivered again
+// after a restart of a durable queue. Job processing must therefore be
+// idempotent, exactly like a Kubernetes reconcile.
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ==========================================================
+// 1. ERRORS
+// ==========================================================
+
+var (
+	// ErrClosed is returned by Submit after Close, and by Ack once a
+	// durable queue has closed its log.
+	ErrClosed = errors.New("jobqueue: queue is closed")
+
+	// ErrUnknownID is returned by Ack for an ID that is not pending.
+	ErrUnknownID = errors.New("jobqueue: unknown job id")
+
+	// ErrCorrupt is matched by the error Open returns for a log that is
+	// damaged before its last record.
+	ErrCorrupt = errors.New("jobqueue: log is corrupt")
+)
+
+// CorruptError describes a damaged record with valid data after it.
+// Open refuses such a log rather than drop the records that follow. It
+// matches ErrCorrupt.
+type CorruptError struct {
+	Path   string
+	Offset int64 // of the damaged record
+	Err    error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("jobqueue: log %s is corrupt at offset %d: %v", e.Path, e.Offset, e.Err)
+}
+
+func (e *CorruptError) Unwrap() error        { return e.Err }
+func (e *CorruptError) Is(target error) bool { return target == ErrCorrupt }
+
+// ==========================================================
+// 2. INTERFACE
+// ==========================================================
+
+// Delivery is a job handed to a consumer.
+type Delivery[T any] struct {
+	ID   uint64
+	Item T
+}
+
+// Queue is a bounded FIFO of jobs with explicit acknowledgement.
+type Queue[T any] interface {
+	// Submit appends item and returns its ID. It blocks while the
+	// queue is full, until ctx is done or the queue is closed.
+	Submit(ctx context.Context, item T) (uint64, error)
+
+	// Deliveries streams submitted jobs in order. It is closed after
+	// Close once the queue stops delivering.
+	Deliveries() <-chan Delivery[T]
+
+	// Ack marks the job with id as finished.
+	Ack(id uint64) error
+
+	// Len returns how many jobs are waiting to be delivered.
+	Len() int
+
+	// Close stops accepting jobs.
+	Close() error
+}
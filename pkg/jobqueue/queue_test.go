@@ -0,0 +1,287 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type job struct {
+	ID       int
+	Filename string
+}
+
+func receive[T any](t *testing.T, q Queue[T]) Delivery[T] {
+	t.Helper()
+
+	var (
+		d  Delivery[T]
+		ok bool
+	)
+	select {
+	case d, ok = <-q.Deliveries():
+		if !ok {
+			t.Fatal("Deliveries closed unexpectedly")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a delivery")
+	}
+	return d
+}
+
+func submit[T any](t *testing.T, q Queue[T], item T) uint64 {
+	t.Helper()
+	id, err := q.Submit(context.Background(), item)
+	if err != nil {
+		t.Fatalf("Submit(%v) returned %v", item, err)
+	}
+	return id
+}
+
+func TestMemory_SubmitDeliverAck(t *testing.T) {
+	q := NewMemory[job](2)
+
+	id := submit[job](t, q, job{ID: 1, Filename: "data_1.csv"})
+
+	d := receive[job](t, q)
+	if d.ID != id || d.Item.Filename != "data_1.csv" {
+		t.Fatalf("delivery = %+v; want ID %d for data_1.csv", d, id)
+	}
+	if err := q.Ack(d.ID); err != nil {
+		t.Fatalf("Ack returned %v", err)
+	}
+	if err := q.Ack(d.ID); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("second Ack = %v; want %v", err, ErrUnknownID)
+	}
+
+	q.Close()
+	if _, err := q.Submit(context.Background(), job{}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Submit after Close = %v; want %v", err, ErrClosed)
+	}
+}
+
+func TestMemory_Backpressure(t *testing.T) {
+	q := NewMemory[int](1)
+	defer q.Close()
+
+	submit[int](t, q, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.Submit(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Submit on full queue = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDurable_ReplaysUnacknowledgedJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+
+	q, err := Open[job](path, Options{Capacity: 4})
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		submit(t, Queue[job](q), job{ID: i})
+	}
+
+	d := receive(t, Queue[job](q))
+	if err := q.Ack(d.ID); err != nil {
+		t.Fatalf("Ack returned %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+
+	// "Restart" the process.
+	q, err = Open[job](path, Options{Capacity: 4})
+	if err != nil {
+		t.Fatalf("reopen returned %v", err)
+	}
+	defer q.Close()
+
+	if got := q.Recovery().Replayed; got != 2 {
+		t.Fatalf("Recovery().Replayed = %d; want 2", got)
+	}
+	for _, want := range []int{2, 3} {
+		if d := receive(t, Queue[job](q)); d.Item.ID != want {
+			t.Fatalf("replayed job = %d; want %d", d.Item.ID, want)
+		}
+	}
+	if id := submit(t, Queue[job](q), job{ID: 4}); id != 4 {
+		t.Fatalf("ID after replay = %d; want 4", id)
+	}
+}
+
+func TestDurable_TruncatesTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+
+	q, err := Open[job](path, Options{Capacity: 4})
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	submit(t, Queue[job](q), job{ID: 1})
+	submit(t, Queue[job](q), job{ID: 2})
+	q.Close()
+
+	tests := []struct {
+		name string
+		tear func(data []byte) []byte
+	}{
+		{"half-written record", func(data []byte) []byte {
+			return append(data, encodeRecord(recordEnqueue, 3, []byte(`{"ID":3}`))[:10]...)
+		}},
+		{"flipped payload byte", func(data []byte) []byte {
+			out := append([]byte(nil), data...)
+			out[len(out)-1] ^= 0xff
+			return out
+		}},
+		{"zero-filled extension", func(data []byte) []byte {
+			return append(data, make([]byte, 100)...)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torn := filepath.Join(t.TempDir(), "torn.wal")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile returned %v", err)
+			}
+			if err := os.WriteFile(torn, tt.tear(data), 0o644); err != nil {
+				t.Fatalf("WriteFile returned %v", err)
+			}
+
+			q, err := Open[job](torn, Options{})
+			if err != nil {
+				t.Fatalf("Open on torn log returned %v", err)
+			}
+			if q.Recovery().TruncatedBytes == 0 {
+				t.Fatal("Recovery().TruncatedBytes = 0; want the torn tail dropped")
+			}
+
+			// The log must be clean again: a new record survives reopen.
+			submit(t, Queue[job](q), job{ID: 9})
+			q.Close()
+
+			q, err = Open[job](torn, Options{})
+			if err != nil {
+				t.Fatalf("reopen returned %v", err)
+			}
+			defer q.Close()
+			if got := q.Recovery().TruncatedBytes; got != 0 {
+				t.Fatalf("second Recovery().TruncatedBytes = %d; want 0", got)
+			}
+		})
+	}
+}
+
+func TestDurable_RefusesMidLogCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+
+	q, err := Open[job](path, Options{Capacity: 4})
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	submit(t, Queue[job](q), job{ID: 1})
+	submit(t, Queue[job](q), job{ID: 2})
+	q.Close()
+
+	// Damage the first record; the second is still intact after it.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile returned %v", err)
+	}
+	data[headerSize+fixedSize] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile returned %v", err)
+	}
+
+	_, err = Open[job](path, Options{})
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) || !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Open = %v; want a *CorruptError", err)
+	}
+	if corrupt.Offset != 0 {
+		t.Fatalf("Offset = %d; want 0", corrupt.Offset)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile returned %v", err)
+	}
+	if len(after) != len(data) {
+		t.Fatalf("log is %d bytes after Open; want it left at %d", len(after), len(data))
+	}
+}
+
+func TestDurable_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+
+	q, err := Open[job](path, Options{Capacity: 8})
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		submit(t, Queue[job](q), job{ID: i})
+	}
+	for i := 0; i < 4; i++ {
+		d := receive(t, Queue[job](q))
+		if err := q.Ack(d.ID); err != nil {
+			t.Fatalf("Ack returned %v", err)
+		}
+	}
+
+	before, _ := os.Stat(path)
+	if err := q.Compact(); err != nil {
+		t.Fatalf("Compact returned %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Fatalf("log size after Compact = %d; want less than %d", after.Size(), before.Size())
+	}
+
+	// Appends after compaction go to the new log.
+	submit(t, Queue[job](q), job{ID: 6})
+	q.Close()
+
+	q, err = Open[job](path, Options{})
+	if err != nil {
+		t.Fatalf("reopen returned %v", err)
+	}
+	defer q.Close()
+	if got := q.Recovery().Replayed; got != 2 {
+		t.Fatalf("Recovery().Replayed = %d; want 2", got)
+	}
+}
+
+func TestDurable_CompactKeepsIDsMonotonic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+
+	q, err := Open[job](path, Options{Capacity: 8})
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		submit(t, Queue[job](q), job{ID: i})
+		if err := q.Ack(receive(t, Queue[job](q)).ID); err != nil {
+			t.Fatalf("Ack returned %v", err)
+		}
+	}
+	// Nothing is left unacknowledged, so the compacted log holds no
+	// enqueue records at all.
+	if err := q.Compact(); err != nil {
+		t.Fatalf("Compact returned %v", err)
+	}
+	q.Close()
+
+	q, err = Open[job](path, Options{})
+	if err != nil {
+		t.Fatalf("reopen returned %v", err)
+	}
+	defer q.Close()
+	if id := submit(t, Queue[job](q), job{ID: 4}); id != 4 {
+		t.Fatalf("Submit after compact and reopen = ID %d; want 4", id)
+	}
+}
@@ -0,0 +1,547 @@
+package jobqueue
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+/*
+WRITE-AHEAD LOG LAYOUT
+
+The log is a sequence of records:
+
+	+---------+---------+------+---------+-----------------+
+	| crc32   | length  | kind | id      | payload         |
+	| 4 bytes | 4 bytes | 1    | 8 bytes | length-9 bytes  |
+	+---------+---------+------+---------+-----------------+
+
+- kind is recordEnqueue (payload = JSON job), recordAck (no payload)
+  or recordNextID (no payload; id is the next ID to issue)
+- crc32 (Castagnoli) covers kind, id and payload
+- every integer is big-endian
+
+A crash in the middle of an append leaves a TORN record at the tail.
+Open detects it (short read, or a checksum mismatch on the last record,
+or nothing but zeros from the bad record on), truncates the log back to
+the last good record and carries on. A bad record with data after it
+is not a torn append but damage, and truncating would silently drop
+every later record, so Open fails with a *CorruptError instead.
+
+Compaction rewrites the log with a recordNextID followed by only the
+unacknowledged enqueue records into a temporary file and renames it
+over the old one, so a crash during compaction leaves either the old
+or the new log intact. The recordNextID keeps IDs from being reused
+after the acks that vouched for them are dropped.
+*/
+
+const (
+	recordEnqueue byte = 1
+	recordAck     byte = 2
+	recordNextID  byte = 3
+
+	headerSize = 8 // crc32 + length
+	fixedSize  = 9 // kind + id
+
+	// maxRecordSize guards against allocating garbage lengths from a
+	// corrupted header.
+	maxRecordSize = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ==========================================================
+// 1. CONFIGURATION
+// ==========================================================
+
+// Options configures a durable queue.
+type Options struct {
+	// Capacity bounds how many submitted jobs may wait for delivery
+	// before Submit blocks. Replayed jobs do not count. Defaults to 1.
+	Capacity int
+
+	// NoSync skips fsync after each append. Faster, but a machine
+	// crash (not just a process crash) can lose acknowledged writes.
+	NoSync bool
+
+	// CompactInterval is how often the log is compacted. Zero disables
+	// periodic compaction; Compact can still be called directly.
+	CompactInterval time.Duration
+
+	// Clock drives periodic compaction. Defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+// Recovery describes what Open found in an existing log.
+type Recovery struct {
+	// Replayed is the number of unacknowledged jobs redelivered.
+	Replayed int
+
+	// TruncatedBytes is how much of a torn tail was discarded.
+	TruncatedBytes int64
+}
+
+// ==========================================================
+// 2. DURABLE QUEUE
+// ==========================================================
+
+// Durable is a Queue persisted in a write-ahead log.
+type Durable[T any] struct {
+	path string
+	opts Options
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	file     *os.File
+	nextID   uint64
+	ready    []Delivery[T]     // waiting to be delivered
+	unacked  map[uint64][]byte // every enqueued, unacknowledged payload
+	closed   bool
+	recovery Recovery
+
+	// slots is the backpressure semaphore for Submit. replayLeft counts
+	// the replayed jobs at the front of ready, which hold no slot.
+	slots      chan struct{}
+	replayLeft int
+	out        chan Delivery[T]
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+	wg        sync.WaitGroup
+}
+
+var _ Queue[int] = (*Durable[int])(nil)
+
+// Open opens (or creates) the log at path and replays every job that
+// was submitted but not acknowledged.
+func Open[T any](path string, opts Options) (*Durable[T], error) {
+	if opts.Capacity <= 0 {
+		opts.Capacity = 1
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.RealClock{}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("jobqueue: open log: %w", err)
+	}
+
+	q := &Durable[T]{
+		path:    path,
+		opts:    opts,
+		file:    file,
+		nextID:  1,
+		unacked: make(map[uint64][]byte),
+		slots:   make(chan struct{}, opts.Capacity),
+		out:     make(chan Delivery[T]),
+		closing: make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	if err := q.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	q.wg.Add(1)
+	go q.deliverLoop()
+
+	if opts.CompactInterval > 0 {
+		q.wg.Add(1)
+		go q.compactLoop()
+	}
+
+	return q, nil
+}
+
+// Recovery reports what Open replayed and repaired.
+func (q *Durable[T]) Recovery() Recovery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.recovery
+}
+
+func (q *Durable[T]) Submit(ctx context.Context, item T) (uint64, error) {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return 0, fmt.Errorf("jobqueue: encode job: %w", err)
+	}
+
+	select {
+	case q.slots <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-q.closing:
+		return 0, ErrClosed
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		<-q.slots
+		return 0, ErrClosed
+	}
+
+	// Write-ahead: the job is durable before anyone can see it.
+	id := q.nextID
+	if err := q.appendLocked(recordEnqueue, id, payload); err != nil {
+		<-q.slots
+		return 0, err
+	}
+	q.nextID++
+
+	q.unacked[id] = payload
+	q.ready = append(q.ready, Delivery[T]{ID: id, Item: item})
+	q.cond.Signal()
+
+	return id, nil
+}
+
+func (q *Durable[T]) Deliveries() <-chan Delivery[T] {
+	return q.out
+}
+
+func (q *Durable[T]) Ack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	if _, ok := q.unacked[id]; !ok {
+		return ErrUnknownID
+	}
+	if err := q.appendLocked(recordAck, id, nil); err != nil {
+		return err
+	}
+	delete(q.unacked, id)
+	return nil
+}
+
+func (q *Durable[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready)
+}
+
+// Close stops accepting and delivering jobs and closes the log.
+// Jobs that were not acknowledged are replayed by the next Open.
+func (q *Durable[T]) Close() error {
+	q.closeOnce.Do(func() {
+		close(q.closing)
+
+		q.mu.Lock()
+		q.closed = true
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		q.wg.Wait()
+
+		q.mu.Lock()
+		q.closeErr = q.file.Close()
+		q.mu.Unlock()
+	})
+	return q.closeErr
+}
+
+// Compact rewrites the log keeping only unacknowledged jobs.
+func (q *Durable[T]) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	return q.compactLocked()
+}
+
+// ==========================================================
+// 3. DELIVERY
+// ==========================================================
+
+func (q *Durable[T]) deliverLoop() {
+	defer q.wg.Done()
+	defer close(q.out)
+
+	for {
+		q.mu.Lock()
+		for len(q.ready) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		d := q.ready[0]
+		q.ready = q.ready[1:]
+		q.mu.Unlock()
+
+		select {
+		case q.out <- d:
+		case <-q.closing:
+			// Not delivered, not acknowledged: replayed next time.
+			return
+		}
+
+		// Free the Submit slot. Replayed jobs never took one.
+		if q.replayLeft > 0 {
+			q.replayLeft--
+		} else {
+			<-q.slots
+		}
+	}
+}
+
+func (q *Durable[T]) compactLoop() {
+	defer q.wg.Done()
+
+	for {
+		timer := q.opts.Clock.NewTimer(q.opts.CompactInterval)
+		select {
+		case <-q.closing:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		q.mu.Lock()
+		if !q.closed {
+			// A failed compaction leaves the old log in place, which
+			// is still correct, so we simply try again next tick.
+			_ = q.compactLocked()
+		}
+		q.mu.Unlock()
+	}
+}
+
+// ==========================================================
+// 4. LOG I/O
+// ==========================================================
+
+func (q *Durable[T]) appendLocked(kind byte, id uint64, payload []byte) error {
+	if _, err := q.file.Write(encodeRecord(kind, id, payload)); err != nil {
+		return fmt.Errorf("jobqueue: append to log: %w", err)
+	}
+	if !q.opts.NoSync {
+		if err := q.file.Sync(); err != nil {
+			return fmt.Errorf("jobqueue: sync log: %w", err)
+		}
+	}
+	return nil
+}
+
+// replay rebuilds the pending set from the log and truncates a torn
+// tail. It runs before any goroutine starts, so it needs no lock.
+func (q *Durable[T]) replay() error {
+	info, err := q.file.Stat()
+	if err != nil {
+		return fmt.Errorf("jobqueue: stat log: %w", err)
+	}
+	size := info.Size()
+	r := bufio.NewReader(q.file)
+
+	var (
+		good  int64
+		order []uint64
+	)
+	for {
+		kind, id, payload, n, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			torn, zerr := q.tornTail(err, good, n, size)
+			if zerr != nil {
+				return zerr
+			}
+			if !torn {
+				return &CorruptError{Path: q.path, Offset: good, Err: err}
+			}
+			// Everything from here is dropped.
+			break
+		}
+		good += n
+
+		switch kind {
+		case recordEnqueue:
+			q.unacked[id] = payload
+			order = append(order, id)
+		case recordAck:
+			delete(q.unacked, id)
+		case recordNextID:
+			q.nextID = max(q.nextID, id)
+			continue
+		}
+		if id >= q.nextID {
+			q.nextID = id + 1
+		}
+	}
+
+	if size > good {
+		if err := q.file.Truncate(good); err != nil {
+			return fmt.Errorf("jobqueue: truncate torn tail: %w", err)
+		}
+		q.recovery.TruncatedBytes = size - good
+	}
+	// Appends go after the last good record.
+	if _, err := q.file.Seek(good, io.SeekStart); err != nil {
+		return fmt.Errorf("jobqueue: seek log: %w", err)
+	}
+
+	for _, id := range order {
+		payload, ok := q.unacked[id]
+		if !ok {
+			continue
+		}
+		var item T
+		if err := json.Unmarshal(payload, &item); err != nil {
+			return fmt.Errorf("jobqueue: decode job %d: %w", id, err)
+		}
+		q.ready = append(q.ready, Delivery[T]{ID: id, Item: item})
+	}
+	q.recovery.Replayed = len(q.ready)
+	q.replayLeft = len(q.ready)
+
+	return nil
+}
+
+// tornTail reports whether the bad record at off, whose header claims n
+// bytes (zero if unknown), can be the remains of an interrupted append:
+// it runs past the end of the log, ends exactly at it, or is followed
+// only by zeros, as a file extended but never written reads back.
+func (q *Durable[T]) tornTail(err error, off, n, size int64) (bool, error) {
+	if errors.Is(err, io.ErrUnexpectedEOF) || (n > 0 && off+n == size) {
+		return true, nil
+	}
+	buf := make([]byte, 32<<10)
+	rest := io.NewSectionReader(q.file, off, size-off)
+	for {
+		m, err := rest.Read(buf)
+		for _, b := range buf[:m] {
+			if b != 0 {
+				return false, nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("jobqueue: read log: %w", err)
+		}
+	}
+}
+
+func (q *Durable[T]) compactLocked() error {
+	tmpPath := q.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("jobqueue: create compacted log: %w", err)
+	}
+
+	// IDs are issued in submission order, so sorting them keeps replay
+	// order stable.
+	w := bufio.NewWriter(tmp)
+	_, err = w.Write(encodeRecord(recordNextID, q.nextID, nil))
+	for _, id := range slices.Sorted(maps.Keys(q.unacked)) {
+		if err != nil {
+			break
+		}
+		_, err = w.Write(encodeRecord(recordEnqueue, id, q.unacked[id]))
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("jobqueue: write compacted log: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, q.path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("jobqueue: replace log: %w", err)
+	}
+	syncDir(filepath.Dir(q.path))
+
+	q.file.Close()
+	q.file = tmp
+	return nil
+}
+
+// syncDir makes a rename durable. Errors are ignored because not every
+// platform supports fsync on directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+func encodeRecord(kind byte, id uint64, payload []byte) []byte {
+	buf := make([]byte, headerSize+fixedSize+len(payload))
+
+	body := buf[headerSize:]
+	body[0] = kind
+	binary.BigEndian.PutUint64(body[1:fixedSize], id)
+	copy(body[fixedSize:], payload)
+
+	binary.BigEndian.PutUint32(buf[0:4], crc32.Checksum(body, crcTable))
+	binary.BigEndian.PutUint32(buf[4:8], uint32(len(body)))
+	return buf
+}
+
+// readRecord returns io.EOF only at a clean record boundary. Any other
+// error means the record at this position is torn or corrupt: it wraps
+// io.ErrUnexpectedEOF if the log ends inside the record, and n is the
+// size the header claims once the header has been read.
+func readRecord(r io.Reader) (kind byte, id uint64, payload []byte, n int64, err error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, 0, nil, 0, io.EOF
+		}
+		return 0, 0, nil, 0, fmt.Errorf("jobqueue: torn record header: %w", err)
+	}
+
+	sum := binary.BigEndian.Uint32(header[0:4])
+	length := binary.BigEndian.Uint32(header[4:8])
+	if length < fixedSize || length > maxRecordSize {
+		return 0, 0, nil, 0, fmt.Errorf("jobqueue: corrupt record length %d", length)
+	}
+	n = int64(headerSize + length)
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, nil, n, fmt.Errorf("jobqueue: torn record body: %w", err)
+	}
+	if crc32.Checksum(body, crcTable) != sum {
+		return 0, 0, nil, n, errors.New("jobqueue: record checksum mismatch")
+	}
+
+	kind = body[0]
+	id = binary.BigEndian.Uint64(body[1:fixedSize])
+	return kind, id, body[fixedSize:], n, nil
+}
@@ -0,0 +1,404 @@
+// Package pipeline builds multi-stage channel pipelines out of generic
+// stages, so the producer → stage → consumer shape from
+// 05-concurrency/02-channels does not have to be hand-written (and
+// leaked) every time.
+//
+// Every stage:
+//   - runs in its own goroutine(s), tracked by the Pipeline
+//   - closes its output channel when it is done (only the SENDER closes)
+//   - stops as soon as the pipeline context is cancelled
+//   - reports its first error to the Pipeline, which cancels every stage
+//
+// Output channels are bounded, so a slow stage pushes back on the
+// stages before it, exactly like the worker-pool jobs channel.
+//
+//	p := pipeline.New(ctx)
+//	lines := pipeline.FromSlice(p, files)
+//	rows := pipeline.FanOut(p, lines, 4, parse)
+//	valid := pipeline.Filter(p, rows, isValid)
+//	batches := pipeline.Batch(p, valid, 100)
+//	err := pipeline.ForEach(p, batches, write)
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ==========================================================
+// 1. PIPELINE
+// ==========================================================
+
+// Config configures a Pipeline.
+type Config struct {
+	// Buffer is the capacity of every stage's output channel.
+	// Zero means unbuffered: each hand-off is a synchronous handshake.
+	Buffer int
+}
+
+// Pipeline owns the goroutines of every stage built on it.
+type Pipeline struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	buffer int
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// New returns a Pipeline whose stages stop when ctx is done.
+func New(ctx context.Context) *Pipeline {
+	return NewWithConfig(ctx, Config{})
+}
+
+// NewWithConfig returns a Pipeline configured by cfg.
+func NewWithConfig(ctx context.Context, cfg Config) *Pipeline {
+	stageCtx, cancel := context.WithCancel(ctx)
+	return &Pipeline{parent: ctx, ctx: stageCtx, cancel: cancel, buffer: cfg.Buffer}
+}
+
+// Context is cancelled on the first stage error, on Cancel, or when
+// the parent context is done.
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Cancel stops every stage. Use it when the final consumer stops
+// reading early.
+func (p *Pipeline) Cancel() {
+	p.cancel()
+}
+
+// Wait blocks until every stage has exited and returns the first
+// error. Cancellation of the parent context is reported as its error.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+
+	if p.err != nil {
+		return p.err
+	}
+	return p.parent.Err()
+}
+
+// fail records the first error and shuts the pipeline down.
+func (p *Pipeline) fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// spawn runs fn as a tracked stage goroutine. A panic is reported as
+// the stage's error instead of crashing the process.
+func (p *Pipeline) spawn(name string, fn func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				p.fail(fmt.Errorf("pipeline: %s stage panicked: %v", name, r))
+			}
+		}()
+
+		if err := fn(p.ctx); err != nil {
+			p.fail(fmt.Errorf("pipeline: %s stage: %w", name, err))
+		}
+	}()
+}
+
+// ==========================================================
+// 2. CHANNEL HELPERS
+// ==========================================================
+
+// send delivers v unless ctx is done first.
+func send[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// recv receives from ch unless ctx is done first. ok is false when ch
+// is closed or ctx is done.
+func recv[T any](ctx context.Context, ch <-chan T) (v T, ok bool) {
+	select {
+	case v, ok = <-ch:
+		return v, ok
+	case <-ctx.Done():
+		return v, false
+	}
+}
+
+// ==========================================================
+// 3. SOURCES
+// ==========================================================
+
+// FromSlice emits every item of items in order.
+func FromSlice[T any](p *Pipeline, items []T) <-chan T {
+	out := make(chan T, p.buffer)
+	p.spawn("source", func(ctx context.Context) error {
+		defer close(out)
+		for _, item := range items {
+			if !send(ctx, out, item) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Generate runs fn as a producer. emit returns false once the pipeline
+// is shutting down, and fn should return promptly when it does.
+func Generate[T any](p *Pipeline, fn func(ctx context.Context, emit func(T) bool) error) <-chan T {
+	out := make(chan T, p.buffer)
+	p.spawn("generate", func(ctx context.Context) error {
+		defer close(out)
+		return fn(ctx, func(v T) bool { return send(ctx, out, v) })
+	})
+	return out
+}
+
+// ==========================================================
+// 4. TRANSFORMS
+// ==========================================================
+
+// Map applies fn to every value, one at a time, preserving order.
+func Map[In, Out any](p *Pipeline, in <-chan In, fn func(ctx context.Context, v In) (Out, error)) <-chan Out {
+	out := make(chan Out, p.buffer)
+	p.spawn("map", func(ctx context.Context) error {
+		defer close(out)
+		return mapLoop(ctx, in, out, fn)
+	})
+	return out
+}
+
+// FanOut is Map with n workers. Output order is not preserved.
+func FanOut[In, Out any](p *Pipeline, in <-chan In, n int, fn func(ctx context.Context, v In) (Out, error)) <-chan Out {
+	if n < 1 {
+		n = 1
+	}
+	out := make(chan Out, p.buffer)
+
+	var workers sync.WaitGroup
+	workers.Add(n)
+	for i := 0; i < n; i++ {
+		p.spawn("fan-out", func(ctx context.Context) error {
+			defer workers.Done()
+			return mapLoop(ctx, in, out, fn)
+		})
+	}
+
+	// The last worker out closes the shared output.
+	p.spawn("fan-out closer", func(context.Context) error {
+		workers.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+func mapLoop[In, Out any](ctx context.Context, in <-chan In, out chan<- Out, fn func(context.Context, In) (Out, error)) error {
+	for {
+		v, ok := recv(ctx, in)
+		if !ok {
+			return nil
+		}
+		res, err := fn(ctx, v)
+		if err != nil {
+			return err
+		}
+		if !send(ctx, out, res) {
+			return nil
+		}
+	}
+}
+
+// Filter keeps the values for which keep returns true.
+func Filter[T any](p *Pipeline, in <-chan T, keep func(ctx context.Context, v T) (bool, error)) <-chan T {
+	out := make(chan T, p.buffer)
+	p.spawn("filter", func(ctx context.Context) error {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return nil
+			}
+			k, err := keep(ctx, v)
+			if err != nil {
+				return err
+			}
+			if k && !send(ctx, out, v) {
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// FlatMap emits every element of the slice fn returns for each value.
+func FlatMap[In, Out any](p *Pipeline, in <-chan In, fn func(ctx context.Context, v In) ([]Out, error)) <-chan Out {
+	out := make(chan Out, p.buffer)
+	p.spawn("flat-map", func(ctx context.Context) error {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return nil
+			}
+			items, err := fn(ctx, v)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				if !send(ctx, out, item) {
+					return nil
+				}
+			}
+		}
+	})
+	return out
+}
+
+// Batch groups values into slices of size. The last batch may be
+// shorter. For a time trigger and per-item retries, use pkg/batcher.
+func Batch[T any](p *Pipeline, in <-chan T, size int) <-chan []T {
+	if size < 1 {
+		size = 1
+	}
+	out := make(chan []T, p.buffer)
+	p.spawn("batch", func(ctx context.Context) error {
+		defer close(out)
+
+		batch := make([]T, 0, size)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				break
+			}
+			batch = append(batch, v)
+			if len(batch) == size {
+				if !send(ctx, out, batch) {
+					return nil
+				}
+				batch = make([]T, 0, size)
+			}
+		}
+
+		if len(batch) > 0 && ctx.Err() == nil {
+			send(ctx, out, batch)
+		}
+		return nil
+	})
+	return out
+}
+
+// ==========================================================
+// 5. FAN-IN & TEE
+// ==========================================================
+
+// Merge (fan-in) forwards every value from every input onto one
+// channel, closing it once all inputs are closed.
+func Merge[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T, p.buffer)
+
+	var forwarders sync.WaitGroup
+	forwarders.Add(len(ins))
+	for _, in := range ins {
+		p.spawn("merge", func(ctx context.Context) error {
+			defer forwarders.Done()
+			for {
+				v, ok := recv(ctx, in)
+				if !ok {
+					return nil
+				}
+				if !send(ctx, out, v) {
+					return nil
+				}
+			}
+		})
+	}
+
+	p.spawn("merge closer", func(context.Context) error {
+		forwarders.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// FanIn is an alias for Merge.
+func FanIn[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	return Merge(p, ins...)
+}
+
+// Tee copies every value onto two outputs. The slower consumer sets
+// the pace for both.
+func Tee[T any](p *Pipeline, in <-chan T) (<-chan T, <-chan T) {
+	out1 := make(chan T, p.buffer)
+	out2 := make(chan T, p.buffer)
+
+	p.spawn("tee", func(ctx context.Context) error {
+		defer close(out1)
+		defer close(out2)
+
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return nil
+			}
+
+			// Send to whichever consumer is ready first, then the other.
+			a, b := out1, out2
+			for a != nil || b != nil {
+				select {
+				case a <- v:
+					a = nil
+				case b <- v:
+					b = nil
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	})
+	return out1, out2
+}
+
+// ==========================================================
+// 6. SINKS
+// ==========================================================
+
+// ForEach calls fn for every value and then waits for the whole
+// pipeline, returning its first error.
+func ForEach[T any](p *Pipeline, in <-chan T, fn func(ctx context.Context, v T) error) error {
+	p.spawn("sink", func(ctx context.Context) error {
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return nil
+			}
+			if err := fn(ctx, v); err != nil {
+				return err
+			}
+		}
+	})
+	return p.Wait()
+}
+
+// Collect gathers every value into a slice and waits for the whole
+// pipeline. On error the partial slice is returned with it.
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var items []T
+	err := ForEach(p, in, func(_ context.Context, v T) error {
+		items = append(items, v)
+		return nil
+	})
+	return items, err
+}
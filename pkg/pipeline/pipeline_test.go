@@ -0,0 +1,186 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func square(_ context.Context, n int) (int, error) { return n * n, nil }
+
+func isEven(_ context.Context, n int) (bool, error) { return n%2 == 0, nil }
+
+func TestPipeline_MapFilterCollect(t *testing.T) {
+	p := New(context.Background())
+
+	nums := FromSlice(p, []int{1, 2, 3, 4, 5, 6})
+	squares := Map(p, nums, square)
+	even := Filter(p, squares, isEven)
+
+	got, err := Collect(p, even)
+	if err != nil {
+		t.Fatalf("Collect returned %v", err)
+	}
+
+	want := []int{4, 16, 36}
+	if len(got) != len(want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v; want %v", got, want)
+		}
+	}
+}
+
+func TestPipeline_Stages(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(p *Pipeline) <-chan int
+		want  []int
+	}{
+		{
+			name: "fan-out",
+			build: func(p *Pipeline) <-chan int {
+				return FanOut(p, FromSlice(p, []int{1, 2, 3, 4}), 3, square)
+			},
+			want: []int{1, 4, 9, 16},
+		},
+		{
+			name: "flat-map",
+			build: func(p *Pipeline) <-chan int {
+				return FlatMap(p, FromSlice(p, []int{1, 2}), func(_ context.Context, n int) ([]int, error) {
+					return []int{n, n * 10}, nil
+				})
+			},
+			want: []int{1, 2, 10, 20},
+		},
+		{
+			name: "merge",
+			build: func(p *Pipeline) <-chan int {
+				return Merge(p, FromSlice(p, []int{1, 3}), FromSlice(p, []int{2, 4}))
+			},
+			want: []int{1, 2, 3, 4},
+		},
+		{
+			name: "tee both sides",
+			build: func(p *Pipeline) <-chan int {
+				a, b := Tee(p, FromSlice(p, []int{1, 2}))
+				return FanIn(p, a, b)
+			},
+			want: []int{1, 1, 2, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(context.Background())
+			got, err := Collect(p, tt.build(p))
+			if err != nil {
+				t.Fatalf("Collect returned %v", err)
+			}
+
+			// These stages do not promise order.
+			sort.Ints(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v; want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v; want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPipeline_Batch(t *testing.T) {
+	p := New(context.Background())
+	batches, err := Collect(p, Batch(p, FromSlice(p, []int{1, 2, 3, 4, 5}), 2))
+	if err != nil {
+		t.Fatalf("Collect returned %v", err)
+	}
+
+	wantSizes := []int{2, 2, 1}
+	if len(batches) != len(wantSizes) {
+		t.Fatalf("got %d batches; want %d", len(batches), len(wantSizes))
+	}
+	for i, b := range batches {
+		if len(b) != wantSizes[i] {
+			t.Fatalf("batch %d has %d items; want %d", i, len(b), wantSizes[i])
+		}
+	}
+}
+
+func TestPipeline_FirstErrorStopsEverything(t *testing.T) {
+	errBadRow := errors.New("bad row")
+	before := runtime.NumGoroutine()
+
+	p := New(context.Background())
+
+	// An endless source: only cancellation can stop it.
+	nums := Generate(p, func(ctx context.Context, emit func(int) bool) error {
+		for i := 0; ; i++ {
+			if !emit(i) {
+				return nil
+			}
+		}
+	})
+	parsed := FanOut(p, nums, 4, func(_ context.Context, n int) (int, error) {
+		if n == 100 {
+			return 0, errBadRow
+		}
+		return n, nil
+	})
+
+	err := ForEach(p, parsed, func(context.Context, int) error { return nil })
+	if !errors.Is(err, errBadRow) {
+		t.Fatalf("ForEach = %v; want %v", err, errBadRow)
+	}
+
+	// Every stage goroutine must be gone once Wait returns.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines = %d after error; want %d (leak)", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPipeline_PanicBecomesError(t *testing.T) {
+	p := New(context.Background())
+	out := Map(p, FromSlice(p, []int{1}), func(context.Context, int) (int, error) {
+		panic("boom")
+	})
+
+	_, err := Collect(p, out)
+	if err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Fatalf("Collect = %v; want a panic error", err)
+	}
+}
+
+func TestPipeline_ParentCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx)
+
+	nums := Generate(p, func(ctx context.Context, emit func(int) bool) error {
+		for emit(1) {
+		}
+		return nil
+	})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err := ForEach(p, nums, func(context.Context, int) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ForEach = %v; want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+/*
+PER-KEY LIMITS
+
+One bucket for the whole process limits total traffic. Fairness needs a
+bucket PER caller: per tenant, per API key, per client IP. Those keys
+are unbounded, so buckets are created lazily on first use and dropped
+once idle.
+
+An idle bucket is only dropped once it has refilled to Burst. At that
+point it is indistinguishable from a fresh one, so eviction can never
+hand a caller extra tokens.
+*/
+
+// KeyedConfig configures a Keyed limiter.
+type KeyedConfig struct {
+	// Limit and Burst apply to every key's bucket.
+	Limit Limit
+	Burst int
+
+	// IdleTimeout is how long a bucket must go unused before it can be
+	// evicted. Defaults to 10m.
+	IdleTimeout time.Duration
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c KeyedConfig) withDefaults() KeyedConfig {
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 10 * time.Minute
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+type keyedEntry struct {
+	lim      *Limiter
+	lastUsed time.Time
+}
+
+// Keyed holds one token bucket per key. It is safe for concurrent use.
+type Keyed[K comparable] struct {
+	cfg KeyedConfig
+
+	mu        sync.Mutex
+	limit     Limit
+	burst     int
+	buckets   map[K]*keyedEntry
+	lastSweep time.Time
+}
+
+// NewKeyed returns an empty Keyed limiter.
+func NewKeyed[K comparable](cfg KeyedConfig) *Keyed[K] {
+	cfg = cfg.withDefaults()
+	return &Keyed[K]{
+		cfg:       cfg,
+		limit:     cfg.Limit,
+		burst:     cfg.Burst,
+		buckets:   make(map[K]*keyedEntry),
+		lastSweep: cfg.Clock.Now(),
+	}
+}
+
+// Get returns key's bucket, creating it on first use. Every call
+// counts as use for idle eviction.
+func (k *Keyed[K]) Get(key K) *Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.cfg.Clock.Now()
+	// Sweep at most once per IdleTimeout so Get stays O(1) amortized.
+	if now.Sub(k.lastSweep) >= k.cfg.IdleTimeout {
+		k.evictLocked(now)
+	}
+
+	e, ok := k.buckets[key]
+	if !ok {
+		e = &keyedEntry{lim: NewWithConfig(Config{Limit: k.limit, Burst: k.burst, Clock: k.cfg.Clock})}
+		k.buckets[key] = e
+	}
+	e.lastUsed = now
+	return e.lim
+}
+
+// Allow reports whether one event for key may happen now.
+func (k *Keyed[K]) Allow(key K) bool {
+	return k.Get(key).Allow()
+}
+
+// Wait blocks until one event for key may happen or ctx is done.
+func (k *Keyed[K]) Wait(ctx context.Context, key K) error {
+	return k.Get(key).Wait(ctx)
+}
+
+// Reserve reserves one token from key's bucket.
+func (k *Keyed[K]) Reserve(key K) *Reservation {
+	return k.Get(key).Reserve()
+}
+
+// SetLimits changes the rate and burst of every bucket, existing and
+// future.
+func (k *Keyed[K]) SetLimits(limit Limit, burst int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.limit, k.burst = limit, burst
+	for _, e := range k.buckets {
+		e.lim.SetLimit(limit)
+		e.lim.SetBurst(burst)
+	}
+}
+
+// Len returns how many buckets exist.
+func (k *Keyed[K]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.buckets)
+}
+
+// EvictIdle drops every idle, full bucket now and returns how many were
+// dropped. Get also does this periodically.
+func (k *Keyed[K]) EvictIdle() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.evictLocked(k.cfg.Clock.Now())
+}
+
+func (k *Keyed[K]) evictLocked(now time.Time) int {
+	k.lastSweep = now
+	evicted := 0
+	for key, e := range k.buckets {
+		if now.Sub(e.lastUsed) < k.cfg.IdleTimeout {
+			continue
+		}
+		if e.lim.Tokens() < float64(k.burst) {
+			continue
+		}
+		delete(k.buckets, key)
+		evicted++
+	}
+	return evicted
+}
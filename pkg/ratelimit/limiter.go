@@ -0,0 +1,362 @@
+// Package ratelimit limits how fast we call something, so that retries
+// and fan-out cannot DOS our own dependencies (the warning in
+// 07-error-handling/05-retry-backoff-patterns).
+//
+// It is a TOKEN BUCKET, with the same semantics as golang.org/x/time/rate:
+//
+//   - the bucket holds at most Burst tokens
+//   - it refills at Limit tokens per second
+//   - every event takes one token (or n for AllowN / WaitN / ReserveN)
+//
+// Three ways to ask for a token:
+//
+//	Allow    → take it now or report false (drop / reject the request)
+//	Wait     → block until it is available or ctx is done
+//	Reserve  → take it now, learn how long to wait, and Cancel to give
+//	           it back if you decide not to act
+//
+// Time comes from a clock.Clock, so tests step a FakeClock instead of
+// sleeping.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. LIMITS & ERRORS
+// ==========================================================
+
+// Limit is a rate in events per second.
+type Limit float64
+
+// Inf allows every event; Burst is ignored.
+const Inf = Limit(math.MaxFloat64)
+
+// Every converts a minimum interval between events to a Limit.
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Inf
+	}
+	return 1 / Limit(interval.Seconds())
+}
+
+// durationFromTokens is how long the bucket takes to refill tokens.
+func (l Limit) durationFromTokens(tokens float64) time.Duration {
+	if l <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	seconds := tokens / float64(l)
+	if seconds >= float64(math.MaxInt64)/float64(time.Second) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// tokensFromDuration is how many tokens refill in d.
+func (l Limit) tokensFromDuration(d time.Duration) float64 {
+	if l <= 0 {
+		return 0
+	}
+	return d.Seconds() * float64(l)
+}
+
+var (
+	// ErrExceedsBurst is returned by WaitN when n can never fit in the
+	// bucket.
+	ErrExceedsBurst = errors.New("ratelimit: n exceeds burst")
+
+	// ErrExceedsDeadline is returned by WaitN when the wait would end
+	// after the context deadline, so it fails without waiting.
+	ErrExceedsDeadline = errors.New("ratelimit: wait would exceed context deadline")
+)
+
+// ==========================================================
+// 2. LIMITER
+// ==========================================================
+
+// Config configures a Limiter.
+type Config struct {
+	Limit Limit
+	Burst int
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+// Limiter is a token bucket. It is safe for concurrent use.
+type Limiter struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	limit  Limit
+	burst  int
+	tokens float64
+	// last is when tokens was last brought up to date.
+	last time.Time
+	// lastEvent is the latest time a reservation acts at.
+	lastEvent time.Time
+}
+
+// New returns a Limiter that allows limit events per second with
+// bursts of up to burst events. The bucket starts full.
+func New(limit Limit, burst int) *Limiter {
+	return NewWithConfig(Config{Limit: limit, Burst: burst})
+}
+
+// NewWithConfig returns a Limiter configured by cfg.
+func NewWithConfig(cfg Config) *Limiter {
+	if cfg.Clock == nil {
+		cfg.Clock = clock.RealClock{}
+	}
+	return &Limiter{
+		clock:  cfg.Clock,
+		limit:  cfg.Limit,
+		burst:  cfg.Burst,
+		tokens: float64(cfg.Burst),
+		last:   cfg.Clock.Now(),
+	}
+}
+
+// Limit returns the current rate.
+func (l *Limiter) Limit() Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Burst returns the current bucket size.
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+// Tokens returns how many tokens are available now. It is negative
+// while reservations are waiting.
+func (l *Limiter) Tokens() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, tokens := l.advance(l.clock.Now())
+	return tokens
+}
+
+// SetLimit changes the rate. Tokens already accrued are kept.
+func (l *Limiter) SetLimit(limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.last, l.tokens = l.advance(now)
+	l.limit = limit
+}
+
+// SetBurst changes the bucket size.
+func (l *Limiter) SetBurst(burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.last, l.tokens = l.advance(now)
+	l.burst = burst
+	l.tokens = math.Min(l.tokens, float64(burst))
+}
+
+// advance returns the bucket brought up to now, without storing it.
+func (l *Limiter) advance(now time.Time) (time.Time, float64) {
+	last := l.last
+	if now.Before(last) {
+		last = now
+	}
+
+	tokens := l.tokens + l.limit.tokensFromDuration(now.Sub(last))
+	if burst := float64(l.burst); tokens > burst {
+		tokens = burst
+	}
+	return now, tokens
+}
+
+// ==========================================================
+// 3. ALLOW & RESERVE
+// ==========================================================
+
+// Allow reports whether one event may happen now.
+func (l *Limiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+// AllowN reports whether n events may happen now, and takes their
+// tokens if so.
+func (l *Limiter) AllowN(n int) bool {
+	return l.reserveN(l.clock.Now(), n, 0).ok
+}
+
+// Reservation is a claim on tokens that may only be usable later.
+type Reservation struct {
+	ok        bool
+	lim       *Limiter
+	tokens    int
+	timeToAct time.Time
+	// limit at reservation time, used to work out what Cancel restores.
+	limit Limit
+}
+
+// OK reports whether the limiter can ever grant the reservation. A
+// reservation for more than Burst tokens is never OK.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay is how long the caller must wait before acting.
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(r.lim.clock.Now())
+}
+
+// DelayFrom is Delay measured from now.
+func (r *Reservation) DelayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	if d := r.timeToAct.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel gives the tokens back, as far as possible. Tokens that later
+// reservations were counted against stay taken.
+func (r *Reservation) Cancel() {
+	r.lim.cancel(r, r.lim.clock.Now())
+}
+
+// Reserve reserves one token.
+func (l *Limiter) Reserve() *Reservation {
+	return l.ReserveN(1)
+}
+
+// ReserveN reserves n tokens. Check OK, then wait Delay before acting,
+// or Cancel.
+func (l *Limiter) ReserveN(n int) *Reservation {
+	return l.reserveN(l.clock.Now(), n, time.Duration(math.MaxInt64))
+}
+
+// reserveN takes n tokens, allowing the bucket to go negative by as
+// many tokens as refill within maxWait.
+func (l *Limiter) reserveN(now time.Time, n int, maxWait time.Duration) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit == Inf {
+		return &Reservation{ok: true, lim: l, tokens: n, timeToAct: now, limit: l.limit}
+	}
+
+	now, tokens := l.advance(now)
+	tokens -= float64(n)
+
+	var wait time.Duration
+	if tokens < 0 {
+		wait = l.limit.durationFromTokens(-tokens)
+	}
+
+	r := &Reservation{
+		ok:    n <= l.burst && wait <= maxWait,
+		lim:   l,
+		limit: l.limit,
+	}
+	if r.ok {
+		r.tokens = n
+		r.timeToAct = now.Add(wait)
+
+		l.last = now
+		l.tokens = tokens
+		l.lastEvent = r.timeToAct
+	}
+	return r
+}
+
+func (l *Limiter) cancel(r *Reservation, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !r.ok || r.tokens == 0 || l.limit == Inf || r.timeToAct.Before(now) {
+		return
+	}
+
+	// Reservations made after r were sized assuming r's tokens were
+	// gone; only what they did not depend on can come back.
+	n := float64(r.tokens)
+	restore := n - r.limit.tokensFromDuration(l.lastEvent.Sub(r.timeToAct))
+	r.tokens = 0
+	if restore <= 0 {
+		return
+	}
+
+	now, tokens := l.advance(now)
+	l.last = now
+	l.tokens = math.Min(tokens+restore, float64(l.burst))
+	if r.timeToAct.Equal(l.lastEvent) {
+		prev := r.timeToAct.Add(-r.limit.durationFromTokens(n))
+		if !prev.Before(now) {
+			l.lastEvent = prev
+		}
+	}
+}
+
+// ==========================================================
+// 4. WAIT
+// ==========================================================
+
+// Wait blocks until one event may happen or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n events may happen. If the wait would outlast
+// ctx's deadline it fails immediately with ErrExceedsDeadline, and if
+// ctx ends while waiting the tokens are given back.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	l.mu.Lock()
+	burst, limit := l.burst, l.limit
+	l.mu.Unlock()
+
+	if n > burst && limit != Inf {
+		return fmt.Errorf("%w: n=%d, burst=%d", ErrExceedsBurst, n, burst)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	maxWait := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		// Context deadlines are wall-clock, whatever l.clock says.
+		maxWait = time.Until(deadline)
+	}
+
+	now := l.clock.Now()
+	r := l.reserveN(now, n, maxWait)
+	if !r.ok {
+		return fmt.Errorf("%w: n=%d", ErrExceedsDeadline, n)
+	}
+
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,205 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newFake(limit Limit, burst int) (*Limiter, *clock.FakeClock) {
+	fake := clock.NewFakeClock(epoch)
+	return NewWithConfig(Config{Limit: limit, Burst: burst, Clock: fake}), fake
+}
+
+func waitForWaiters(t *testing.T, c *clock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a clock waiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimiter_AllowBurstThenRefill(t *testing.T) {
+	lim, fake := newFake(10, 3) // one token per 100ms
+
+	for i := 0; i < 3; i++ {
+		if !lim.Allow() {
+			t.Fatalf("Allow() #%d = false; want burst of 3", i+1)
+		}
+	}
+	if lim.Allow() {
+		t.Fatal("Allow() past burst = true; want false")
+	}
+
+	fake.Step(100 * time.Millisecond)
+	if !lim.Allow() {
+		t.Fatal("Allow() after one refill = false; want true")
+	}
+	if lim.Allow() {
+		t.Fatal("second Allow() after one refill = true; want false")
+	}
+
+	// Refill stops at Burst.
+	fake.Step(time.Hour)
+	if got := lim.Tokens(); got != 3 {
+		t.Fatalf("Tokens() after a long idle = %v; want 3", got)
+	}
+}
+
+func TestLimiter_ReserveDelayAndCancel(t *testing.T) {
+	lim, _ := newFake(10, 1)
+
+	now := lim.Reserve()
+	if !now.OK() || now.Delay() != 0 {
+		t.Fatalf("first Reserve: OK=%v Delay=%v; want immediate", now.OK(), now.Delay())
+	}
+
+	later := lim.Reserve()
+	if got := later.Delay(); got != 100*time.Millisecond {
+		t.Fatalf("second Reserve Delay = %v; want 100ms", got)
+	}
+	latest := lim.Reserve()
+	if got := latest.Delay(); got != 200*time.Millisecond {
+		t.Fatalf("third Reserve Delay = %v; want 200ms", got)
+	}
+
+	// Cancelling the last reservation gives its token back.
+	latest.Cancel()
+	if got := lim.Reserve().Delay(); got != 200*time.Millisecond {
+		t.Fatalf("Reserve after Cancel Delay = %v; want 200ms", got)
+	}
+
+	if r := lim.ReserveN(2); r.OK() {
+		t.Fatal("ReserveN above burst is OK; want never")
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	lim, fake := newFake(10, 1)
+	lim.Allow()
+
+	done := make(chan error, 1)
+	go func() { done <- lim.Wait(context.Background()) }()
+
+	waitForWaiters(t, fake)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v before the token refilled", err)
+	default:
+	}
+
+	fake.Step(100 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("Wait returned %v", err)
+	}
+}
+
+func TestLimiter_WaitCancelReturnsToken(t *testing.T) {
+	lim, fake := newFake(1, 1)
+	lim.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- lim.Wait(ctx) }()
+
+	waitForWaiters(t, fake)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v; want %v", err, context.Canceled)
+	}
+
+	// The cancelled waiter no longer holds the next token.
+	fake.Step(time.Second)
+	if !lim.Allow() {
+		t.Fatal("Allow() after cancelled Wait = false; want true")
+	}
+}
+
+func TestLimiter_WaitFailsFast(t *testing.T) {
+	lim, _ := newFake(Every(time.Hour), 1)
+	lim.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lim.Wait(ctx); !errors.Is(err, ErrExceedsDeadline) {
+		t.Fatalf("Wait = %v; want %v", err, ErrExceedsDeadline)
+	}
+	if err := lim.WaitN(context.Background(), 2); !errors.Is(err, ErrExceedsBurst) {
+		t.Fatalf("WaitN above burst = %v; want %v", err, ErrExceedsBurst)
+	}
+}
+
+func TestLimiter_SetLimitAtRuntime(t *testing.T) {
+	lim, fake := newFake(1, 1)
+	lim.Allow()
+
+	lim.SetLimit(10)
+	fake.Step(100 * time.Millisecond)
+	if !lim.Allow() {
+		t.Fatal("Allow() after raising the limit = false; want true")
+	}
+
+	lim.SetBurst(5)
+	fake.Step(time.Second)
+	if got := lim.Tokens(); got != 5 {
+		t.Fatalf("Tokens() after raising burst = %v; want 5", got)
+	}
+
+	lim.SetLimit(Inf)
+	for i := 0; i < 100; i++ {
+		if !lim.Allow() {
+			t.Fatal("Allow() with Inf limit = false; want true")
+		}
+	}
+}
+
+func TestKeyed_LazyBucketsAndEviction(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	k := NewKeyed[string](KeyedConfig{Limit: 1, Burst: 1, IdleTimeout: time.Minute, Clock: fake})
+
+	if !k.Allow("tenant-a") || k.Allow("tenant-a") {
+		t.Fatal("tenant-a should get exactly its burst")
+	}
+	if !k.Allow("tenant-b") {
+		t.Fatal("tenant-b was limited by tenant-a's bucket")
+	}
+	if got := k.Len(); got != 2 {
+		t.Fatalf("Len() = %d; want 2", got)
+	}
+
+	fake.Step(30 * time.Second)
+	k.Get("tenant-b") // still active
+
+	fake.Step(45 * time.Second)
+	if got := k.EvictIdle(); got != 1 {
+		t.Fatalf("EvictIdle() = %d; want 1 (only tenant-a is idle)", got)
+	}
+	if got := k.Len(); got != 1 {
+		t.Fatalf("Len() after eviction = %d; want 1", got)
+	}
+}
+
+func TestKeyed_SetLimits(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	k := NewKeyed[int](KeyedConfig{Limit: 1, Burst: 1, Clock: fake})
+	k.Allow(1)
+
+	k.SetLimits(1, 3)
+	fake.Step(time.Minute)
+	for i := 0; i < 3; i++ {
+		if !k.Allow(1) {
+			t.Fatalf("existing bucket Allow #%d = false; want new burst of 3", i+1)
+		}
+	}
+	if got := k.Get(2).Burst(); got != 3 {
+		t.Fatalf("new bucket Burst() = %d; want 3", got)
+	}
+}
@@ -0,0 +1,99 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+)
+
+// ==========================================================
+// 4. SLOG HANDLER
+// ==========================================================
+
+// Handler is a slog.Handler that redacts records before passing them
+// on. It scrubs the message and every attribute: values under
+// sensitive keys are replaced whole, groups are walked, and anything
+// that is text (strings, errors, fmt.Stringers, []byte and named
+// string types) is run through the Redactor. Other values, such as
+// structs, are passed on untouched without being formatted, so hot
+// log sites pay nothing for them; give their secret fields the Secret
+// type instead.
+type Handler struct {
+	next slog.Handler
+	r    *Redactor
+}
+
+// NewHandler returns a Handler that redacts with r and writes to next.
+//
+//	logger := slog.New(redact.NewHandler(slog.NewJSONHandler(os.Stderr, nil), redact.Default()))
+func NewHandler(next slog.Handler, r *Redactor) *Handler {
+	return &Handler{next: next, r: r}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, h.r.String(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	red := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		red[i] = h.attr(a)
+	}
+	return &Handler{next: h.next.WithAttrs(red), r: h.r}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name), r: h.r}
+}
+
+func (h *Handler) attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if h.r.SensitiveKey(a.Key) {
+		return slog.String(a.Key, Placeholder)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.r.String(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		red := make([]slog.Attr, len(group))
+		for i, g := range group {
+			red[i] = h.attr(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(red...)}
+	case slog.KindAny:
+		if s, ok := text(a.Value.Any()); ok {
+			if red := h.r.String(s); red != s {
+				return slog.String(a.Key, red)
+			}
+		}
+	}
+	return a
+}
+
+// text returns v as a string if it is a kind of text.
+func text(v any) (string, bool) {
+	switch v := v.(type) {
+	case error:
+		return v.Error(), true
+	case fmt.Stringer:
+		return v.String(), true
+	case []byte:
+		return string(v), true
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return rv.String(), true
+	}
+	return "", false
+}
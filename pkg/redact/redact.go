@@ -0,0 +1,239 @@
+// Package redact keeps secrets out of errors and logs.
+//
+// DatabaseConfig.Connection in 02-data-structures/03-structs-json holds
+// "postgres://localhost:5432"; a real one holds a password, and the
+// first time the connection fails it ends up in
+//
+//	fmt.Errorf("connect to %s: %w", cfg.Connection, err)
+//
+// and from there in every log line and API response that carries the
+// error. This package closes both ends. A Secret is a string that
+// prints, formats and marshals as Placeholder, so the value never
+// leaves by accident. A Redactor scrubs text that was already
+// built: URL credentials, "password=..." style pairs, bearer tokens,
+// and whatever other Detectors it is given. NewWriter applies it to
+// the standard log package's output, NewHandler to every slog record,
+// and status.Registry.SetRedact to every error written to a client.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "[REDACTED]"
+
+// ==========================================================
+// 1. SECRET
+// ==========================================================
+
+// Secret is a string that is never printed. String, every fmt verb,
+// JSON and slog all produce Placeholder; Reveal returns the value.
+// Decoding JSON into a Secret works as for a string, so config files
+// can hold them.
+type Secret string
+
+// Reveal returns the secret value. Call it only where the value is
+// used, never to build a message.
+func (s Secret) Reveal() string { return string(s) }
+
+func (s Secret) String() string   { return Placeholder }
+func (s Secret) GoString() string { return `redact.Secret("` + Placeholder + `")` }
+
+// Format prints Placeholder for every verb, including %x and %q,
+// which would otherwise bypass String.
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if f.Flag('#') {
+			io.WriteString(f, s.GoString())
+			return
+		}
+	case 'q':
+		io.WriteString(f, `"`+Placeholder+`"`)
+		return
+	}
+	io.WriteString(f, Placeholder)
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) { return json.Marshal(Placeholder) }
+func (s Secret) MarshalText() ([]byte, error) { return []byte(Placeholder), nil }
+func (s Secret) LogValue() slog.Value         { return slog.StringValue(Placeholder) }
+
+// ==========================================================
+// 2. DETECTORS
+// ==========================================================
+
+// Detector finds secrets in text.
+type Detector interface {
+	// Redact returns s with every secret it finds replaced by
+	// Placeholder.
+	Redact(s string) string
+}
+
+// KeyDetector is a Detector that also recognizes sensitive keys, such
+// as slog attribute names, whose values are redacted whole.
+type KeyDetector interface {
+	Detector
+	SensitiveKey(key string) bool
+}
+
+// Pattern is a regular expression detector. If the expression has a
+// group named "secret", only that group is replaced; otherwise the
+// whole match is.
+type Pattern struct {
+	re    *regexp.Regexp
+	group int
+}
+
+// NewPattern compiles expr into a Pattern. It panics if expr does not
+// compile, like regexp.MustCompile; patterns are fixed at startup.
+func NewPattern(expr string) *Pattern {
+	re := regexp.MustCompile(expr)
+	return &Pattern{re: re, group: re.SubexpIndex("secret")}
+}
+
+func (p *Pattern) Redact(s string) string {
+	matches := p.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if p.group > 0 {
+			start, end = m[2*p.group], m[2*p.group+1]
+		}
+		if start < 0 || start == end {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Placeholder)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// URLCredentials redacts the password in URLs such as
+// "postgres://app:hunter2@db:5432/users", keeping the user name, as
+// url.URL.Redacted does.
+func URLCredentials() *Pattern {
+	return NewPattern(`(?i)\b[a-z][a-z0-9+.-]*://[^/\s:@]*:(?P<secret>[^/\s@]+)@`)
+}
+
+// BearerTokens redacts the token in "Bearer <token>".
+func BearerTokens() *Pattern {
+	return NewPattern(`(?i)\bbearer\s+(?P<secret>[\w.~+/=-]+)`)
+}
+
+// Keywords redacts the value of any key containing one of its words,
+// case-insensitively, in "key=value", "key:value" and JSON
+// "key": "value" form. "key: value" is left alone: it is how wrapped
+// error messages read ("load secret: permission denied"). As a
+// KeyDetector it also marks such keys sensitive.
+type Keywords struct {
+	words   []string
+	pattern *Pattern
+}
+
+// NewKeywords returns a Keywords detector for words.
+func NewKeywords(words ...string) *Keywords {
+	lower := make([]string, len(words))
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		lower[i] = strings.ToLower(w)
+		quoted[i] = regexp.QuoteMeta(w)
+	}
+	expr := `(?i)[\w.-]*(?:` + strings.Join(quoted, "|") + `)[\w.-]*` +
+		`(?:"\s*:\s*"?|\s*=\s*"?|:"?)(?P<secret>[^\s"'&,;}]+)`
+	return &Keywords{words: lower, pattern: NewPattern(expr)}
+}
+
+func (k *Keywords) Redact(s string) string { return k.pattern.Redact(s) }
+
+func (k *Keywords) SensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, w := range k.words {
+		if strings.Contains(key, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultKeywords are the words Default treats as sensitive.
+var DefaultKeywords = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "credential", "authorization"}
+
+// ==========================================================
+// 3. REDACTOR
+// ==========================================================
+
+// Redactor applies a set of Detectors, in order. A nil *Redactor
+// redacts nothing.
+type Redactor struct {
+	detectors []Detector
+}
+
+// New returns a Redactor using detectors.
+func New(detectors ...Detector) *Redactor {
+	return &Redactor{detectors: detectors}
+}
+
+// Default returns a Redactor for URL credentials, bearer tokens and
+// DefaultKeywords.
+func Default() *Redactor {
+	return New(URLCredentials(), BearerTokens(), NewKeywords(DefaultKeywords...))
+}
+
+// String returns s with every detected secret replaced.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, d := range r.detectors {
+		s = d.Redact(s)
+	}
+	return s
+}
+
+// SensitiveKey reports whether any KeyDetector marks key sensitive.
+func (r *Redactor) SensitiveKey(key string) bool {
+	if r == nil {
+		return false
+	}
+	for _, d := range r.detectors {
+		if kd, ok := d.(KeyDetector); ok && kd.SensitiveKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Error returns err with its message redacted. The result unwraps to
+// err, so errors.Is and errors.As still work; only the text changes.
+// err is returned as is if nothing was redacted.
+func (r *Redactor) Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if red := r.String(msg); red != msg {
+		return &redactedError{msg: red, err: err}
+	}
+	return err
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
@@ -0,0 +1,117 @@
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+type databaseConfig struct {
+	DBName     string
+	Connection Secret
+}
+
+func TestSecretNeverPrints(t *testing.T) {
+	const pw = "postgres://app:hunter2@db:5432/users"
+	cfg := databaseConfig{DBName: "users_db", Connection: Secret(pw)}
+
+	outputs := map[string]string{
+		"String": cfg.Connection.String(),
+	}
+	for _, verb := range []string{"%s", "%v", "%+v", "%#v", "%q", "%x", "%X", "%10s", "%d"} {
+		outputs[verb] = fmt.Sprintf(verb, cfg.Connection)
+		outputs[verb+" struct"] = fmt.Sprintf(verb, cfg)
+	}
+	outputs["Sprint"] = fmt.Sprint(cfg.Connection, &cfg)
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	outputs["json"] = string(b)
+
+	for name, out := range outputs {
+		if strings.Contains(out, "hunter2") {
+			t.Fatalf("%s = %q; leaks the secret", name, out)
+		}
+		if !strings.Contains(out, Placeholder) {
+			t.Fatalf("%s = %q; want %s", name, out, Placeholder)
+		}
+	}
+
+	var decoded databaseConfig
+	if err := json.Unmarshal([]byte(`{"Connection":"`+pw+`"}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if decoded.Connection.Reveal() != pw {
+		t.Fatalf("Reveal = %q; want %q", decoded.Connection.Reveal(), pw)
+	}
+}
+
+func TestDefaultDetectors(t *testing.T) {
+	r := Default()
+	tests := []struct {
+		in, want string
+	}{
+		{"connect to postgres://app:hunter2@db:5432/users: refused",
+			"connect to postgres://app:[REDACTED]@db:5432/users: refused"},
+		{"dial redis://:s3cret@cache:6379 failed", "dial redis://:[REDACTED]@cache:6379 failed"},
+		{"postgres://localhost:5432", "postgres://localhost:5432"},
+		{"https://user@example.com/x", "https://user@example.com/x"},
+		{"login failed: user=bob password=hunter2 retry=3", "login failed: user=bob password=[REDACTED] retry=3"},
+		{`{"user":"bob","db_password": "hunter2"}`, `{"user":"bob","db_password": "[REDACTED]"}`},
+		{"GET /x?api_key=abc123&page=2", "GET /x?api_key=[REDACTED]&page=2"},
+		{"header Authorization: Bearer eyJhbGciOi.J9.x", "header Authorization: Bearer [REDACTED]"},
+		{"load secret: permission denied", "load secret: permission denied"},
+		{"no secrets here", "no secrets here"},
+	}
+	for _, tt := range tests {
+		if got := r.String(tt.in); got != tt.want {
+			t.Fatalf("String(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+		if got := r.String(tt.want); got != tt.want {
+			t.Fatalf("String is not idempotent: %q -> %q", tt.want, got)
+		}
+	}
+}
+
+func TestCustomDetectors(t *testing.T) {
+	r := New(
+		NewPattern(`\b\d{4}-\d{4}-\d{4}-\d{4}\b`),
+		NewPattern(`(?i)x-internal-key (?P<secret>\S+)`),
+		NewKeywords("PIN"),
+	)
+	in := "card 4111-1111-1111-1111 pin=1234 X-Internal-Key abc"
+	want := "card [REDACTED] pin=[REDACTED] X-Internal-Key [REDACTED]"
+	if got := r.String(in); got != want {
+		t.Fatalf("String = %q; want %q", got, want)
+	}
+	if !r.SensitiveKey("user_pin") || r.SensitiveKey("password") {
+		t.Fatalf("SensitiveKey should follow the configured keywords only")
+	}
+
+	var nilRedactor *Redactor
+	if nilRedactor.String(in) != in || nilRedactor.SensitiveKey("pin") {
+		t.Fatalf("nil Redactor redacted something")
+	}
+}
+
+func TestErrorKeepsChain(t *testing.T) {
+	r := Default()
+	base := fmt.Errorf("connect to postgres://app:hunter2@db/users: %w", fs.ErrPermission)
+
+	err := r.Error(fmt.Errorf("startup: %w", base))
+	if strings.Contains(err.Error(), "hunter2") || !strings.Contains(err.Error(), "app:[REDACTED]@db") {
+		t.Fatalf("Error() = %q; want the password redacted", err)
+	}
+	if !errors.Is(err, fs.ErrPermission) || !errors.Is(err, base) {
+		t.Fatalf("redacted error lost its chain")
+	}
+
+	clean := errors.New("timeout")
+	if r.Error(clean) != clean || r.Error(nil) != nil {
+		t.Fatalf("Error should return clean errors unchanged")
+	}
+}
@@ -0,0 +1,34 @@
+package redact
+
+import "io"
+
+// ==========================================================
+// 5. WRITER
+// ==========================================================
+
+// Writer redacts everything written through it. It is meant for the
+// standard log package, which writes one whole line per Write, so a
+// secret is never split across calls:
+//
+//	log.SetOutput(redact.NewWriter(os.Stderr, redact.Default()))
+//
+// Writes from other sources may split a secret in two and leak it.
+type Writer struct {
+	w io.Writer
+	r *Redactor
+}
+
+// NewWriter returns a Writer that redacts with r and writes to w.
+func NewWriter(w io.Writer, r *Redactor) *Writer {
+	return &Writer{w: w, r: r}
+}
+
+// Write writes the redacted form of p. It reports len(p) on success,
+// as the io.Writer contract requires, even though the bytes written
+// may differ.
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
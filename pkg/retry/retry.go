@@ -0,0 +1,292 @@
+// Package retry is the reusable version of the retry loops in
+// 07-error-handling/05-retry-backoff-patterns.
+//
+// boundedRetry, retryWithBackoff and retryWithJitter are the same loop
+// with different knobs:
+//
+//	for attempt := 1; attempt <= maxAttempts; attempt++ {
+//		err := op()
+//		if err == nil || permanent(err) { return err }
+//		wait(backoff(attempt)) or ctx.Done()
+//	}
+//
+// Retry is that loop once, with every knob in a Policy: the backoff
+// strategy, attempt and elapsed-time caps, which errors are retryable,
+// a hook per attempt, and the clock it sleeps on.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go-systems-learning/pkg/backoff"
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. ERROR CLASSIFICATION
+// ==========================================================
+
+// ErrExhausted is matched by the error Retry returns when it gives up
+// because of MaxAttempts or MaxElapsedTime.
+var ErrExhausted = errors.New("retry: retry limit exceeded")
+
+// ExhaustedError reports the attempts made and wraps the last error.
+type ExhaustedError struct {
+	Attempts int
+	Elapsed  time.Duration
+	Last     error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("retry: retry limit exceeded after %d attempts (%v): %v", e.Attempts, e.Elapsed, e.Last)
+}
+
+func (e *ExhaustedError) Is(target error) bool { return target == ErrExhausted }
+func (e *ExhaustedError) Unwrap() error        { return e.Last }
+
+// ErrBudgetExhausted is matched by the error Retry returns when
+// Policy.Budget denies a retry.
+var ErrBudgetExhausted = errors.New("retry: retry budget exhausted")
+
+// permanentError marks an error as not worth retrying.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the default classifier does not retry it. The
+// lesson's ErrPermanentFailure plays this role; Permanent works for any
+// error without a package-level sentinel.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or anything it wraps, was marked by
+// Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Classifier reports whether an error is worth another attempt.
+type Classifier func(err error) bool
+
+// DefaultRetryable retries every error except those marked Permanent.
+func DefaultRetryable(err error) bool {
+	return !IsPermanent(err)
+}
+
+// ==========================================================
+// 2. BACKOFF STRATEGIES
+// ==========================================================
+
+// Backoff computes the wait before a retry.
+//
+// attempt is the attempt that just failed (1-based), prev is the delay
+// used before it (zero after the first attempt) and r is uniform in
+// [0, 1).
+type Backoff interface {
+	Delay(attempt int, prev time.Duration, r float64) time.Duration
+}
+
+// BackoffFunc adapts a function to Backoff.
+type BackoffFunc func(attempt int, prev time.Duration, r float64) time.Duration
+
+func (f BackoffFunc) Delay(attempt int, prev time.Duration, r float64) time.Duration {
+	return f(attempt, prev, r)
+}
+
+// Constant waits d between every attempt.
+func Constant(d time.Duration) Backoff {
+	return BackoffFunc(func(int, time.Duration, float64) time.Duration { return d })
+}
+
+// Exponential waits base, 2*base, 4*base, ... up to max, like
+// retryWithBackoff.
+func Exponential(base, max time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration, _ float64) time.Duration {
+		return backoff.Exponential(base, attempt, max)
+	})
+}
+
+// FullJitter waits a random time in [0, Exponential).
+func FullJitter(base, max time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration, r float64) time.Duration {
+		return backoff.FullJitter(base, attempt, r, max)
+	})
+}
+
+// EqualJitter waits half the exponential delay plus a random share of
+// the other half.
+func EqualJitter(base, max time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration, r float64) time.Duration {
+		return backoff.EqualJitter(base, attempt, r, max)
+	})
+}
+
+// DecorrelatedJitter waits a random time in [base, 3*previous delay).
+func DecorrelatedJitter(base, max time.Duration) Backoff {
+	return BackoffFunc(func(_ int, prev time.Duration, r float64) time.Duration {
+		return backoff.Decorrelated(base, prev, r, max)
+	})
+}
+
+// ==========================================================
+// 3. POLICY
+// ==========================================================
+
+// Budget caps retries across every caller sharing it, so an outage
+// does not multiply load by MaxAttempts (see pkg/throttle).
+type Budget interface {
+	// AllowRetry reports whether one more retry may be sent, and
+	// counts it if so.
+	AllowRetry() bool
+
+	// RecordSuccess counts a successful call.
+	RecordSuccess()
+}
+
+// DefaultMaxAttempts is used when Policy.MaxAttempts is zero.
+const DefaultMaxAttempts = 5
+
+// Attempt describes one finished attempt, for Policy.OnAttempt.
+type Attempt struct {
+	// Number is 1-based.
+	Number int
+	Err    error
+
+	// Elapsed is the time since Retry was called.
+	Elapsed time.Duration
+
+	// Delay is the wait before the next attempt, or zero when Retry is
+	// about to return.
+	Delay time.Duration
+}
+
+// Policy configures Retry. The zero value retries every non-permanent
+// error up to DefaultMaxAttempts times with the lesson's exponential
+// backoff from 100ms.
+type Policy struct {
+	// MaxAttempts caps the number of calls to op, including the first.
+	// A negative value means no cap (use MaxElapsedTime or ctx).
+	MaxAttempts int
+
+	// MaxElapsedTime stops retrying once the next wait would end past
+	// this much time since Retry started. Zero means no cap.
+	MaxElapsedTime time.Duration
+
+	// Backoff defaults to Exponential(100ms, 10s).
+	Backoff Backoff
+
+	// Retryable defaults to DefaultRetryable.
+	Retryable Classifier
+
+	// Budget, if set, is asked before every retry (never before the
+	// first attempt) and told about every success.
+	Budget Budget
+
+	// OnAttempt, if set, is called after every attempt, successful or
+	// not, before any wait.
+	OnAttempt func(Attempt)
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+
+	// Rand feeds jittered strategies. Defaults to rand.Float64.
+	Rand func() float64
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.Backoff == nil {
+		p.Backoff = Exponential(100*time.Millisecond, 10*time.Second)
+	}
+	if p.Retryable == nil {
+		p.Retryable = DefaultRetryable
+	}
+	if p.Clock == nil {
+		p.Clock = clock.RealClock{}
+	}
+	if p.Rand == nil {
+		p.Rand = rand.Float64
+	}
+	return p
+}
+
+// ==========================================================
+// 4. RETRY
+// ==========================================================
+
+// Retry calls op until it succeeds, returns a non-retryable error, the
+// policy's limits are reached or ctx is done.
+//
+// A non-retryable error is returned unchanged. Hitting a limit returns
+// an *ExhaustedError wrapping the last error, and a denied retry an
+// error matching ErrBudgetExhausted. If ctx ends while waiting, the
+// result wraps both ctx.Err() and the last error.
+func Retry(ctx context.Context, op func(ctx context.Context) error, p Policy) error {
+	p = p.withDefaults()
+	start := p.Clock.Now()
+	var delay time.Duration
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := op(ctx)
+		info := Attempt{Number: attempt, Err: err, Elapsed: p.Clock.Since(start)}
+
+		if err == nil && p.Budget != nil {
+			p.Budget.RecordSuccess()
+		}
+		if err == nil || !p.Retryable(err) {
+			p.notify(info)
+			return err
+		}
+
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			p.notify(info)
+			return &ExhaustedError{Attempts: attempt, Elapsed: info.Elapsed, Last: err}
+		}
+
+		delay = p.Backoff.Delay(attempt, delay, p.Rand())
+		if p.MaxElapsedTime > 0 && info.Elapsed+delay > p.MaxElapsedTime {
+			p.notify(info)
+			return &ExhaustedError{Attempts: attempt, Elapsed: info.Elapsed, Last: err}
+		}
+
+		if p.Budget != nil && !p.Budget.AllowRetry() {
+			p.notify(info)
+			return fmt.Errorf("%w after %d attempts: %w", ErrBudgetExhausted, attempt, err)
+		}
+
+		info.Delay = delay
+		p.notify(info)
+
+		timer := p.Clock.NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry: %w after %d attempts: %w", ctx.Err(), attempt, err)
+		}
+	}
+}
+
+func (p Policy) notify(a Attempt) {
+	if p.OnAttempt != nil {
+		p.OnAttempt(a)
+	}
+}
@@ -0,0 +1,237 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var (
+	epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	errTemporary = errors.New("temporary failure")
+	errFatal     = errors.New("fatal failure")
+)
+
+// failTimes returns an op that fails n times with err and then succeeds.
+func failTimes(n int, err error) (op func(context.Context) error, calls *int) {
+	calls = new(int)
+	return func(context.Context) error {
+		*calls++
+		if *calls <= n {
+			return err
+		}
+		return nil
+	}, calls
+}
+
+func waitForWaiters(t *testing.T, c *clock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a clock waiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRetry_BacksOffUntilSuccess(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	op, calls := failTimes(3, errTemporary)
+
+	var delays []time.Duration
+	policy := Policy{
+		Backoff:   Exponential(100*time.Millisecond, 0),
+		Clock:     fake,
+		OnAttempt: func(a Attempt) { delays = append(delays, a.Delay) },
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- Retry(context.Background(), op, policy) }()
+
+	for _, d := range []time.Duration{100, 200, 400} {
+		waitForWaiters(t, fake)
+		fake.Step(d * time.Millisecond)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Retry returned %v", err)
+	}
+	if *calls != 4 {
+		t.Fatalf("op called %d times; want 4", *calls)
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 0}
+	if !slices.Equal(delays, want) {
+		t.Fatalf("delays = %v; want %v", delays, want)
+	}
+}
+
+func TestRetry_StopsOnNonRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable Classifier
+	}{
+		{"marked permanent", Permanent(errFatal), nil},
+		{"custom classifier", errFatal, func(err error) bool { return errors.Is(err, errTemporary) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, calls := failTimes(10, tt.err)
+			err := Retry(context.Background(), op, Policy{Backoff: Constant(0), Retryable: tt.retryable})
+			if !errors.Is(err, errFatal) {
+				t.Fatalf("Retry = %v; want %v", err, errFatal)
+			}
+			if *calls != 1 {
+				t.Fatalf("op called %d times; want 1", *calls)
+			}
+		})
+	}
+}
+
+func TestRetry_MaxAttempts(t *testing.T) {
+	op, calls := failTimes(10, errTemporary)
+	err := Retry(context.Background(), op, Policy{MaxAttempts: 3, Backoff: Constant(0)})
+
+	var exhausted *ExhaustedError
+	if !errors.As(err, &exhausted) || !errors.Is(err, ErrExhausted) {
+		t.Fatalf("Retry = %v; want *ExhaustedError", err)
+	}
+	if !errors.Is(err, errTemporary) {
+		t.Fatalf("Retry = %v; want it to wrap %v", err, errTemporary)
+	}
+	if exhausted.Attempts != 3 || *calls != 3 {
+		t.Fatalf("Attempts = %d, calls = %d; want 3 and 3", exhausted.Attempts, *calls)
+	}
+}
+
+func TestRetry_MaxElapsedTime(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	op, calls := failTimes(10, errTemporary)
+	policy := Policy{
+		MaxAttempts:    -1,
+		MaxElapsedTime: time.Second,
+		Backoff:        Constant(400 * time.Millisecond),
+		Clock:          fake,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- Retry(context.Background(), op, policy) }()
+
+	// Waits end at 400ms and 800ms; a third would end at 1.2s.
+	for i := 0; i < 2; i++ {
+		waitForWaiters(t, fake)
+		fake.Step(400 * time.Millisecond)
+	}
+
+	if err := <-done; !errors.Is(err, ErrExhausted) {
+		t.Fatalf("Retry = %v; want %v", err, ErrExhausted)
+	}
+	if *calls != 3 {
+		t.Fatalf("op called %d times; want 3", *calls)
+	}
+}
+
+func TestRetry_ContextCancelledWhileWaiting(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	op, _ := failTimes(10, errTemporary)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- Retry(ctx, op, Policy{Backoff: Constant(time.Hour), Clock: fake}) }()
+
+	waitForWaiters(t, fake)
+	cancel()
+
+	err := <-done
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errTemporary) {
+		t.Fatalf("Retry = %v; want it to wrap %v and %v", err, context.Canceled, errTemporary)
+	}
+}
+
+// countingBudget allows a fixed number of retries and counts calls.
+type countingBudget struct {
+	retries   int // remaining
+	asked     int
+	successes int
+}
+
+func (b *countingBudget) AllowRetry() bool {
+	b.asked++
+	if b.retries == 0 {
+		return false
+	}
+	b.retries--
+	return true
+}
+
+func (b *countingBudget) RecordSuccess() { b.successes++ }
+
+func TestRetry_Budget(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int
+		retries       int
+		wantCalls     int
+		wantAsked     int
+		wantSuccesses int
+		wantDenied    bool
+	}{
+		{"first attempt succeeds", 0, 0, 1, 0, 1, false},
+		{"retries within budget", 2, 2, 3, 2, 1, false},
+		{"denied retry stops", 10, 1, 2, 2, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := &countingBudget{retries: tt.retries}
+			op, calls := failTimes(tt.failures, errTemporary)
+			err := Retry(context.Background(), op, Policy{Backoff: Constant(0), Budget: budget})
+
+			if tt.wantDenied {
+				if !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, errTemporary) {
+					t.Fatalf("Retry = %v; want it to wrap %v and %v", err, ErrBudgetExhausted, errTemporary)
+				}
+			} else if err != nil {
+				t.Fatalf("Retry returned %v", err)
+			}
+			if *calls != tt.wantCalls || budget.asked != tt.wantAsked || budget.successes != tt.wantSuccesses {
+				t.Fatalf("calls, asked, successes = %d, %d, %d; want %d, %d, %d",
+					*calls, budget.asked, budget.successes, tt.wantCalls, tt.wantAsked, tt.wantSuccesses)
+			}
+		})
+	}
+}
+
+func TestBackoffStrategies(t *testing.T) {
+	base, max := 100*time.Millisecond, time.Second
+
+	tests := []struct {
+		name    string
+		backoff Backoff
+		attempt int
+		prev    time.Duration
+		r       float64
+		want    time.Duration
+	}{
+		{"constant", Constant(time.Second), 7, 0, 0.9, time.Second},
+		{"exponential", Exponential(base, max), 3, 0, 0.9, 400 * time.Millisecond},
+		{"exponential capped", Exponential(base, max), 10, 0, 0, max},
+		{"full jitter", FullJitter(base, max), 3, 0, 0.25, 100 * time.Millisecond},
+		{"equal jitter", EqualJitter(base, max), 3, 0, 0, 200 * time.Millisecond},
+		{"decorrelated from prev", DecorrelatedJitter(base, max), 5, 200 * time.Millisecond, 0.5, 350 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backoff.Delay(tt.attempt, tt.prev, tt.r); got != tt.want {
+				t.Fatalf("Delay(%d, %v, %v) = %v; want %v", tt.attempt, tt.prev, tt.r, got, tt.want)
+			}
+		})
+	}
+}
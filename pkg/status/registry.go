@@ -0,0 +1,280 @@
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go-systems-learning/pkg/field"
+)
+
+// ==========================================================
+// 3. BUILT-IN SENTINELS
+// ==========================================================
+
+// Sentinels for the common reasons. Every Registry knows them; code
+// with its own sentinels (such as a lesson's ErrNotFound) registers
+// those alongside.
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("not found")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrConflict           = errors.New("conflict")
+	ErrInvalid            = errors.New("invalid")
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrServiceUnavailable = errors.New("service unavailable")
+	ErrTimeout            = errors.New("timeout")
+)
+
+// ==========================================================
+// 4. REGISTRY
+// ==========================================================
+
+// Converter turns an error it recognizes into a Status. ok is false
+// for errors it does not handle.
+type Converter func(err error) (s Status, ok bool)
+
+type sentinel struct {
+	err    error
+	reason Reason
+	code   int
+}
+
+// Registry maps errors to Statuses and back. It is safe for concurrent
+// use; register everything at startup.
+type Registry struct {
+	mu         sync.RWMutex
+	converters []Converter
+	sentinels  []sentinel
+	redact     func(string) string
+}
+
+// NewRegistry returns a Registry that knows the built-in sentinels and
+// converts pkg/field validation errors to 422 Invalid with causes.
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.Register(ErrBadRequest, ReasonBadRequest, http.StatusBadRequest)
+	r.Register(ErrUnauthorized, ReasonUnauthorized, http.StatusUnauthorized)
+	r.Register(ErrForbidden, ReasonForbidden, http.StatusForbidden)
+	r.Register(ErrNotFound, ReasonNotFound, http.StatusNotFound)
+	r.Register(ErrAlreadyExists, ReasonAlreadyExists, http.StatusConflict)
+	r.Register(ErrConflict, ReasonConflict, http.StatusConflict)
+	r.Register(ErrInvalid, ReasonInvalid, http.StatusUnprocessableEntity)
+	r.Register(ErrTooManyRequests, ReasonTooManyRequests, http.StatusTooManyRequests)
+	r.Register(ErrServiceUnavailable, ReasonServiceUnavailable, http.StatusServiceUnavailable)
+	r.Register(ErrTimeout, ReasonTimeout, http.StatusGatewayTimeout)
+	r.RegisterConverter(fieldErrors)
+	return r
+}
+
+// Register maps a sentinel to a reason and HTTP code, in both
+// directions: errors matching it encode with that reason, and decoded
+// errors with that reason match it. Several sentinels may share a
+// reason; the first registered that matches an error wins.
+func (r *Registry) Register(err error, reason Reason, code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sentinels = append(r.sentinels, sentinel{err: err, reason: reason, code: code})
+}
+
+// RegisterConverter adds a converter for typed errors. Converters run
+// before sentinels, most recently registered first, so a specific
+// converter can override a general one.
+func (r *Registry) RegisterConverter(c Converter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.converters = append([]Converter{c}, r.converters...)
+}
+
+// SetRedact sets a function applied to every message FromError
+// produces, including cause messages, so secrets in an error never
+// reach a client. redact.Default().String is the usual choice.
+func (r *Registry) SetRedact(fn func(string) string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redact = fn
+}
+
+// FromError returns the Status for err. A *StatusError anywhere in the
+// chain is passed through as is, so a proxy forwards what it received.
+// Unrecognized errors become 500 InternalError, and so does a nil err,
+// which is a bug in the caller rather than a success. A Status with no
+// valid HTTP code gets one from its Reason.
+func (r *Registry) FromError(err error) Status {
+	if err == nil {
+		return Status{
+			Kind:    "Status",
+			Status:  "Failure",
+			Code:    http.StatusInternalServerError,
+			Reason:  ReasonInternalError,
+			Message: "status: nil error",
+		}
+	}
+
+	s := r.fromError(err)
+	s.Kind, s.Status = "Status", "Failure"
+	if s.Code < 100 || s.Code > 999 {
+		s.Code = codeForReason(s.Reason)
+	}
+	if s.Message == "" {
+		s.Message = err.Error()
+	}
+	return r.redacted(s)
+}
+
+// redacted returns s with its messages redacted. Details are copied,
+// since s may share them with a StatusError.
+func (r *Registry) redacted(s Status) Status {
+	r.mu.RLock()
+	fn := r.redact
+	r.mu.RUnlock()
+	if fn == nil {
+		return s
+	}
+
+	s.Message = fn(s.Message)
+	if s.Details != nil && len(s.Details.Causes) > 0 {
+		d := *s.Details
+		d.Causes = make([]Cause, len(s.Details.Causes))
+		for i, c := range s.Details.Causes {
+			c.Message = fn(c.Message)
+			d.Causes[i] = c
+		}
+		s.Details = &d
+	}
+	return s
+}
+
+func (r *Registry) fromError(err error) Status {
+	if se, ok := asStatusError(err); ok {
+		return se.Status
+	}
+
+	// Converters and errors.Is may call back into the registry, so
+	// they run unlocked, on a snapshot. Both slices only ever grow by
+	// append or are replaced whole, so the snapshot stays valid.
+	r.mu.RLock()
+	converters, sentinels := r.converters, r.sentinels
+	r.mu.RUnlock()
+
+	for _, c := range converters {
+		if s, ok := c(err); ok {
+			return s
+		}
+	}
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			return Status{Code: s.code, Reason: s.reason}
+		}
+	}
+	return Status{Code: http.StatusInternalServerError, Reason: ReasonInternalError}
+}
+
+// ToError returns s as a *StatusError that matches this registry's
+// sentinels for s.Reason.
+func (r *Registry) ToError(s Status) error {
+	return &StatusError{Status: s, reg: r}
+}
+
+func (r *Registry) matches(reason Reason, target error) bool {
+	if reason == ReasonUnknown {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.sentinels {
+		if s.reason == reason && s.err == target {
+			return true
+		}
+	}
+	return false
+}
+
+func asStatusError(err error) (*StatusError, bool) {
+	var se *StatusError
+	ok := errors.As(err, &se)
+	return se, ok
+}
+
+// ==========================================================
+// 5. HTTP
+// ==========================================================
+
+const contentType = "application/json"
+
+// WriteError writes err's Status as JSON with the matching HTTP code.
+func (r *Registry) WriteError(w http.ResponseWriter, err error) {
+	s := r.FromError(err)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(s.Code)
+	json.NewEncoder(w).Encode(s)
+}
+
+// FromResponse returns nil for a 2xx response and a *StatusError
+// otherwise. It reads but does not close the body. A body that is not
+// a Status still yields a StatusError, with the body as the message.
+// Either way, a missing reason is guessed from the code.
+func (r *Registry) FromResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	var s Status
+	if err := json.Unmarshal(body, &s); err != nil || s.Kind != "Status" {
+		s = Status{
+			Kind:    "Status",
+			Status:  "Failure",
+			Message: strings.TrimSpace(string(body)),
+		}
+	}
+	s.Code = resp.StatusCode
+	if s.Reason == ReasonUnknown {
+		s.Reason = reasonForCode(resp.StatusCode)
+	}
+	return r.ToError(s)
+}
+
+// ==========================================================
+// 6. FIELD ERRORS
+// ==========================================================
+
+// fieldErrors converts every *field.Error in err, including inside an
+// aggregate, into one Invalid status with a cause per field.
+func fieldErrors(err error) (Status, bool) {
+	var causes []Cause
+	var walk func(error)
+	walk = func(err error) {
+		if fe, ok := err.(*field.Error); ok {
+			causes = append(causes, Cause{Type: string(fe.Type), Message: fe.ErrorBody(), Field: fe.Field})
+			return
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				walk(e)
+			}
+		case interface{ Unwrap() error }:
+			if e := u.Unwrap(); e != nil {
+				walk(e)
+			}
+		}
+	}
+	walk(err)
+
+	if len(causes) == 0 {
+		return Status{}, false
+	}
+	return Status{
+		Code:    http.StatusUnprocessableEntity,
+		Reason:  ReasonInvalid,
+		Details: &Details{Causes: causes},
+	}, true
+}
@@ -0,0 +1,170 @@
+// Package status turns errors into machine-readable API responses and
+// back, in the spirit of Kubernetes' metav1.Status.
+//
+// A handler that writes err.Error() into the response body throws away
+// everything the lessons in 07-error-handling teach: the client gets a
+// string, and errors.Is(err, ErrNotFound) is false on the other side.
+// Instead, a Registry maps sentinel and typed errors to a Status:
+//
+//	{
+//	  "kind": "Status",
+//	  "status": "Failure",
+//	  "code": 404,
+//	  "reason": "NotFound",
+//	  "message": "get user 7: resource not found"
+//	}
+//
+// and the client decodes that into a *StatusError. The error matches
+// every sentinel registered for its reason, so
+//
+//	err := reg.FromResponse(resp)
+//	errors.Is(err, ErrNotFound) // true, after a trip over HTTP
+//
+// Field validation errors (pkg/field) travel as Details.Causes, and
+// SetRedact scrubs secrets from every message before it is sent.
+package status
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ==========================================================
+// 1. STATUS
+// ==========================================================
+
+// Reason is a machine-readable category, stable across versions.
+type Reason string
+
+const (
+	ReasonUnknown            Reason = ""
+	ReasonBadRequest         Reason = "BadRequest"
+	ReasonUnauthorized       Reason = "Unauthorized"
+	ReasonForbidden          Reason = "Forbidden"
+	ReasonNotFound           Reason = "NotFound"
+	ReasonAlreadyExists      Reason = "AlreadyExists"
+	ReasonConflict           Reason = "Conflict"
+	ReasonInvalid            Reason = "Invalid"
+	ReasonTooManyRequests    Reason = "TooManyRequests"
+	ReasonInternalError      Reason = "InternalError"
+	ReasonServiceUnavailable Reason = "ServiceUnavailable"
+	ReasonTimeout            Reason = "Timeout"
+)
+
+// reasonForCode is the fallback when a response has no Status body.
+func reasonForCode(code int) Reason {
+	switch code {
+	case http.StatusBadRequest:
+		return ReasonBadRequest
+	case http.StatusUnauthorized:
+		return ReasonUnauthorized
+	case http.StatusForbidden:
+		return ReasonForbidden
+	case http.StatusNotFound:
+		return ReasonNotFound
+	case http.StatusConflict:
+		return ReasonConflict
+	case http.StatusUnprocessableEntity:
+		return ReasonInvalid
+	case http.StatusTooManyRequests:
+		return ReasonTooManyRequests
+	case http.StatusServiceUnavailable:
+		return ReasonServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return ReasonTimeout
+	}
+	if code >= 500 {
+		return ReasonInternalError
+	}
+	return ReasonUnknown
+}
+
+// codeForReason is the fallback when a Status has no valid code.
+func codeForReason(reason Reason) int {
+	switch reason {
+	case ReasonBadRequest:
+		return http.StatusBadRequest
+	case ReasonUnauthorized:
+		return http.StatusUnauthorized
+	case ReasonForbidden:
+		return http.StatusForbidden
+	case ReasonNotFound:
+		return http.StatusNotFound
+	case ReasonAlreadyExists, ReasonConflict:
+		return http.StatusConflict
+	case ReasonInvalid:
+		return http.StatusUnprocessableEntity
+	case ReasonTooManyRequests:
+		return http.StatusTooManyRequests
+	case ReasonServiceUnavailable:
+		return http.StatusServiceUnavailable
+	case ReasonTimeout:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// Cause is one reason a request failed, usually one invalid field.
+type Cause struct {
+	Type    string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+// Details carries extra, reason-specific data.
+type Details struct {
+	// Name and Kind identify the resource involved, if any.
+	Name string `json:"name,omitempty"`
+	Kind string `json:"kind,omitempty"`
+
+	Causes []Cause `json:"causes,omitempty"`
+
+	// RetryAfterSeconds suggests when to try again.
+	RetryAfterSeconds int `json:"retryAfterSeconds,omitempty"`
+}
+
+// Status is the wire form of a failed request.
+type Status struct {
+	Kind    string   `json:"kind"`   // always "Status"
+	Status  string   `json:"status"` // always "Failure"
+	Code    int      `json:"code"`
+	Reason  Reason   `json:"reason,omitempty"`
+	Message string   `json:"message,omitempty"`
+	Details *Details `json:"details,omitempty"`
+}
+
+// ==========================================================
+// 2. STATUS ERROR
+// ==========================================================
+
+// StatusError is a Status received from, or headed for, the wire. It
+// matches, via errors.Is, every sentinel its Registry has for its
+// Reason.
+type StatusError struct {
+	Status Status
+
+	reg *Registry
+}
+
+func (e *StatusError) Error() string {
+	if e.Status.Message != "" {
+		return e.Status.Message
+	}
+	return fmt.Sprintf("status %d %s", e.Status.Code, e.Status.Reason)
+}
+
+func (e *StatusError) Is(target error) bool {
+	if e.reg == nil {
+		return false
+	}
+	return e.reg.matches(e.Status.Reason, target)
+}
+
+// ReasonOf returns the Reason of a *StatusError in err's chain, or
+// ReasonUnknown.
+func ReasonOf(err error) Reason {
+	if se, ok := asStatusError(err); ok {
+		return se.Status.Reason
+	}
+	return ReasonUnknown
+}
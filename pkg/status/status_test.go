@@ -0,0 +1,299 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/field"
+	"go-systems-learning/pkg/redact"
+)
+
+// The lessons' own errors, as an application would have them.
+var (
+	errUserNotFound     = errors.New("resource not found")
+	errPermissionDenied = errors.New("permission denied")
+)
+
+type validationError struct {
+	Field string
+	Rule  string
+}
+
+func (e *validationError) Error() string {
+	return fmt.Sprintf("validation failed: %s (%s)", e.Field, e.Rule)
+}
+
+func newRegistry() *Registry {
+	reg := NewRegistry()
+	reg.Register(errUserNotFound, ReasonNotFound, http.StatusNotFound)
+	reg.Register(errPermissionDenied, ReasonForbidden, http.StatusForbidden)
+	reg.RegisterConverter(func(err error) (Status, bool) {
+		var ve *validationError
+		if !errors.As(err, &ve) {
+			return Status{}, false
+		}
+		return Status{
+			Code:    http.StatusUnprocessableEntity,
+			Reason:  ReasonInvalid,
+			Details: &Details{Causes: []Cause{{Type: "FieldValueInvalid", Message: ve.Rule, Field: ve.Field}}},
+		}, true
+	})
+	return reg
+}
+
+// roundTrip serves err from an httptest server and decodes the response.
+func roundTrip(t *testing.T, reg *Registry, err error) error {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.WriteError(w, err)
+	}))
+	defer srv.Close()
+
+	resp, getErr := http.Get(srv.URL)
+	if getErr != nil {
+		t.Fatalf("GET failed: %v", getErr)
+	}
+	defer resp.Body.Close()
+	return reg.FromResponse(resp)
+}
+
+func TestRoundTrip(t *testing.T) {
+	reg := newRegistry()
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantReason Reason
+		wantIs     []error
+		wantNotIs  []error
+	}{
+		{"wrapped lesson sentinel", fmt.Errorf("get user 7: %w", errUserNotFound), 404, ReasonNotFound,
+			[]error{errUserNotFound, ErrNotFound}, []error{errPermissionDenied}},
+		{"permission denied", fmt.Errorf("load config: %w", errPermissionDenied), 403, ReasonForbidden,
+			[]error{errPermissionDenied, ErrForbidden}, []error{ErrNotFound}},
+		{"built-in sentinel", ErrConflict, 409, ReasonConflict, []error{ErrConflict}, []error{ErrAlreadyExists}},
+		{"typed error", &validationError{Field: "username", Rule: "cannot be empty"}, 422, ReasonInvalid,
+			[]error{ErrInvalid}, nil},
+		{"unknown error", errors.New("disk on fire"), 500, ReasonInternalError, nil, []error{ErrNotFound}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := roundTrip(t, reg, tt.err)
+
+			var se *StatusError
+			if !errors.As(err, &se) {
+				t.Fatalf("FromResponse = %v; want *StatusError", err)
+			}
+			if se.Status.Code != tt.wantCode || se.Status.Reason != tt.wantReason {
+				t.Fatalf("status = %d %s; want %d %s", se.Status.Code, se.Status.Reason, tt.wantCode, tt.wantReason)
+			}
+			if err.Error() != tt.err.Error() {
+				t.Fatalf("message = %q; want %q", err.Error(), tt.err.Error())
+			}
+			for _, target := range tt.wantIs {
+				if !errors.Is(err, target) {
+					t.Fatalf("errors.Is(err, %v) = false after the round trip", target)
+				}
+			}
+			for _, target := range tt.wantNotIs {
+				if errors.Is(err, target) {
+					t.Fatalf("errors.Is(err, %v) = true; want false", target)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldErrorsBecomeCauses(t *testing.T) {
+	reg := NewRegistry()
+	containers := field.NewPath("spec", "containers")
+	errs := field.ErrorList{
+		field.Required(containers.Index(2).Child("image"), ""),
+		field.NotSupported(field.NewPath("spec", "restartPolicy"), "Sometimes", []string{"Always"}),
+	}
+
+	err := roundTrip(t, reg, fmt.Errorf("create pod: %w", errs.ToAggregate()))
+
+	var se *StatusError
+	if !errors.As(err, &se) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("FromResponse = %v; want an Invalid *StatusError", err)
+	}
+	causes := se.Status.Details.Causes
+	if len(causes) != 2 || causes[0].Field != "spec.containers[2].image" || causes[0].Type != string(field.ErrorTypeRequired) {
+		t.Fatalf("causes = %+v; want one per field error", causes)
+	}
+}
+
+func TestFromResponse_NonStatusBody(t *testing.T) {
+	reg := NewRegistry()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	err = reg.FromResponse(resp)
+	if !errors.Is(err, ErrServiceUnavailable) || err.Error() != "upstream overloaded" {
+		t.Fatalf("FromResponse = %q; want ServiceUnavailable with the body as message", err)
+	}
+	if ReasonOf(err) != ReasonServiceUnavailable {
+		t.Fatalf("ReasonOf = %q; want %q", ReasonOf(err), ReasonServiceUnavailable)
+	}
+}
+
+func TestFromError_PassesStatusErrorsThrough(t *testing.T) {
+	reg := NewRegistry()
+	upstream := reg.ToError(Status{Code: 429, Reason: ReasonTooManyRequests, Message: "slow down",
+		Details: &Details{RetryAfterSeconds: 3}})
+
+	s := reg.FromError(fmt.Errorf("calling billing: %w", upstream))
+	if s.Code != 429 || s.Reason != ReasonTooManyRequests || s.Details.RetryAfterSeconds != 3 {
+		t.Fatalf("FromError = %+v; want the upstream status unchanged", s)
+	}
+	if s.Kind != "Status" || s.Status != "Failure" {
+		t.Fatalf("FromError = %+v; want kind Status, status Failure", s)
+	}
+}
+
+func TestRedactedMessages(t *testing.T) {
+	reg := newRegistry()
+	reg.SetRedact(redact.Default().String)
+
+	conn := "postgres://app:hunter2@db:5432/users"
+	errs := field.ErrorList{field.Invalid(field.NewPath("spec", "connection"), conn, "host unreachable")}
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"message", fmt.Errorf("connect to %s: %w", conn, errPermissionDenied)},
+		{"cause", errs.ToAggregate()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := roundTrip(t, reg, tt.err)
+
+			var se *StatusError
+			if !errors.As(err, &se) {
+				t.Fatalf("FromResponse = %v; want *StatusError", err)
+			}
+			body := fmt.Sprintf("%+v", se.Status)
+			if strings.Contains(body, "hunter2") {
+				t.Fatalf("status leaks the password: %s", body)
+			}
+			if !strings.Contains(body, "app:"+redact.Placeholder+"@db") {
+				t.Fatalf("status = %s; want the password redacted", body)
+			}
+		})
+	}
+
+	// Redaction applies to the encoded copy, not to a passed-through
+	// StatusError.
+	upstream := reg.ToError(Status{Code: 500, Message: "dial " + conn,
+		Details: &Details{Causes: []Cause{{Message: conn}}}}).(*StatusError)
+	reg.FromError(upstream)
+	if upstream.Status.Message != "dial "+conn || upstream.Status.Details.Causes[0].Message != conn {
+		t.Fatalf("FromError modified the StatusError it passed through")
+	}
+}
+
+func TestFromError_FillsMissingCode(t *testing.T) {
+	reg := NewRegistry()
+	errGone := errors.New("gone")
+	reg.RegisterConverter(func(err error) (Status, bool) {
+		return Status{Reason: ReasonNotFound}, errors.Is(err, errGone)
+	})
+	errWeird := errors.New("weird")
+	reg.RegisterConverter(func(err error) (Status, bool) {
+		return Status{Code: 42, Reason: "Weird"}, errors.Is(err, errWeird)
+	})
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errGone, http.StatusNotFound},
+		{errWeird, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if s := reg.FromError(tt.err); s.Code != tt.want {
+			t.Fatalf("FromError(%v).Code = %d; want %d", tt.err, s.Code, tt.want)
+		}
+		// WriteError must not panic on the converter's code.
+		if err := roundTrip(t, reg, tt.err); err.(*StatusError).Status.Code != tt.want {
+			t.Fatalf("round trip of %v = %d; want %d", tt.err, err.(*StatusError).Status.Code, tt.want)
+		}
+	}
+	if err := roundTrip(t, reg, errGone); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("round trip = %v; want it to match ErrNotFound", err)
+	}
+}
+
+func TestFromError_Nil(t *testing.T) {
+	reg := NewRegistry()
+	if s := reg.FromError(nil); s.Code != http.StatusInternalServerError || s.Reason != ReasonInternalError {
+		t.Fatalf("FromError(nil) = %+v; want 500 InternalError", s)
+	}
+	rec := httptest.NewRecorder()
+	reg.WriteError(rec, nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("WriteError(nil) wrote %d; want 500", rec.Code)
+	}
+}
+
+func TestFromResponse_StatusWithoutReason(t *testing.T) {
+	reg := NewRegistry()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"kind":"Status","status":"Failure","code":404,"message":"no such user"}`)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	err = reg.FromResponse(resp)
+	if !errors.Is(err, ErrNotFound) || err.Error() != "no such user" {
+		t.Fatalf("FromResponse = %q; want NotFound with the decoded message", err)
+	}
+}
+
+func TestFromError_ConverterMayUseRegistry(t *testing.T) {
+	reg := NewRegistry()
+	errLazy := errors.New("lazy")
+	reg.RegisterConverter(func(err error) (Status, bool) {
+		if !errors.Is(err, errLazy) {
+			return Status{}, false
+		}
+		// Registering from inside a converter used to deadlock on the
+		// registry's lock, as did delegating to FromError.
+		reg.Register(errLazy, ReasonConflict, http.StatusConflict)
+		reg.SetRedact(nil)
+		return reg.FromError(fmt.Errorf("wrapped: %w", ErrNotFound)), true
+	})
+
+	done := make(chan Status, 1)
+	go func() { done <- reg.FromError(errLazy) }()
+	select {
+	case s := <-done:
+		if s.Code != http.StatusNotFound {
+			t.Fatalf("FromError(errLazy).Code = %d; want %d", s.Code, http.StatusNotFound)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("FromError deadlocked on a converter that uses the registry")
+	}
+}
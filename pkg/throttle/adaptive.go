@@ -0,0 +1,180 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/internal/window"
+	"go-systems-learning/pkg/retry"
+)
+
+// ==========================================================
+// 2. ADAPTIVE THROTTLING
+// ==========================================================
+
+/*
+From "Handling Overload" in the Google SRE book. Each client tracks,
+over the last couple of minutes:
+
+	requests  – attempts made by the application
+	accepts   – attempts the backend actually accepted
+
+and rejects a new request LOCALLY with probability
+
+	max(0, (requests − K·accepts) / (requests + 1))
+
+While the backend is healthy, accepts ≈ requests and nothing is
+rejected. As it starts refusing work, clients back off on their own,
+without a round trip, and recover as soon as accepts climb again.
+Locally rejected requests still count as requests, which keeps the
+pressure on while the backend stays unhealthy. K = 2 lets clients send
+about twice what the backend accepts.
+*/
+
+// ErrThrottled is returned when a request is rejected locally.
+var ErrThrottled = errors.New("throttle: request rejected by adaptive throttling")
+
+// ThrottleConfig configures a Throttle.
+type ThrottleConfig struct {
+	// K is the multiplier on accepts. Lower is more aggressive.
+	// Defaults to 2.
+	K float64
+
+	// Window is how far back requests and accepts are counted.
+	// Defaults to 2m.
+	Window time.Duration
+
+	// IsAccepted decides whether the backend accepted a request that
+	// returned err. Defaults to: success, or a permanent error (the
+	// backend answered; it was the request that was wrong).
+	IsAccepted func(err error) bool
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+
+	// Rand defaults to rand.Float64.
+	Rand func() float64
+}
+
+func (c ThrottleConfig) withDefaults() ThrottleConfig {
+	if c.K <= 0 {
+		c.K = 2
+	}
+	if c.Window <= 0 {
+		c.Window = 2 * time.Minute
+	}
+	if c.IsAccepted == nil {
+		c.IsAccepted = func(err error) bool { return err == nil || retry.IsPermanent(err) }
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	if c.Rand == nil {
+		c.Rand = rand.Float64
+	}
+	return c
+}
+
+// ThrottleStats is a snapshot of a Throttle.
+type ThrottleStats struct {
+	// Requests and Accepts are the counts inside the current window.
+	Requests int
+	Accepts  int
+
+	// RejectProbability is the chance the next request is rejected.
+	RejectProbability float64
+
+	// Allowed and Rejected count every Allow call since creation.
+	Allowed  uint64
+	Rejected uint64
+}
+
+// Throttle is client-side adaptive throttling for one backend. It is
+// safe for concurrent use.
+type Throttle struct {
+	cfg ThrottleConfig
+
+	mu       sync.Mutex
+	requests *window.Counter
+	accepts  *window.Counter
+	allowed  uint64
+	rejected uint64
+}
+
+// NewThrottle returns a Throttle that rejects nothing until the
+// backend starts refusing requests.
+func NewThrottle(cfg ThrottleConfig) *Throttle {
+	cfg = cfg.withDefaults()
+	return &Throttle{
+		cfg:      cfg,
+		requests: window.New(cfg.Window, windowBuckets),
+		accepts:  window.New(cfg.Window, windowBuckets),
+	}
+}
+
+// Do runs fn unless the request is rejected locally, and records
+// whether the backend accepted it.
+func (t *Throttle) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := t.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	done(err)
+	return err
+}
+
+// Allow decides whether to send a request. If it returns nil, the
+// caller must send it and pass the outcome to done. Only the first
+// call to done counts.
+func (t *Throttle) Allow() (done func(err error), err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.cfg.Clock.Now()
+	p := t.rejectProbabilityLocked(now)
+	t.requests.Add(now, 1)
+
+	if t.cfg.Rand() < p {
+		t.rejected++
+		return nil, ErrThrottled
+	}
+	t.allowed++
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			if !t.cfg.IsAccepted(err) {
+				return
+			}
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.accepts.Add(t.cfg.Clock.Now(), 1)
+		})
+	}, nil
+}
+
+func (t *Throttle) rejectProbabilityLocked(now time.Time) float64 {
+	requests := t.requests.Sum(now)
+	accepts := t.accepts.Sum(now)
+	return max(0, (requests-t.cfg.K*accepts)/(requests+1))
+}
+
+// Stats returns the throttle's counters.
+func (t *Throttle) Stats() ThrottleStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.cfg.Clock.Now()
+	return ThrottleStats{
+		Requests:          int(t.requests.Sum(now)),
+		Accepts:           int(t.accepts.Sum(now)),
+		RejectProbability: t.rejectProbabilityLocked(now),
+		Allowed:           t.allowed,
+		Rejected:          t.rejected,
+	}
+}
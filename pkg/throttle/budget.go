@@ -0,0 +1,138 @@
+// Package throttle keeps retries and requests from piling onto a
+// struggling backend.
+//
+// retryWithJitter (07-error-handling/05-retry-backoff-patterns) spreads
+// one caller's retries out in time, but a hundred goroutines each
+// retrying 10 times still multiply load by up to 10x during an outage,
+// exactly when the backend can least afford it. Two process-wide
+// controls fix that:
+//
+//   - Budget: retries are allowed only while they stay under a
+//     percentage of recent successful requests (plus a small floor), so
+//     retries add at most, say, 10% load. Plug it into retry.Policy.
+//   - Throttle: the client-side adaptive throttling from the Google SRE
+//     book. Once the backend accepts too few requests, new requests are
+//     rejected locally with a probability that grows with the gap.
+package throttle
+
+import (
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/internal/window"
+)
+
+// ==========================================================
+// 1. RETRY BUDGET
+// ==========================================================
+
+// BudgetConfig configures a Budget.
+type BudgetConfig struct {
+	// Ratio is how many retries are allowed per successful request over
+	// Window. Defaults to 0.1 (retries add at most 10% load).
+	Ratio float64
+
+	// MinRetries is a floor of retries allowed per Window regardless of
+	// traffic, so a quiet service can still retry. Defaults to 10.
+	MinRetries int
+
+	// Window is how far back successes and retries are counted.
+	// Defaults to 10s.
+	Window time.Duration
+
+	// Clock defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (c BudgetConfig) withDefaults() BudgetConfig {
+	if c.Ratio <= 0 {
+		c.Ratio = 0.1
+	}
+	if c.MinRetries <= 0 {
+		c.MinRetries = 10
+	}
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.Clock == nil {
+		c.Clock = clock.RealClock{}
+	}
+	return c
+}
+
+// BudgetStats is a snapshot of a Budget.
+type BudgetStats struct {
+	// RetriesAllowed and RetriesDenied count every AllowRetry call
+	// since the Budget was created.
+	RetriesAllowed uint64
+	RetriesDenied  uint64
+
+	// Successes and Retries are the counts inside the current window.
+	Successes int
+	Retries   int
+}
+
+// Budget is a retry budget shared by every caller of one backend. It
+// satisfies retry.Budget and is safe for concurrent use.
+type Budget struct {
+	cfg BudgetConfig
+
+	mu        sync.Mutex
+	successes *window.Counter
+	retries   *window.Counter
+	allowed   uint64
+	denied    uint64
+}
+
+// windowBuckets is the resolution of every sliding window here.
+const windowBuckets = 10
+
+// NewBudget returns a Budget with nothing spent.
+func NewBudget(cfg BudgetConfig) *Budget {
+	cfg = cfg.withDefaults()
+	return &Budget{
+		cfg:       cfg,
+		successes: window.New(cfg.Window, windowBuckets),
+		retries:   window.New(cfg.Window, windowBuckets),
+	}
+}
+
+// RecordSuccess counts one successful request, which earns Ratio
+// retries for everyone.
+func (b *Budget) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.successes.Add(b.cfg.Clock.Now(), 1)
+}
+
+// AllowRetry reports whether one more retry fits in the budget and
+// spends it if so.
+func (b *Budget) AllowRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.cfg.Clock.Now()
+	limit := float64(b.cfg.MinRetries) + b.cfg.Ratio*b.successes.Sum(now)
+	if b.retries.Sum(now)+1 > limit {
+		b.denied++
+		return false
+	}
+	b.retries.Add(now, 1)
+	b.allowed++
+	return true
+}
+
+// Stats returns the budget's counters.
+func (b *Budget) Stats() BudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.cfg.Clock.Now()
+	return BudgetStats{
+		RetriesAllowed: b.allowed,
+		RetriesDenied:  b.denied,
+		Successes:      int(b.successes.Sum(now)),
+		Retries:        int(b.retries.Sum(now)),
+	}
+}
@@ -0,0 +1,142 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+	"go-systems-learning/pkg/retry"
+)
+
+var (
+	epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	errOverloaded = errors.New("backend overloaded")
+)
+
+func TestBudget_AllowsRatioOfSuccesses(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	b := NewBudget(BudgetConfig{Ratio: 0.1, MinRetries: 2, Window: 10 * time.Second, Clock: fake})
+
+	for i := 0; i < 30; i++ {
+		b.RecordSuccess()
+	}
+
+	// 2 (floor) + 0.1 * 30 = 5 retries.
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if b.AllowRetry() {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Fatalf("allowed %d retries; want 5", allowed)
+	}
+
+	got := b.Stats()
+	want := BudgetStats{RetriesAllowed: 5, RetriesDenied: 5, Successes: 30, Retries: 5}
+	if got != want {
+		t.Fatalf("Stats() = %+v; want %+v", got, want)
+	}
+
+	// Once everything slides out of the window, only the floor is left.
+	fake.Step(time.Minute)
+	if !b.AllowRetry() || !b.AllowRetry() || b.AllowRetry() {
+		t.Fatal("after the window, want exactly MinRetries retries")
+	}
+}
+
+func TestBudget_SharedByRetryPolicies(t *testing.T) {
+	b := NewBudget(BudgetConfig{MinRetries: 3, Clock: clock.NewFakeClock(epoch)})
+	failing := func(context.Context) error { return errOverloaded }
+	policy := retry.Policy{MaxAttempts: 10, Backoff: retry.Constant(0), Budget: b}
+
+	// Two callers share three retries between them.
+	calls := 0
+	counting := func(ctx context.Context) error { calls++; return failing(ctx) }
+	for i := 0; i < 2; i++ {
+		err := retry.Retry(context.Background(), counting, policy)
+		if !errors.Is(err, retry.ErrBudgetExhausted) || !errors.Is(err, errOverloaded) {
+			t.Fatalf("Retry = %v; want budget exhaustion wrapping %v", err, errOverloaded)
+		}
+	}
+
+	// 2 first attempts + 3 retries.
+	if calls != 5 {
+		t.Fatalf("op called %d times; want 5", calls)
+	}
+	if s := b.Stats(); s.RetriesAllowed != 3 || s.RetriesDenied != 2 {
+		t.Fatalf("Stats() = %+v; want 3 allowed, 2 denied", s)
+	}
+}
+
+func TestThrottle_RejectsAsAcceptsDrop(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	r := 0.0
+	th := NewThrottle(ThrottleConfig{K: 2, Clock: fake, Rand: func() float64 { return r }})
+
+	// Healthy backend: nothing is rejected.
+	for i := 0; i < 100; i++ {
+		if err := th.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+			t.Fatalf("healthy Do returned %v", err)
+		}
+	}
+	if p := th.Stats().RejectProbability; p != 0 {
+		t.Fatalf("RejectProbability when healthy = %v; want 0", p)
+	}
+
+	// The backend starts refusing everything. After 300 more requests:
+	// (400 - 2*100) / 401 ≈ 0.5.
+	r = 1 // never rejected while we build up the history
+	for i := 0; i < 300; i++ {
+		th.Do(context.Background(), func(context.Context) error { return errOverloaded })
+	}
+	p := th.Stats().RejectProbability
+	if p < 0.49 || p > 0.5 {
+		t.Fatalf("RejectProbability = %v; want ≈ 0.5", p)
+	}
+
+	ran := false
+	r = 0.1
+	err := th.Do(context.Background(), func(context.Context) error { ran = true; return nil })
+	if !errors.Is(err, ErrThrottled) || ran {
+		t.Fatalf("Do = %v (ran %v); want %v without calling the backend", err, ran, ErrThrottled)
+	}
+	if s := th.Stats(); s.Rejected != 1 || s.Allowed != 400 {
+		t.Fatalf("Stats() = %+v; want 400 allowed, 1 rejected", s)
+	}
+
+	// History ages out and the throttle opens up again.
+	fake.Step(3 * time.Minute)
+	if p := th.Stats().RejectProbability; p != 0 {
+		t.Fatalf("RejectProbability after the window = %v; want 0", p)
+	}
+}
+
+func TestThrottle_PermanentErrorsCountAsAccepted(t *testing.T) {
+	th := NewThrottle(ThrottleConfig{Clock: clock.NewFakeClock(epoch)})
+	notFound := retry.Permanent(errors.New("not found"))
+
+	for i := 0; i < 50; i++ {
+		th.Do(context.Background(), func(context.Context) error { return notFound })
+	}
+	if s := th.Stats(); s.Accepts != 50 || s.RejectProbability != 0 {
+		t.Fatalf("Stats() = %+v; want all accepted", s)
+	}
+}
+
+func TestThrottle_DoneCountsOnce(t *testing.T) {
+	th := NewThrottle(ThrottleConfig{Clock: clock.NewFakeClock(epoch)})
+
+	done, err := th.Allow()
+	if err != nil {
+		t.Fatalf("Allow = %v", err)
+	}
+	done(nil)
+	done(nil)
+	if s := th.Stats(); s.Accepts != 1 || s.Requests != 1 {
+		t.Fatalf("Stats() = %+v; want 1 request, 1 accept", s)
+	}
+}
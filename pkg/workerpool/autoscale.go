@@ -0,0 +1,325 @@
+package workerpool
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+/*
+AUTOSCALING
+
+numWorkers in the lesson is a const. Too low and bursts pile up in the
+queue, too high and idle goroutines hold connections for nothing.
+
+In autoscaling mode the pool re-evaluates its size every Interval:
+
+	estimatedWait = queueDepth * avgJobDuration / workers
+	ratio         = estimatedWait / TargetWait
+
+	ratio > ScaleUpThreshold   → add workers
+	ratio < ScaleDownThreshold → remove one worker
+	otherwise                  → hold
+
+The gap between the two thresholds is the HYSTERESIS: a pool sitting
+right at its target does not flap between two sizes. Cooldowns add a
+second guard by spacing out consecutive scaling actions.
+*/
+
+// ==========================================================
+// 1. CONFIGURATION
+// ==========================================================
+
+// AutoscaleOptions enables autoscaling when set on Options.
+type AutoscaleOptions struct {
+	// MinWorkers and MaxWorkers bound the pool size. MinWorkers
+	// defaults to 1 and MaxWorkers to MinWorkers.
+	MinWorkers int
+	MaxWorkers int
+
+	// Interval is how often the pool size is evaluated. Defaults to 1s.
+	Interval time.Duration
+
+	// TargetWait is how long a job should sit in the queue before a
+	// worker picks it up. Defaults to Interval.
+	TargetWait time.Duration
+
+	// ScaleUpThreshold and ScaleDownThreshold are compared with
+	// estimatedWait / TargetWait. Defaults are 1.0 and 0.5.
+	ScaleUpThreshold   float64
+	ScaleDownThreshold float64
+
+	// ScaleUpCooldown and ScaleDownCooldown are the minimum time since
+	// the last scaling action before scaling in that direction again.
+	// Defaults are Interval and 5 * Interval.
+	ScaleUpCooldown   time.Duration
+	ScaleDownCooldown time.Duration
+
+	// HistorySize is how many decisions ScalingHistory keeps.
+	// Defaults to 64.
+	HistorySize int
+
+	// OnDecision, if set, is called for every recorded decision.
+	OnDecision func(ScalingDecision)
+
+	// Clock drives the evaluation ticks and cooldowns.
+	// Defaults to clock.RealClock.
+	Clock clock.Clock
+}
+
+func (a AutoscaleOptions) withDefaults() AutoscaleOptions {
+	if a.MinWorkers <= 0 {
+		a.MinWorkers = 1
+	}
+	if a.MaxWorkers < a.MinWorkers {
+		a.MaxWorkers = a.MinWorkers
+	}
+	if a.Interval <= 0 {
+		a.Interval = time.Second
+	}
+	if a.TargetWait <= 0 {
+		a.TargetWait = a.Interval
+	}
+	if a.ScaleUpThreshold <= 0 {
+		a.ScaleUpThreshold = 1.0
+	}
+	if a.ScaleDownThreshold <= 0 || a.ScaleDownThreshold >= a.ScaleUpThreshold {
+		a.ScaleDownThreshold = a.ScaleUpThreshold / 2
+	}
+	if a.ScaleUpCooldown <= 0 {
+		a.ScaleUpCooldown = a.Interval
+	}
+	if a.ScaleDownCooldown <= 0 {
+		a.ScaleDownCooldown = 5 * a.Interval
+	}
+	if a.HistorySize <= 0 {
+		a.HistorySize = 64
+	}
+	if a.Clock == nil {
+		a.Clock = clock.RealClock{}
+	}
+	return a
+}
+
+// ==========================================================
+// 2. DECISION RECORD
+// ==========================================================
+
+// ScalingDecision records why the pool did, or did not, change size.
+// Only evaluations that wanted a different size are recorded, so a
+// stable pool leaves the history untouched.
+type ScalingDecision struct {
+	Time          time.Time
+	From          int
+	To            int
+	QueueDepth    int
+	AvgDuration   time.Duration
+	EstimatedWait time.Duration
+	Reason        string
+}
+
+// Applied reports whether the decision changed the pool size.
+func (d ScalingDecision) Applied() bool {
+	return d.From != d.To
+}
+
+// ==========================================================
+// 3. AUTOSCALER
+// ==========================================================
+
+type autoscaler struct {
+	opts AutoscaleOptions
+
+	// latency is an exponentially weighted moving average of
+	// Result.Duration, fed by the workers.
+	latencyMu  sync.Mutex
+	latency    time.Duration
+	hasLatency bool
+
+	// lastScale is when the pool last changed size.
+	lastScale time.Time
+
+	historyMu sync.Mutex
+	history   []ScalingDecision
+
+	done chan struct{}
+}
+
+func newAutoscaler(opts AutoscaleOptions) *autoscaler {
+	return &autoscaler{
+		opts: opts,
+		done: make(chan struct{}),
+	}
+}
+
+// observe feeds one job duration into the moving average.
+func (a *autoscaler) observe(d time.Duration) {
+	const weight = 0.2
+
+	a.latencyMu.Lock()
+	defer a.latencyMu.Unlock()
+
+	if !a.hasLatency {
+		a.latency = d
+		a.hasLatency = true
+		return
+	}
+	a.latency = time.Duration(weight*float64(d) + (1-weight)*float64(a.latency))
+}
+
+func (a *autoscaler) avgLatency() (time.Duration, bool) {
+	a.latencyMu.Lock()
+	defer a.latencyMu.Unlock()
+	return a.latency, a.hasLatency
+}
+
+// decide returns the new size and, if the evaluation wanted a change,
+// the decision explaining it.
+func (a *autoscaler) decide(now time.Time, current, depth int) (int, *ScalingDecision) {
+	o := a.opts
+
+	avg, ok := a.avgLatency()
+	if !ok {
+		// No job has finished yet: assume each takes TargetWait, so
+		// the ratio degrades to queued jobs per worker.
+		avg = o.TargetWait
+	}
+
+	wait := time.Duration(float64(depth) * float64(avg) / float64(current))
+	ratio := float64(wait) / float64(o.TargetWait)
+
+	d := &ScalingDecision{
+		Time:          now,
+		From:          current,
+		To:            current,
+		QueueDepth:    depth,
+		AvgDuration:   avg,
+		EstimatedWait: wait,
+	}
+	sinceLast := now.Sub(a.lastScale)
+
+	switch {
+	case ratio > o.ScaleUpThreshold:
+		if current >= o.MaxWorkers {
+			d.Reason = fmt.Sprintf("scale up wanted (wait ratio %.2f > %.2f) but already at max %d",
+				ratio, o.ScaleUpThreshold, o.MaxWorkers)
+			return current, d
+		}
+		if !a.lastScale.IsZero() && sinceLast < o.ScaleUpCooldown {
+			d.Reason = fmt.Sprintf("scale up wanted (wait ratio %.2f > %.2f) but in cooldown for %v",
+				ratio, o.ScaleUpThreshold, o.ScaleUpCooldown-sinceLast)
+			return current, d
+		}
+
+		// Size the pool so the backlog drains within TargetWait.
+		want := int(math.Ceil(float64(depth) * float64(avg) / float64(o.TargetWait)))
+		d.To = min(max(want, current+1), o.MaxWorkers)
+		d.Reason = fmt.Sprintf("scale up: wait ratio %.2f > %.2f", ratio, o.ScaleUpThreshold)
+
+	case ratio < o.ScaleDownThreshold:
+		if current <= o.MinWorkers {
+			// Idle at the floor is the normal resting state.
+			return current, nil
+		}
+		if !a.lastScale.IsZero() && sinceLast < o.ScaleDownCooldown {
+			d.Reason = fmt.Sprintf("scale down wanted (wait ratio %.2f < %.2f) but in cooldown for %v",
+				ratio, o.ScaleDownThreshold, o.ScaleDownCooldown-sinceLast)
+			return current, d
+		}
+
+		// Shrink one step at a time: removing capacity is the risky
+		// direction.
+		d.To = current - 1
+		d.Reason = fmt.Sprintf("scale down: wait ratio %.2f < %.2f", ratio, o.ScaleDownThreshold)
+
+	default:
+		return current, nil
+	}
+
+	a.lastScale = now
+	return d.To, d
+}
+
+func (a *autoscaler) record(d ScalingDecision) {
+	a.historyMu.Lock()
+	if len(a.history) == a.opts.HistorySize {
+		copy(a.history, a.history[1:])
+		a.history = a.history[:len(a.history)-1]
+	}
+	a.history = append(a.history, d)
+	a.historyMu.Unlock()
+
+	if a.opts.OnDecision != nil {
+		a.opts.OnDecision(d)
+	}
+}
+
+// ==========================================================
+// 4. POOL INTEGRATION
+// ==========================================================
+
+// ScalingHistory returns the most recent scaling decisions, oldest
+// first. It is empty unless autoscaling is enabled.
+func (p *Pool[In, Out]) ScalingHistory() []ScalingDecision {
+	if p.scaler == nil {
+		return nil
+	}
+
+	p.scaler.historyMu.Lock()
+	defer p.scaler.historyMu.Unlock()
+	return append([]ScalingDecision(nil), p.scaler.history...)
+}
+
+// Workers returns the current target number of workers.
+func (p *Pool[In, Out]) Workers() int {
+	p.sizeMu.Lock()
+	defer p.sizeMu.Unlock()
+	return p.size
+}
+
+// autoscaleLoop evaluates the pool size every Interval until Close.
+func (p *Pool[In, Out]) autoscaleLoop() {
+	a := p.scaler
+	defer close(a.done)
+
+	for {
+		timer := a.opts.Clock.NewTimer(a.opts.Interval)
+		select {
+		case <-p.closing:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		p.sizeMu.Lock()
+		current := p.size
+		target, d := a.decide(a.opts.Clock.Now(), current, len(p.jobs))
+		p.resizeLocked(target)
+		p.sizeMu.Unlock()
+
+		if d != nil {
+			a.record(*d)
+		}
+	}
+}
+
+// resizeLocked starts or stops workers to reach target.
+// Stopping is cooperative: the next worker to go idle exits.
+func (p *Pool[In, Out]) resizeLocked(target int) {
+	for ; p.size < target; p.size++ {
+		select {
+		case <-p.shrink:
+			// A worker had not picked up its stop yet: cancel it
+			// instead of starting a new goroutine.
+		default:
+			p.wg.Add(1)
+			go p.worker()
+		}
+	}
+	for ; p.size > target; p.size-- {
+		p.shrink <- struct{}{}
+	}
+}
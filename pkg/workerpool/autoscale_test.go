@@ -0,0 +1,151 @@
+package workerpool
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestAutoscaler_Decide(t *testing.T) {
+	opts := AutoscaleOptions{
+		MinWorkers: 1,
+		MaxWorkers: 8,
+		Interval:   time.Second,
+		TargetWait: time.Second,
+	}.withDefaults()
+
+	tests := []struct {
+		name       string
+		current    int
+		depth      int
+		latency    time.Duration
+		lastScale  time.Time
+		wantSize   int
+		wantReason string // empty means no decision recorded
+	}{
+		{
+			name:    "backlog scales up to drain within target",
+			current: 2, depth: 10, latency: 500 * time.Millisecond,
+			wantSize: 5, wantReason: "scale up",
+		},
+		{
+			name:    "inside hysteresis band holds",
+			current: 4, depth: 6, latency: 500 * time.Millisecond,
+			wantSize: 4,
+		},
+		{
+			name:    "empty queue scales down one step",
+			current: 4, depth: 0, latency: 500 * time.Millisecond,
+			wantSize: 3, wantReason: "scale down",
+		},
+		{
+			name:    "idle at minimum records nothing",
+			current: 1, depth: 0, latency: 500 * time.Millisecond,
+			wantSize: 1,
+		},
+		{
+			name:    "scale up capped at max",
+			current: 8, depth: 100, latency: time.Second,
+			wantSize: 8, wantReason: "already at max",
+		},
+		{
+			name:    "scale down suppressed by cooldown",
+			current: 4, depth: 0, latency: 500 * time.Millisecond,
+			lastScale: epoch.Add(-2 * time.Second),
+			wantSize:  4, wantReason: "in cooldown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAutoscaler(opts)
+			a.observe(tt.latency)
+			a.lastScale = tt.lastScale
+
+			size, d := a.decide(epoch, tt.current, tt.depth)
+			if size != tt.wantSize {
+				t.Fatalf("decide() size = %d; want %d", size, tt.wantSize)
+			}
+
+			switch {
+			case tt.wantReason == "" && d != nil:
+				t.Fatalf("decide() recorded %q; want no decision", d.Reason)
+			case tt.wantReason != "" && d == nil:
+				t.Fatalf("decide() recorded nothing; want reason containing %q", tt.wantReason)
+			case d != nil && !strings.Contains(d.Reason, tt.wantReason):
+				t.Fatalf("decide() reason = %q; want it to contain %q", d.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPool_AutoscalesWithQueueDepth(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	release := make(chan struct{})
+	block := func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}
+
+	p := New(block, Options{
+		QueueSize: 8,
+		Autoscale: &AutoscaleOptions{
+			MinWorkers: 1,
+			MaxWorkers: 4,
+			Interval:   time.Second,
+			Clock:      fake,
+		},
+	})
+	go func() {
+		for range p.Results() {
+		}
+	}()
+
+	// One job held by the single worker, eight waiting in the queue.
+	for i := 0; i < 9; i++ {
+		if _, err := p.Submit(context.Background(), i); err != nil {
+			t.Fatalf("Submit(%d) returned %v", i, err)
+		}
+	}
+
+	waitForWaiters(t, fake)
+	fake.Step(time.Second)
+	waitForHistory(t, p, 1)
+
+	if got := p.Workers(); got != 4 {
+		t.Fatalf("Workers() after backlog = %d; want 4", got)
+	}
+	if d := p.ScalingHistory()[0]; !d.Applied() || d.From != 1 || d.To != 4 {
+		t.Fatalf("decision = %+v; want an applied 1 → 4 scale up", d)
+	}
+
+	close(release)
+	p.Close()
+}
+
+func waitForWaiters(t *testing.T, c *clock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the autoscaler to wait on the clock")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitForHistory[In, Out any](t *testing.T, p *Pool[In, Out], n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(p.ScalingHistory()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("ScalingHistory() has %d decisions; want %d", len(p.ScalingHistory()), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,335 @@
+// Package workerpool is the reusable version of the WORKER POOL pattern
+// from 05-concurrency/06-patterns.
+//
+// The lesson hard-wires Job, Result and worker to time.Sleep and a fixed
+// numJobs. This package keeps the same shape:
+//   - a FIXED number of workers ("threadiness")
+//   - a BOUNDED jobs channel (backpressure)
+//   - a results stream
+//
+// and makes the work itself a generic function.
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ==========================================================
+// 1. ERRORS
+// ==========================================================
+
+// ErrPoolClosed is returned by Submit once Close has been called.
+var ErrPoolClosed = errors.New("workerpool: pool is closed")
+
+// PanicError is the error recorded in a Result when the job function
+// panicked. The panic never escapes the worker goroutine.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("workerpool: job panicked: %v", e.Value)
+}
+
+// ==========================================================
+// 2. JOB & RESULT DEFINITIONS
+// ==========================================================
+
+// Func is the work performed for a single job.
+//
+// ctx is derived from the context passed to Submit, so the job is
+// cancelled exactly when its submitter gives up on it, or when
+// Options.JobTimeout expires.
+type Func[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Result represents completed work.
+type Result[Out any] struct {
+	JobID    int
+	Status   Status
+	Value    Out
+	Err      error
+	Duration time.Duration
+}
+
+// job is what actually travels through the jobs channel.
+type job[In any] struct {
+	ctx context.Context
+	id  int
+	in  In
+}
+
+// ==========================================================
+// 3. CONFIGURATION
+// ==========================================================
+
+// Options configures a Pool.
+type Options struct {
+	// Workers is the number of goroutines processing jobs
+	// (the "threadiness" of a Kubernetes controller). Defaults to 1.
+	Workers int
+
+	// QueueSize is the capacity of the jobs channel. Once it is full,
+	// Submit blocks: this is the backpressure. Defaults to Workers, or
+	// to Autoscale.MaxWorkers when autoscaling.
+	QueueSize int
+
+	// Autoscale, when set, lets the pool grow and shrink between
+	// Autoscale.MinWorkers and Autoscale.MaxWorkers. Workers is then
+	// only the starting size.
+	Autoscale *AutoscaleOptions
+
+	// JobTimeout, when positive, is the deadline for each job measured
+	// from when a worker starts it. Time spent queued does not count.
+	JobTimeout time.Duration
+
+	// Ordered makes Results emit in JobID order.
+	Ordered bool
+
+	// ReorderBuffer bounds how many results may wait for an earlier
+	// job when Ordered is set. Defaults to QueueSize plus the maximum
+	// number of workers.
+	ReorderBuffer int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = 1
+	}
+	if o.Autoscale != nil {
+		a := o.Autoscale.withDefaults()
+		o.Autoscale = &a
+		o.Workers = min(max(o.Workers, a.MinWorkers), a.MaxWorkers)
+		if o.QueueSize <= 0 {
+			o.QueueSize = a.MaxWorkers
+		}
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = o.Workers
+	}
+	if o.Ordered && o.ReorderBuffer <= 0 {
+		o.ReorderBuffer = o.QueueSize + o.Workers
+		if o.Autoscale != nil {
+			o.ReorderBuffer = o.QueueSize + o.Autoscale.MaxWorkers
+		}
+	}
+	return o
+}
+
+// ==========================================================
+// 4. POOL
+// ==========================================================
+
+// Pool runs submitted jobs on a bounded set of workers.
+//
+// Callers MUST keep reading Results until it is closed, otherwise
+// workers block on sending and the pool stops making progress.
+type Pool[In, Out any] struct {
+	fn      Func[In, Out]
+	timeout time.Duration
+	jobs    chan job[In]
+	results chan Result[Out]
+
+	// out is where workers send results: results itself, or the input
+	// of the resequencer when Ordered is set.
+	out       chan Result[Out]
+	reseq     *Resequencer[Out]
+	reseqDone chan struct{}
+
+	// size is the number of workers the pool is aiming for. shrink
+	// carries one token per worker that should stop.
+	sizeMu sync.Mutex
+	size   int
+	shrink chan struct{}
+	scaler *autoscaler
+
+	// submitMu serializes Submit so job IDs follow channel order.
+	submitMu sync.Mutex
+	nextID   int
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// New starts a pool running fn on opts.Workers goroutines.
+func New[In, Out any](fn Func[In, Out], opts Options) *Pool[In, Out] {
+	opts = opts.withDefaults()
+
+	p := &Pool[In, Out]{
+		fn:      fn,
+		timeout: opts.JobTimeout,
+		jobs:    make(chan job[In], opts.QueueSize),
+		results: make(chan Result[Out], opts.QueueSize),
+		nextID:  1,
+		closing: make(chan struct{}),
+	}
+
+	p.out = p.results
+	if opts.Ordered {
+		p.out = make(chan Result[Out], opts.QueueSize)
+		p.reseq = NewResequencer[Out](p.nextID, opts.ReorderBuffer)
+		p.reseqDone = make(chan struct{})
+		go func() {
+			defer close(p.reseqDone)
+			p.resequenceLoop(p.out)
+		}()
+	}
+
+	if opts.Autoscale != nil {
+		p.shrink = make(chan struct{}, opts.Autoscale.MaxWorkers)
+		p.scaler = newAutoscaler(*opts.Autoscale)
+	}
+
+	p.sizeMu.Lock()
+	p.resizeLocked(opts.Workers)
+	p.sizeMu.Unlock()
+
+	if p.scaler != nil {
+		go p.autoscaleLoop()
+	}
+
+	return p
+}
+
+// Submit enqueues in and returns the job ID assigned to it.
+//
+// IDs start at 1 and increase in the order jobs enter the queue.
+// If the queue is full Submit blocks until a worker frees a slot,
+// ctx is done, or the pool is closed.
+func (p *Pool[In, Out]) Submit(ctx context.Context, in In) (int, error) {
+	p.submitMu.Lock()
+	defer p.submitMu.Unlock()
+
+	select {
+	case <-p.closing:
+		return 0, ErrPoolClosed
+	default:
+	}
+
+	if p.reseq != nil {
+		if err := p.reseq.Reserve(ctx, p.closing); err != nil {
+			return 0, err
+		}
+	}
+
+	j := job[In]{ctx: ctx, id: p.nextID, in: in}
+
+	select {
+	case p.jobs <- j:
+		p.nextID++
+		return j.id, nil
+	case <-ctx.Done():
+		p.releaseReservation()
+		return 0, ctx.Err()
+	case <-p.closing:
+		p.releaseReservation()
+		return 0, ErrPoolClosed
+	}
+}
+
+func (p *Pool[In, Out]) releaseReservation() {
+	if p.reseq != nil {
+		p.reseq.Release()
+	}
+}
+
+// Results returns the stream of completed jobs, in JobID order when
+// Options.Ordered is set. It is closed after Close once every accepted
+// job has produced its Result.
+func (p *Pool[In, Out]) Results() <-chan Result[Out] {
+	return p.results
+}
+
+// Close stops accepting jobs, waits for queued and in-flight jobs to
+// finish, and then closes Results. It is safe to call more than once.
+func (p *Pool[In, Out]) Close() {
+	p.closeOnce.Do(func() {
+		// Unblock any Submit waiting on a full queue first, then take
+		// the lock so no one can send on jobs after it is closed.
+		close(p.closing)
+		if p.scaler != nil {
+			// The autoscaler must not start workers once we wait.
+			<-p.scaler.done
+		}
+		p.submitMu.Lock()
+		close(p.jobs)
+		p.submitMu.Unlock()
+
+		p.wg.Wait()
+		close(p.out)
+		if p.reseq != nil {
+			<-p.reseqDone
+		}
+	})
+}
+
+// ==========================================================
+// 5. WORKER
+// ==========================================================
+
+func (p *Pool[In, Out]) worker() {
+	defer p.wg.Done()
+
+	// Receiving until jobs is closed drains the queue even after Close,
+	// which is what makes Close a graceful shutdown rather than a drop.
+	for {
+		select {
+		case j, ok := <-p.jobs:
+			if !ok {
+				return
+			}
+			res := p.run(j)
+			if p.scaler != nil {
+				p.scaler.observe(res.Duration)
+			}
+			p.out <- res
+
+		case <-p.shrink:
+			// Nil unless autoscaling: the pool scaled down.
+			return
+		}
+	}
+}
+
+// run executes a single job, turning a panic into a failed Result.
+func (p *Pool[In, Out]) run(j job[In]) (res Result[Out]) {
+	res.JobID = j.id
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			res.Err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		res.Status = statusOf(res.Err)
+		res.Duration = time.Since(start)
+	}()
+
+	// Do not start work nobody is waiting for any more.
+	if err := j.ctx.Err(); err != nil {
+		res.Err = err
+		return res
+	}
+
+	ctx := j.ctx
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	res.Value, res.Err = p.fn(ctx, j.in)
+
+	// A job that ignored ctx and returned nil still succeeded. One that
+	// returned a different error after its deadline expired is reported
+	// as timed out or cancelled, since that is why it failed.
+	if res.Err != nil && ctx.Err() != nil && !errors.Is(res.Err, ctx.Err()) {
+		res.Err = fmt.Errorf("%w: %w", ctx.Err(), res.Err)
+	}
+	return res
+}
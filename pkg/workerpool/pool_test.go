@@ -0,0 +1,229 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func double(_ context.Context, n int) (int, error) {
+	return n * 2, nil
+}
+
+func TestPool_ProcessesAllJobs(t *testing.T) {
+	p := New(double, Options{Workers: 3, QueueSize: 2})
+
+	const numJobs = 10
+	go func() {
+		defer p.Close()
+		for i := 1; i <= numJobs; i++ {
+			if _, err := p.Submit(context.Background(), i); err != nil {
+				t.Errorf("Submit(%d) returned %v", i, err)
+			}
+		}
+	}()
+
+	var got []int
+	for res := range p.Results() {
+		if res.Err != nil {
+			t.Fatalf("job %d failed: %v", res.JobID, res.Err)
+		}
+		if res.Value != res.JobID*2 {
+			t.Fatalf("job %d value = %d; want %d", res.JobID, res.Value, res.JobID*2)
+		}
+		got = append(got, res.JobID)
+	}
+
+	sort.Ints(got)
+	if len(got) != numJobs {
+		t.Fatalf("got %d results; want %d", len(got), numJobs)
+	}
+	for i, id := range got {
+		if id != i+1 {
+			t.Fatalf("job IDs = %v; want 1..%d", got, numJobs)
+		}
+	}
+}
+
+func TestPool_Backpressure(t *testing.T) {
+	release := make(chan struct{})
+	block := func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}
+
+	p := New(block, Options{Workers: 1, QueueSize: 1})
+	defer func() {
+		close(release)
+		p.Close()
+	}()
+	go func() {
+		for range p.Results() {
+		}
+	}()
+
+	// One job in the worker, one in the queue.
+	for i := 0; i < 2; i++ {
+		if _, err := p.Submit(context.Background(), i); err != nil {
+			t.Fatalf("Submit(%d) returned %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The queue is full, so this must block until ctx expires.
+	if _, err := p.Submit(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Submit on full queue = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPool_PanicBecomesFailedResult(t *testing.T) {
+	boom := func(_ context.Context, n int) (int, error) {
+		panic("boom")
+	}
+
+	p := New(boom, Options{Workers: 1})
+	if _, err := p.Submit(context.Background(), 1); err != nil {
+		t.Fatalf("Submit returned %v", err)
+	}
+	go p.Close()
+
+	res := <-p.Results()
+
+	var pErr *PanicError
+	if !errors.As(res.Err, &pErr) {
+		t.Fatalf("result error = %v; want *PanicError", res.Err)
+	}
+	if pErr.Value != "boom" {
+		t.Fatalf("panic value = %v; want %q", pErr.Value, "boom")
+	}
+}
+
+func TestPool_CancelledJobIsNotRun(t *testing.T) {
+	ran := false
+	fn := func(_ context.Context, n int) (int, error) {
+		ran = true
+		return n, nil
+	}
+
+	p := New(fn, Options{Workers: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Submit may succeed or observe the cancelled ctx; both are fine.
+	if _, err := p.Submit(ctx, 1); err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatalf("Submit returned %v", err)
+	}
+	p.Close()
+
+	for res := range p.Results() {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Fatalf("result error = %v; want %v", res.Err, context.Canceled)
+		}
+	}
+	if ran {
+		t.Fatal("job function ran for a cancelled context")
+	}
+}
+
+func TestPool_SubmitAfterClose(t *testing.T) {
+	p := New(double, Options{Workers: 1})
+	p.Close()
+
+	if _, err := p.Submit(context.Background(), 1); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Submit after Close = %v; want %v", err, ErrPoolClosed)
+	}
+}
+
+func TestPool_ResultStatus(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name string
+		fn   Func[int, int]
+		opts Options
+		ctx  func() context.Context
+		want Status
+	}{
+		{
+			name: "succeeded",
+			fn:   double,
+			want: StatusSucceeded,
+		},
+		{
+			name: "failed",
+			fn:   func(context.Context, int) (int, error) { return 0, errBoom },
+			want: StatusFailed,
+		},
+		{
+			name: "timed out by JobTimeout",
+			fn: func(ctx context.Context, _ int) (int, error) {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			},
+			opts: Options{JobTimeout: 10 * time.Millisecond},
+			want: StatusTimedOut,
+		},
+		{
+			name: "cancelled by submitter",
+			fn:   double,
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			want: StatusCancelled,
+		},
+		{
+			name: "panicked",
+			fn:   func(context.Context, int) (int, error) { panic("boom") },
+			want: StatusPanicked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(tt.fn, tt.opts)
+
+			ctx := context.Background()
+			if tt.ctx != nil {
+				// Bypass Submit's own ctx check by queueing directly.
+				p.jobs <- job[int]{ctx: tt.ctx(), id: 1, in: 1}
+			} else if _, err := p.Submit(ctx, 1); err != nil {
+				t.Fatalf("Submit returned %v", err)
+			}
+			p.Close()
+
+			res := <-p.Results()
+			if res.Status != tt.want {
+				t.Fatalf("Status = %v (err %v); want %v", res.Status, res.Err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPool_JobTimeoutWrapsLateErrors(t *testing.T) {
+	errDriver := errors.New("driver: i/o timeout")
+	slow := func(ctx context.Context, _ int) (int, error) {
+		<-ctx.Done()
+		return 0, errDriver
+	}
+
+	p := New(slow, Options{JobTimeout: 10 * time.Millisecond})
+	if _, err := p.Submit(context.Background(), 1); err != nil {
+		t.Fatalf("Submit returned %v", err)
+	}
+	p.Close()
+
+	res := <-p.Results()
+	if res.Status != StatusTimedOut {
+		t.Fatalf("Status = %v; want %v", res.Status, StatusTimedOut)
+	}
+	if !errors.Is(res.Err, errDriver) {
+		t.Fatalf("Err = %v; want it to wrap %v", res.Err, errDriver)
+	}
+}
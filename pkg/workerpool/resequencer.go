@@ -0,0 +1,100 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+/*
+IN-ORDER RESULTS
+
+Workers finish in any order, so results come back shuffled. For batch
+jobs that must write output in input order (CSV rows, log segments) the
+pool can re-sequence results by JobID.
+
+The reorder buffer is BOUNDED. Unbounded buffering would let one slow
+job at the head hold every later result in memory. Instead, a job only
+enters the pool once it has a slot in the buffer:
+
+	Submit → take a slot → ... → result emitted in order → slot freed
+
+so at most Size results ever wait, and Submit blocks (backpressure)
+while the head job is still running.
+*/
+
+// Resequencer emits results in JobID order using a bounded buffer.
+//
+// It is safe for one goroutine to call Push while others call Reserve,
+// Release and Buffered.
+type Resequencer[Out any] struct {
+	next     int
+	pending  map[int]Result[Out]
+	buffered atomic.Int64 // len(pending) as of the last Push, for Buffered
+	slots    chan struct{}
+}
+
+// NewResequencer returns a Resequencer expecting firstID first and
+// holding at most size out-of-order results.
+func NewResequencer[Out any](firstID, size int) *Resequencer[Out] {
+	if size <= 0 {
+		size = 1
+	}
+	return &Resequencer[Out]{
+		next:    firstID,
+		pending: make(map[int]Result[Out], size),
+		slots:   make(chan struct{}, size),
+	}
+}
+
+// Reserve takes a buffer slot for one job. It blocks while the buffer
+// is full, until ctx is done or stop is closed.
+func (r *Resequencer[Out]) Reserve(ctx context.Context, stop <-chan struct{}) error {
+	select {
+	case r.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-stop:
+		return ErrPoolClosed
+	}
+}
+
+// Release gives back a slot whose job never entered the pool.
+func (r *Resequencer[Out]) Release() {
+	<-r.slots
+}
+
+// Push accepts one result and returns every result that is now ready,
+// in JobID order. Each returned result frees its slot.
+func (r *Resequencer[Out]) Push(res Result[Out]) []Result[Out] {
+	r.pending[res.JobID] = res
+
+	var ready []Result[Out]
+	for {
+		next, ok := r.pending[r.next]
+		if !ok {
+			r.buffered.Store(int64(len(r.pending)))
+			return ready
+		}
+		delete(r.pending, r.next)
+		r.next++
+		ready = append(ready, next)
+		<-r.slots
+	}
+}
+
+// Buffered returns how many results are waiting for an earlier one.
+func (r *Resequencer[Out]) Buffered() int {
+	return int(r.buffered.Load())
+}
+
+// resequenceLoop moves results from the workers to Results in order.
+func (p *Pool[In, Out]) resequenceLoop(unordered <-chan Result[Out]) {
+	defer close(p.results)
+
+	for res := range unordered {
+		for _, ready := range p.reseq.Push(res) {
+			p.results <- ready
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResequencer_Push(t *testing.T) {
+	r := NewResequencer[string](1, 3)
+	for i := 0; i < 3; i++ {
+		if err := r.Reserve(context.Background(), nil); err != nil {
+			t.Fatalf("Reserve returned %v", err)
+		}
+	}
+
+	steps := []struct {
+		push     int
+		want     []int
+		buffered int
+	}{
+		{push: 3, want: nil, buffered: 1},
+		{push: 2, want: nil, buffered: 2},
+		{push: 1, want: []int{1, 2, 3}, buffered: 0},
+	}
+
+	// Buffered may be polled while Push runs; -race checks that.
+	stop := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				r.Buffered()
+			}
+		}
+	}()
+	defer func() { close(stop); <-polled }()
+
+	for _, step := range steps {
+		got := r.Push(Result[string]{JobID: step.push})
+		if len(got) != len(step.want) {
+			t.Fatalf("Push(%d) returned %d results; want %v", step.push, len(got), step.want)
+		}
+		for i, res := range got {
+			if res.JobID != step.want[i] {
+				t.Fatalf("Push(%d) returned job %d at %d; want %v", step.push, res.JobID, i, step.want)
+			}
+		}
+		if r.Buffered() != step.buffered {
+			t.Fatalf("Buffered() after Push(%d) = %d; want %d", step.push, r.Buffered(), step.buffered)
+		}
+	}
+}
+
+func TestResequencer_ReserveBlocksWhenFull(t *testing.T) {
+	r := NewResequencer[int](1, 1)
+	if err := r.Reserve(context.Background(), nil); err != nil {
+		t.Fatalf("Reserve returned %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Reserve(ctx, nil); err == nil {
+		t.Fatal("Reserve on a full buffer returned nil")
+	}
+}
+
+func TestPool_OrderedResults(t *testing.T) {
+	// Later jobs finish first, so an unordered pool would reverse them.
+	const numJobs = 6
+	fn := func(_ context.Context, n int) (int, error) {
+		time.Sleep(time.Duration(numJobs-n) * 5 * time.Millisecond)
+		return n, nil
+	}
+
+	p := New(fn, Options{Workers: numJobs, Ordered: true, ReorderBuffer: 3})
+
+	go func() {
+		defer p.Close()
+		for i := 1; i <= numJobs; i++ {
+			if _, err := p.Submit(context.Background(), i); err != nil {
+				t.Errorf("Submit(%d) returned %v", i, err)
+			}
+		}
+	}()
+
+	want := 1
+	for res := range p.Results() {
+		if res.JobID != want {
+			t.Fatalf("result for job %d; want job %d", res.JobID, want)
+		}
+		want++
+	}
+	if want != numJobs+1 {
+		t.Fatalf("got %d results; want %d", want-1, numJobs)
+	}
+}
@@ -0,0 +1,78 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+)
+
+// ==========================================================
+// JOB OUTCOMES
+// ==========================================================
+
+/*
+The lesson's Result.Status is always the string "Success". Real jobs
+end in one of several ways, and callers react differently to each:
+
+	Succeeded → write output
+	Failed    → maybe retry (see pkg/workqueue AddRateLimited)
+	TimedOut  → retry with a longer deadline, or alert
+	Cancelled → the caller gave up, nothing to do
+	Panicked  → a bug: log the stack, do NOT retry blindly
+*/
+
+// Status is the outcome of a job.
+type Status int
+
+const (
+	// StatusSucceeded means the job function returned a nil error.
+	StatusSucceeded Status = iota
+
+	// StatusFailed means the job function returned an error.
+	StatusFailed
+
+	// StatusTimedOut means the job's deadline expired, either
+	// Options.JobTimeout or a deadline on the Submit context.
+	StatusTimedOut
+
+	// StatusCancelled means the Submit context was cancelled.
+	StatusCancelled
+
+	// StatusPanicked means the job function panicked. Result.Err is a
+	// *PanicError.
+	StatusPanicked
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusSucceeded:
+		return "Succeeded"
+	case StatusFailed:
+		return "Failed"
+	case StatusTimedOut:
+		return "TimedOut"
+	case StatusCancelled:
+		return "Cancelled"
+	case StatusPanicked:
+		return "Panicked"
+	default:
+		return "Unknown"
+	}
+}
+
+// statusOf classifies the error a job finished with.
+func statusOf(err error) Status {
+	var pErr *PanicError
+
+	switch {
+	case err == nil:
+		return StatusSucceeded
+	case errors.As(err, &pErr):
+		return StatusPanicked
+	case errors.Is(err, context.DeadlineExceeded):
+		return StatusTimedOut
+	case errors.Is(err, context.Canceled):
+		return StatusCancelled
+	default:
+		return StatusFailed
+	}
+}
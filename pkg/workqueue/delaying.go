@@ -0,0 +1,150 @@
+package workqueue
+
+import (
+	"container/heap"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. DELAYED ADDS
+// ==========================================================
+
+// waitFor is an item that becomes ready at readyAt.
+type waitFor[T comparable] struct {
+	item    T
+	readyAt time.Time
+	index   int
+}
+
+type delayingState[T comparable] struct {
+	stop          chan struct{}
+	waitingForAdd chan *waitFor[T]
+}
+
+func newDelayingState[T comparable]() *delayingState[T] {
+	return &delayingState[T]{
+		stop:          make(chan struct{}),
+		waitingForAdd: make(chan *waitFor[T], 1000),
+	}
+}
+
+// AddAfter adds item once duration has passed. Several AddAfter calls
+// for the same item keep only the earliest deadline.
+func (q *Queue[T]) AddAfter(item T, duration time.Duration) {
+	if q.ShuttingDown() {
+		return
+	}
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+
+	select {
+	case <-q.delaying.stop:
+	case q.delaying.waitingForAdd <- &waitFor[T]{item: item, readyAt: q.clock.Now().Add(duration)}:
+	}
+}
+
+// waitingLoop moves items from the waiting heap onto the queue as
+// their deadlines pass. It exits on shutdown.
+func (q *Queue[T]) waitingLoop() {
+	waiting := &waitForHeap[T]{}
+	entries := make(map[T]*waitFor[T])
+
+	for {
+		now := q.clock.Now()
+
+		for waiting.Len() > 0 && !(*waiting)[0].readyAt.After(now) {
+			entry := heap.Pop(waiting).(*waitFor[T])
+			delete(entries, entry.item)
+			q.Add(entry.item)
+		}
+
+		var (
+			timer     clock.Timer
+			nextReady <-chan time.Time
+		)
+		if waiting.Len() > 0 {
+			timer = q.clock.NewTimer((*waiting)[0].readyAt.Sub(now))
+			nextReady = timer.C()
+		}
+
+		select {
+		case <-q.delaying.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+
+		case <-nextReady:
+
+		case w := <-q.delaying.waitingForAdd:
+			insert(waiting, entries, w)
+
+			// Take everything else already buffered in one pass.
+			drained := false
+			for !drained {
+				select {
+				case w := <-q.delaying.waitingForAdd:
+					insert(waiting, entries, w)
+				default:
+					drained = true
+				}
+			}
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// insert adds w to the heap, or moves an existing entry for the same
+// item earlier if w is ready sooner.
+func insert[T comparable](h *waitForHeap[T], entries map[T]*waitFor[T], w *waitFor[T]) {
+	if existing, ok := entries[w.item]; ok {
+		if w.readyAt.Before(existing.readyAt) {
+			existing.readyAt = w.readyAt
+			heap.Fix(h, existing.index)
+		}
+		return
+	}
+
+	heap.Push(h, w)
+	entries[w.item] = w
+}
+
+// ==========================================================
+// 2. MIN-HEAP BY readyAt
+// ==========================================================
+
+type waitForHeap[T comparable] []*waitFor[T]
+
+func (h waitForHeap[T]) Len() int { return len(h) }
+
+func (h waitForHeap[T]) Less(i, j int) bool {
+	return h[i].readyAt.Before(h[j].readyAt)
+}
+
+func (h waitForHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waitForHeap[T]) Push(x any) {
+	w := x.(*waitFor[T])
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waitForHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return w
+}
@@ -0,0 +1,214 @@
+// Package workqueue is a client-go style work queue for controllers.
+//
+// The worker pool in 05-concurrency/06-patterns pushes Job values
+// straight through a channel. That has two problems in a controller:
+//   - a key that fails is simply dropped
+//   - the same key can be processed by two workers at once
+//
+// This queue fixes both, with the same guarantees as
+// k8s.io/client-go/util/workqueue:
+//   - an item that is already queued is collapsed into one entry
+//   - an item being processed is never handed out again until Done
+//   - failed items are re-added with per-item exponential backoff
+//
+// The intended worker loop is:
+//
+//	for {
+//		key, shutdown := q.Get()
+//		if shutdown {
+//			return
+//		}
+//		if err := reconcile(key); err != nil {
+//			q.AddRateLimited(key)
+//		} else {
+//			q.Forget(key)
+//		}
+//		q.Done(key)
+//	}
+package workqueue
+
+import (
+	"sync"
+
+	"go-systems-learning/pkg/clock"
+)
+
+// ==========================================================
+// 1. CONFIGURATION
+// ==========================================================
+
+// Config configures a Queue. The zero value is usable.
+type Config[T comparable] struct {
+	// Clock drives AddAfter. Defaults to clock.RealClock.
+	Clock clock.Clock
+
+	// RateLimiter decides the delay for AddRateLimited.
+	// Defaults to DefaultRateLimiter.
+	RateLimiter RateLimiter[T]
+}
+
+// ==========================================================
+// 2. QUEUE
+// ==========================================================
+
+// Queue is a de-duplicating, delaying, rate-limited work queue.
+type Queue[T comparable] struct {
+	clock       clock.Clock
+	rateLimiter RateLimiter[T]
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// queue holds the processing order. Every element is also in dirty
+	// and none is in processing.
+	queue []T
+
+	// dirty is every item that needs processing.
+	dirty map[T]struct{}
+
+	// processing is every item handed out by Get and not yet Done.
+	// An item can be in both dirty and processing: it was re-added
+	// while a worker held it and will be queued again on Done.
+	processing map[T]struct{}
+
+	shuttingDown bool
+
+	delaying *delayingState[T]
+}
+
+// New returns a Queue with the default configuration.
+func New[T comparable]() *Queue[T] {
+	return NewWithConfig(Config[T]{})
+}
+
+// NewWithConfig returns a Queue configured by cfg.
+func NewWithConfig[T comparable](cfg Config[T]) *Queue[T] {
+	if cfg.Clock == nil {
+		cfg.Clock = clock.RealClock{}
+	}
+	if cfg.RateLimiter == nil {
+		cfg.RateLimiter = DefaultRateLimiter[T]()
+	}
+
+	q := &Queue[T]{
+		clock:       cfg.Clock,
+		rateLimiter: cfg.RateLimiter,
+		dirty:       make(map[T]struct{}),
+		processing:  make(map[T]struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	q.delaying = newDelayingState[T]()
+
+	go q.waitingLoop()
+
+	return q
+}
+
+// Add marks item as needing processing.
+//
+// If item is already queued, this is a no-op. If item is currently
+// being processed, it is queued again once the worker calls Done.
+func (q *Queue[T]) Add(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.shuttingDown {
+		return
+	}
+	if _, ok := q.dirty[item]; ok {
+		return
+	}
+
+	q.dirty[item] = struct{}{}
+	if _, ok := q.processing[item]; ok {
+		return
+	}
+
+	q.queue = append(q.queue, item)
+	q.cond.Signal()
+}
+
+// Len returns the number of items waiting to be handed out.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// Get blocks until an item is available and hands it to the caller,
+// who MUST call Done when finished with it.
+//
+// shutdown is true once the queue is shut down and empty.
+func (q *Queue[T]) Get() (item T, shutdown bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.queue) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.queue) == 0 {
+		return item, true
+	}
+
+	item = q.queue[0]
+	var zero T
+	q.queue[0] = zero // let the GC reclaim it
+	q.queue = q.queue[1:]
+
+	q.processing[item] = struct{}{}
+	delete(q.dirty, item)
+
+	return item, false
+}
+
+// Done marks item as finished. If it was added again while being
+// processed, it goes back on the queue now.
+func (q *Queue[T]) Done(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.processing, item)
+	if _, ok := q.dirty[item]; ok {
+		q.queue = append(q.queue, item)
+		q.cond.Signal()
+	} else if len(q.processing) == 0 {
+		// ShutDownWithDrain may be waiting for this.
+		q.cond.Broadcast()
+	}
+}
+
+// ShutDown stops accepting new items. Workers keep receiving what is
+// already queued, then Get reports shutdown.
+func (q *Queue[T]) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutDownLocked()
+}
+
+// ShutDownWithDrain is ShutDown, but it also blocks until every item
+// handed out by Get has been marked Done.
+func (q *Queue[T]) ShutDownWithDrain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.shutDownLocked()
+
+	for len(q.processing) != 0 {
+		q.cond.Wait()
+	}
+}
+
+// ShuttingDown reports whether ShutDown has been called.
+func (q *Queue[T]) ShuttingDown() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.shuttingDown
+}
+
+func (q *Queue[T]) shutDownLocked() {
+	if !q.shuttingDown {
+		q.shuttingDown = true
+		close(q.delaying.stop)
+	}
+	q.cond.Broadcast()
+}
@@ -0,0 +1,186 @@
+package workqueue
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go-systems-learning/pkg/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestQueue_DeduplicatesQueuedItems(t *testing.T) {
+	q := New[string]()
+	defer q.ShutDown()
+
+	q.Add("default/pod-a")
+	q.Add("default/pod-a")
+	q.Add("default/pod-b")
+
+	if got := q.Len(); got != 2 {
+		t.Fatalf("Len() = %d; want 2", got)
+	}
+}
+
+func TestQueue_ItemIsNotHandedOutTwiceWhileProcessing(t *testing.T) {
+	q := New[string]()
+	defer q.ShutDown()
+
+	q.Add("key")
+	item, _ := q.Get()
+
+	// Re-added while being processed: must wait for Done.
+	q.Add("key")
+	if got := q.Len(); got != 0 {
+		t.Fatalf("Len() while processing = %d; want 0", got)
+	}
+
+	q.Done(item)
+	if got := q.Len(); got != 1 {
+		t.Fatalf("Len() after Done = %d; want 1", got)
+	}
+}
+
+func TestQueue_ConcurrentWorkersNeverShareAKey(t *testing.T) {
+	q := New[int]()
+
+	var (
+		mu     sync.Mutex
+		active = make(map[int]bool)
+		wg     sync.WaitGroup
+	)
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				key, shutdown := q.Get()
+				if shutdown {
+					return
+				}
+
+				mu.Lock()
+				if active[key] {
+					t.Errorf("key %d handed to two workers at once", key)
+				}
+				active[key] = true
+				mu.Unlock()
+
+				mu.Lock()
+				active[key] = false
+				mu.Unlock()
+				q.Done(key)
+			}
+		}()
+	}
+
+	for i := 0; i < 1000; i++ {
+		q.Add(i % 5)
+	}
+
+	q.ShutDownWithDrain()
+	wg.Wait()
+}
+
+func TestQueue_ShutDownWithDrainWaitsForDone(t *testing.T) {
+	q := New[string]()
+	q.Add("key")
+	item, _ := q.Get()
+
+	drained := make(chan struct{})
+	go func() {
+		q.ShutDownWithDrain()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		t.Fatal("ShutDownWithDrain returned before Done")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	q.Done(item)
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("ShutDownWithDrain did not return after Done (possible deadlock)")
+	}
+
+	if _, shutdown := q.Get(); !shutdown {
+		t.Fatal("Get after drain did not report shutdown")
+	}
+}
+
+func TestQueue_AddAfter(t *testing.T) {
+	fake := clock.NewFakeClock(epoch)
+	q := NewWithConfig(Config[string]{Clock: fake})
+	defer q.ShutDown()
+
+	q.AddAfter("key", time.Minute)
+	waitForWaiters(t, fake)
+
+	if got := q.Len(); got != 0 {
+		t.Fatalf("Len() before delay = %d; want 0", got)
+	}
+
+	fake.Step(time.Minute)
+	waitForLen(t, q, 1)
+}
+
+func TestQueue_AddRateLimitedBacksOffPerItem(t *testing.T) {
+	limiter := NewItemExponentialFailureRateLimiter[string](100*time.Millisecond, time.Minute)
+	limiter.rand = func() float64 { return 0.5 } // jitter factor 1.0
+
+	fake := clock.NewFakeClock(epoch)
+	q := NewWithConfig(Config[string]{Clock: fake, RateLimiter: limiter})
+	defer q.ShutDown()
+
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+	}
+	for i, w := range want {
+		if got := limiter.When("key"); got != w {
+			t.Fatalf("failure %d: When() = %v; want %v", i+1, got, w)
+		}
+	}
+	if got := q.NumRequeues("key"); got != 3 {
+		t.Fatalf("NumRequeues() = %d; want 3", got)
+	}
+
+	q.Forget("key")
+	if got := q.NumRequeues("key"); got != 0 {
+		t.Fatalf("NumRequeues() after Forget = %d; want 0", got)
+	}
+
+	q.AddRateLimited("key")
+	waitForWaiters(t, fake)
+	fake.Step(100 * time.Millisecond)
+	waitForLen(t, q, 1)
+}
+
+func waitForWaiters(t *testing.T, c *clock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the queue to wait on the clock")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitForLen[T comparable](t *testing.T, q *Queue[T], want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for q.Len() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len() = %d; want %d", q.Len(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,103 @@
+package workqueue
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go-systems-learning/pkg/backoff"
+)
+
+// ==========================================================
+// 1. RATE LIMITER INTERFACE
+// ==========================================================
+
+// RateLimiter decides how long an item waits before it is retried.
+type RateLimiter[T comparable] interface {
+	// When records a failure for item and returns how long to wait.
+	When(item T) time.Duration
+
+	// Forget clears the failure history of item.
+	Forget(item T)
+
+	// NumRequeues returns how many failures item has had.
+	NumRequeues(item T) int
+}
+
+// DefaultRateLimiter backs off per item from the lesson's baseDelay of
+// 100ms up to 1000s.
+func DefaultRateLimiter[T comparable]() RateLimiter[T] {
+	return NewItemExponentialFailureRateLimiter[T](100*time.Millisecond, 1000*time.Second)
+}
+
+// ==========================================================
+// 2. PER-ITEM EXPONENTIAL BACKOFF WITH JITTER
+// ==========================================================
+
+// ItemExponentialFailureRateLimiter waits
+//
+//	2^(failures-1) * base * jitter   (jitter in [0.5, 1.5))
+//
+// for each item, capped at max. This is exactly retryWithJitter from
+// 07-error-handling/05-retry-backoff-patterns, tracked per item.
+type ItemExponentialFailureRateLimiter[T comparable] struct {
+	mu       sync.Mutex
+	failures map[T]int
+
+	base time.Duration
+	max  time.Duration
+
+	// rand returns a value in [0, 1). Tests replace it.
+	rand func() float64
+}
+
+// NewItemExponentialFailureRateLimiter returns a limiter starting at
+// base and never waiting longer than max.
+func NewItemExponentialFailureRateLimiter[T comparable](base, max time.Duration) *ItemExponentialFailureRateLimiter[T] {
+	return &ItemExponentialFailureRateLimiter[T]{
+		failures: make(map[T]int),
+		base:     base,
+		max:      max,
+		rand:     rand.Float64,
+	}
+}
+
+func (r *ItemExponentialFailureRateLimiter[T]) When(item T) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[item]++
+	return backoff.ExponentialJitter(r.base, r.failures[item], r.rand(), r.max)
+}
+
+func (r *ItemExponentialFailureRateLimiter[T]) Forget(item T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.failures, item)
+}
+
+func (r *ItemExponentialFailureRateLimiter[T]) NumRequeues(item T) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failures[item]
+}
+
+// ==========================================================
+// 3. RATE-LIMITED QUEUE METHODS
+// ==========================================================
+
+// AddRateLimited adds item after the rate limiter says it is ok.
+func (q *Queue[T]) AddRateLimited(item T) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+// Forget tells the rate limiter item succeeded, so its backoff resets.
+// It does not remove item from the queue.
+func (q *Queue[T]) Forget(item T) {
+	q.rateLimiter.Forget(item)
+}
+
+// NumRequeues returns how many times item has been rate-limited.
+func (q *Queue[T]) NumRequeues(item T) int {
+	return q.rateLimiter.NumRequeues(item)
+}